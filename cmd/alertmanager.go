@@ -0,0 +1,68 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/alertmanager"
+	"github.com/lenye/pmsg/pkg/flags"
+)
+
+// alertmanagerCmd 接收 Prometheus Alertmanager webhook，发送到多个机器人
+var alertmanagerCmd = &cobra.Command{
+	Use:   "alertmanager",
+	Short: "receive prometheus alertmanager webhooks and publish alerts to bots",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := alertmanager.CmdServeParams{
+			Client:        newHTTPClient(),
+			Logger:        log.New(os.Stderr, "", log.LstdFlags),
+			Classify:      classifyError,
+			Listen:        serveListen,
+			Auth:          serveAuth,
+			To:            to,
+			TemplateFile:  templateFile,
+			AtUserLabel:   atUserLabel,
+			AtMobileLabel: atMobileLabel,
+			AllOrNothing:  allOrNothing,
+			MinSuccess:    minSuccess,
+		}
+		return alertmanager.CmdServe(cmd.Context(), &arg)
+	},
+	Example: `pmsg alertmanager --listen 127.0.0.1:8080 --to wecom-bot:env:WECOM_BOT_KEY --to dingtalk:env:DINGTALK_BOT --at_mobile_label owner_mobile
+alertmanager.yml: webhook_configs: [{url: http://127.0.0.1:8080/v1/alertmanager}]`,
+}
+
+func init() {
+	setHTTPClientFlags(alertmanagerCmd)
+
+	alertmanagerCmd.Flags().StringVar(&serveListen, flags.Listen, "127.0.0.1:8080", "listen address")
+	alertmanagerCmd.Flags().StringVar(&serveAuth, flags.ServerAuth, "", "client auth token, set as http_config.authorization.credentials in alertmanager, required unless listening on a loopback address")
+
+	alertmanagerCmd.Flags().StringArrayVar(&to, flags.To, nil, "destination, can be repeated: wecom-bot:key, dingtalk:access_token[:secret], feishu:access_token[:secret], slack:webhook_url (required)")
+	alertmanagerCmd.MarkFlagRequired(flags.To)
+
+	alertmanagerCmd.Flags().StringVar(&templateFile, flags.TemplateFile, "", "go text/template file, overrides the \"title\", \"body\" or \"alert\" templates of the default")
+	alertmanagerCmd.Flags().StringVar(&atUserLabel, flags.AtUserLabel, "", "alert label holding user ids to @, multiple separated by '|' or ','")
+	alertmanagerCmd.Flags().StringVar(&atMobileLabel, flags.AtMobileLabel, "", "alert label holding mobiles to @, multiple separated by '|' or ','")
+
+	alertmanagerCmd.Flags().BoolVar(&allOrNothing, flags.AllOrNothing, false, "fail unless every destination succeeds")
+	alertmanagerCmd.Flags().IntVar(&minSuccess, flags.MinSuccess, 0, "fail unless at least this many destinations succeed (default 1)")
+	alertmanagerCmd.MarkFlagsMutuallyExclusive(flags.AllOrNothing, flags.MinSuccess)
+}
@@ -0,0 +1,48 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/batch"
+	"github.com/lenye/pmsg/pkg/flags"
+)
+
+// setBatchFlags 批量发送参数，每行一个接收者，--batch 时消息内容为可选的公共模板数据
+func setBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&batchFile, flags.Batch, "", "send to every row of a csv or jsonl file, column openid (or touser) is the receiver, other columns are template data")
+	cmd.Flags().StringVar(&resultFile, flags.ResultFile, "", "batch result file, rows already in it are skipped on rerun (default <batch>.result.jsonl)")
+	cmd.Flags().IntVar(&concurrency, flags.Concurrency, batch.Concurrency, "batch concurrent requests")
+	cmd.Flags().BoolVar(&retryFailed, flags.RetryFailed, false, "batch resends the rows that failed in the result file, except rows whose result is unknown")
+	cmd.Flags().BoolVar(&retryUnknown, flags.RetryUnknown, false, "batch resends the rows whose result is unknown in the result file, they may have been sent")
+	cmd.MarkFlagsMutuallyExclusive(flags.ToUser, flags.Batch)
+}
+
+// batchArgs --batch 时消息内容可选
+func batchArgs(cmd *cobra.Command, args []string) error {
+	if batchFile != "" {
+		return cobra.MaximumNArgs(1)(cmd, args)
+	}
+	return cobra.ExactArgs(1)(cmd, args)
+}
+
+// readBatchMessage 读取批量发送的公共模板数据，未指定时为空
+func readBatchMessage(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	return readMessage(cmd, args[0])
+}
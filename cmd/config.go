@@ -0,0 +1,85 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/config"
+)
+
+// configCmd 配置文件
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "list and show profiles in the config file",
+}
+
+// configListCmd 列出配置
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(configFile())
+		if err != nil {
+			return err
+		}
+		type item struct {
+			Name     string `json:"name"`
+			Provider string `json:"provider"`
+		}
+		items := make([]item, 0, len(cfg.Profiles))
+		lines := make([]string, 0, len(cfg.Profiles))
+		for _, name := range cfg.Names() {
+			items = append(items, item{Name: name, Provider: cfg.Profiles[name].Provider})
+			lines = append(lines, fmt.Sprintf("%s\t%s", name, cfg.Profiles[name].Provider))
+		}
+		return newPrinter().Print(strings.Join(lines, "\n"), items)
+	},
+	Example: "pmsg config list",
+}
+
+// configShowCmd 显示配置，隐藏凭证
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "show profile with secrets masked",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(configFile())
+		if err != nil {
+			return err
+		}
+		p, err := cfg.Profile(args[0])
+		if err != nil {
+			return err
+		}
+		masked := p.Masked()
+
+		lines := []string{fmt.Sprintf("provider: %s", masked.Provider)}
+		for _, name := range masked.Names() {
+			lines = append(lines, fmt.Sprintf("%s: %s", name, masked.Flags[name]))
+		}
+		return newPrinter().Print(strings.Join(lines, "\n"), masked)
+	},
+	Example: "pmsg config show wecom-ops",
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configShowCmd)
+}
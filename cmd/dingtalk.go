@@ -17,7 +17,7 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 
-	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/dingtalk"
 )
 
 // dingTalkCmd 钉钉
@@ -28,7 +28,8 @@ var dingTalkCmd = &cobra.Command{
 }
 
 func init() {
-	dingTalkCmd.PersistentFlags().StringVarP(&userAgent, flags.UserAgent, "a", "", "http user agent")
+	setHTTPClientFlags(dingTalkCmd)
+	setAPIBaseFlag(dingTalkCmd, &dingTalkAPIBase, dingtalk.Host, dingtalk.HostEnv)
 
 	dingTalkCmd.AddCommand(dingTalkBotCmd)
 }
@@ -15,49 +15,58 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/dingtalk/bot"
 	"github.com/lenye/pmsg/pkg/flags"
 )
 
 // dingTalkBotCmd 钉钉自定义机器人
-var dingTalkBotCmd = &cobra.Command{
-	Use:   "bot",
-	Short: "publish ding talk bot message",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := bot.CmdSendParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			Secret:      secret,
-			MsgType:     msgType,
-			AtUser:      atUser,
-			AtMobile:    atMobile,
-			IsAtAll:     isAtAll,
-			Data:        args[0],
-		}
-		if err := bot.CmdSend(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var dingTalkBotCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "bot",
+		Short:   "publish ding talk bot message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg dingtalk bot -t access_token -m text 'hello world'",
 	},
-	Example: "pmsg dingtalk bot -t access_token -m text 'hello world'",
-}
+	flags: dingTalkBotFlags,
+	run:   sendDingTalkBot,
+})
 
 func init() {
-	dingTalkBotCmd.Flags().StringVarP(&accessToken, flags.AccessToken, "t", "", "dingtalk bot access token (required)")
-	dingTalkBotCmd.MarkFlagRequired(flags.AccessToken)
+	setTemplateFlags(dingTalkBotCmd)
+	setDryRunFlags(dingTalkBotCmd)
+}
 
-	dingTalkBotCmd.Flags().StringVarP(&secret, flags.Secret, "s", "", "sign secret")
+// dingTalkBotFlags 钉钉自定义机器人消息的参数
+func dingTalkBotFlags(fs *pflag.FlagSet) {
+	fs.StringP(flags.AccessToken, "t", "", "dingtalk bot access token (required)")
+	cobra.MarkFlagRequired(fs, flags.AccessToken)
 
-	dingTalkBotCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	dingTalkBotCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.Secret, "s", "", "sign secret")
 
-	dingTalkBotCmd.Flags().StringVarP(&atUser, flags.AtUser, "o", "", "dingtalk user id list")
-	dingTalkBotCmd.Flags().StringVarP(&atMobile, flags.AtMobile, "b", "", "mobile list")
-	dingTalkBotCmd.Flags().BoolVarP(&isAtAll, flags.IsAtAll, "i", false, "is @all")
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
+
+	fs.StringP(flags.AtUser, "o", "", "dingtalk user id list")
+	fs.StringP(flags.AtMobile, "b", "", "mobile list")
+	fs.BoolP(flags.IsAtAll, "i", false, "is @all")
+}
 
+// sendDingTalkBot 发送钉钉自定义机器人消息
+func sendDingTalkBot(ctx context.Context, r *sendRequest) error {
+	return bot.CmdSend(ctx, &bot.CmdSendParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		AccessToken: r.str(flags.AccessToken),
+		Secret:      r.str(flags.Secret),
+		MsgType:     r.str(flags.MsgType),
+		AtUser:      r.str(flags.AtUser),
+		AtMobile:    r.str(flags.AtMobile),
+		IsAtAll:     r.boolean(flags.IsAtAll),
+		Data:        r.data,
+	})
 }
@@ -0,0 +1,79 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// EnvPrefix 命令行参数对应的环境变量前缀，如 corp_secret 对应 PMSG_CORP_SECRET
+const EnvPrefix = "PMSG_"
+
+// envAnnotation 参数的环境变量名称不是 EnvName 时，在参数的 Annotations 中指定
+const envAnnotation = "pmsg_env"
+
+// envHelp 帮助信息中的环境变量说明
+const envHelp = `
+Environment:
+  Every flag can be set by an environment variable named PMSG_ followed by
+  the flag name in upper case, e.g. PMSG_CORP_SECRET for --corp_secret;
+  --api_base uses the platform variable, e.g. PMSG_DINGTALK_API_BASE.
+  Precedence: flag > environment variable > profile > default value.
+`
+
+// EnvName 命令行参数对应的环境变量名称
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// flagEnvName 参数对应的环境变量名称
+func flagEnvName(f *pflag.Flag) string {
+	if v := f.Annotations[envAnnotation]; len(v) > 0 {
+		return v[0]
+	}
+	return EnvName(f.Name)
+}
+
+// applyEnv 使用环境变量中的参数值，命令行参数优先
+// 设置后参数视为已设置，MarkFlagRequired 等检查同样通过
+func applyEnv(cmd *cobra.Command) error {
+	credentialChanged := credentialFlagChanged(cmd)
+
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" || f.Name == "version" {
+			return
+		}
+		if credentialChanged && isCredentialFlag(f.Name) {
+			return
+		}
+		name := flagEnvName(f)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid environment variable %s: %v", name, setErr)
+			return
+		}
+		envSources[f.Name] = name
+	})
+	return err
+}
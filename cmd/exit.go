@@ -0,0 +1,87 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lenye/pmsg/pkg/dingtalk"
+	"github.com/lenye/pmsg/pkg/feishu"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/slack"
+	"github.com/lenye/pmsg/pkg/tokencache"
+	"github.com/lenye/pmsg/pkg/weixin"
+)
+
+// 退出码
+const (
+	ExitOK           = 0   // 成功
+	ExitInvalid      = 1   // 参数错误：命令行参数、消息内容或本地文件无效，未发出请求
+	ExitNetwork      = 2   // 网络错误：连接失败、超时或 http 状态码异常
+	ExitAPI          = 3   // 接口错误：平台返回错误码
+	ExitUnauthorized = 4   // 认证错误：access_token、密钥、签名无效或获取失败
+	ExitFailure      = 5   // 其它错误：发出请求后失败，消息可能已发送
+	ExitInterrupted  = 130 // 被中断 (Ctrl+C)
+)
+
+// exitTypes 退出码对应的错误类型，用于 json 格式的错误输出
+var exitTypes = map[int]string{
+	ExitInvalid:      "invalid",
+	ExitNetwork:      "network",
+	ExitAPI:          "api",
+	ExitUnauthorized: "unauthorized",
+	ExitFailure:      "failure",
+	ExitInterrupted:  "interrupted",
+}
+
+// exitCodeHelp 帮助信息中的退出码说明
+const exitCodeHelp = `
+Exit Codes:
+  0    success
+  1    invalid flags, arguments, message content or local file; nothing was sent
+  2    network error: connection failed, timed out or unexpected http status
+  3    provider api error: the platform returned an error code
+  4    auth error: invalid or expired access token, secret, sign or webhook
+  5    other error after a request was sent; the message may have been sent
+  130  interrupted
+`
+
+// exitCode 按错误类型返回退出码，未分类的错误：sent 为 false 未发出请求时为参数错误，否则为其它错误
+func exitCode(err error, sent bool) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, weixin.ErrUnauthorized),
+		errors.Is(err, dingtalk.ErrUnauthorized),
+		errors.Is(err, feishu.ErrUnauthorized),
+		errors.Is(err, slack.ErrUnauthorized),
+		errors.Is(err, tokencache.ErrServerUnauthorized):
+		return ExitUnauthorized
+	case errors.Is(err, weixin.ErrRequest),
+		errors.Is(err, dingtalk.ErrRequest),
+		errors.Is(err, feishu.ErrRequest),
+		errors.Is(err, slack.ErrRequest):
+		return ExitAPI
+	case errors.Is(err, client.ErrRequest), errors.Is(err, context.DeadlineExceeded):
+		return ExitNetwork
+	}
+	if sent {
+		return ExitFailure
+	}
+	return ExitInvalid
+}
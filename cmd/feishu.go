@@ -17,7 +17,7 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 
-	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/feishu"
 )
 
 // feiShuCmd 飞书
@@ -28,7 +28,8 @@ var feiShuCmd = &cobra.Command{
 }
 
 func init() {
-	feiShuCmd.PersistentFlags().StringVarP(&userAgent, flags.UserAgent, "a", "", "http user agent")
+	setHTTPClientFlags(feiShuCmd)
+	setAPIBaseFlag(feiShuCmd, &feiShuAPIBase, feishu.Host, feishu.HostEnv)
 
 	feiShuCmd.AddCommand(feiShuBotCmd)
 }
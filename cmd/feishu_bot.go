@@ -15,42 +15,51 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/feishu/bot"
 	"github.com/lenye/pmsg/pkg/flags"
 )
 
 // feiShuBotCmd 飞书自定义机器人
-var feiShuBotCmd = &cobra.Command{
-	Use:   "bot",
-	Short: "publish fei shu bot message",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := bot.CmdSendParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			Secret:      secret,
-			MsgType:     msgType,
-			Data:        args[0],
-		}
-		if err := bot.CmdSend(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var feiShuBotCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "bot",
+		Short:   "publish fei shu bot message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg feishu bot -t access_token -m text 'hello world'",
 	},
-	Example: "pmsg feishu bot -t access_token -m text 'hello world'",
-}
+	flags: feiShuBotFlags,
+	run:   sendFeiShuBot,
+})
 
 func init() {
-	feiShuBotCmd.Flags().StringVarP(&accessToken, flags.AccessToken, "t", "", "feishu bot access token (required)")
-	feiShuBotCmd.MarkFlagRequired(flags.AccessToken)
+	setTemplateFlags(feiShuBotCmd)
+	setDryRunFlags(feiShuBotCmd)
+}
 
-	feiShuBotCmd.Flags().StringVarP(&secret, flags.Secret, "s", "", "sign secret")
+// feiShuBotFlags 飞书自定义机器人消息的参数
+func feiShuBotFlags(fs *pflag.FlagSet) {
+	fs.StringP(flags.AccessToken, "t", "", "feishu bot access token (required)")
+	cobra.MarkFlagRequired(fs, flags.AccessToken)
 
-	feiShuBotCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	feiShuBotCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.Secret, "s", "", "sign secret")
+
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
+}
 
+// sendFeiShuBot 发送飞书自定义机器人消息
+func sendFeiShuBot(ctx context.Context, r *sendRequest) error {
+	return bot.CmdSend(ctx, &bot.CmdSendParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		AccessToken: r.str(flags.AccessToken),
+		Secret:      r.str(flags.Secret),
+		MsgType:     r.str(flags.MsgType),
+		Data:        r.data,
+	})
 }
@@ -0,0 +1,260 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/lenye/pmsg/pkg/config"
+	"github.com/lenye/pmsg/pkg/dingtalk"
+	"github.com/lenye/pmsg/pkg/feishu"
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/outbox"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/source"
+	"github.com/lenye/pmsg/pkg/weixin"
+)
+
+// outboxCmd 本地发件箱，--queue 的消息由 outbox run 在后台投递
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "local outbox of messages queued with --queue",
+}
+
+func init() {
+	outboxCmd.PersistentFlags().StringVar(&outboxFile, flags.Outbox, outbox.DefaultFile(), "outbox file")
+
+	outboxCmd.AddCommand(outboxRunCmd)
+	outboxCmd.AddCommand(outboxListCmd)
+	outboxCmd.AddCommand(outboxRetryCmd)
+	outboxCmd.AddCommand(outboxPurgeCmd)
+
+	for _, r := range sendRoutes {
+		setQueueFlags(r)
+	}
+}
+
+// setQueueFlags --queue 参数：消息保存到发件箱后立即返回，不发送
+func setQueueFlags(r sendRoute) {
+	r.cmd.Flags().BoolVar(&queue, flags.Queue, false, "append the message to the local outbox and return, 'pmsg outbox run' delivers it")
+	r.cmd.Flags().StringVar(&outboxFile, flags.Outbox, outbox.DefaultFile(), "outbox file")
+
+	run := r.cmd.RunE
+	r.cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if !queue {
+			return run(cmd, args)
+		}
+		return enqueue(cmd, args, r)
+	}
+}
+
+// queueSkipFlags 不保存到发件箱的参数，消息内容入队时已按模板渲染
+func queueSkipFlags(name string) bool {
+	switch name {
+	case flags.Queue, flags.Outbox, flags.DryRun, flags.DryRunToken, flags.Template, flags.Var, flags.Vars:
+		return true
+	}
+	return false
+}
+
+// enqueue 消息保存到发件箱，由 outbox run 投递
+func enqueue(cmd *cobra.Command, args []string, r sendRoute) error {
+	if dryRun {
+		return fmt.Errorf("invalid flags: %s and %s cannot be used together", flags.Queue, flags.DryRun)
+	}
+	m, err := buildMessage(cmd, args, r)
+	if err != nil {
+		return err
+	}
+
+	store := outbox.NewStore(outboxFile)
+	e := outbox.Entry{Message: *m}
+	if err := store.Add(&e); err != nil {
+		return err
+	}
+	return newPrinter().Print("queued "+e.ID, outbox.Queued{ID: e.ID, Command: e.Command, Outbox: store.Path()})
+}
+
+// buildMessage 保存命令、已设置的参数和消息内容，凭证参数只保存来源，见 secretReference，
+// 并按 dry run 构建请求，验证参数和消息内容
+func buildMessage(cmd *cobra.Command, args []string, r sendRoute) (*outbox.Message, error) {
+	if f := cmd.Flags().Lookup(flags.Batch); f != nil && f.Changed {
+		return nil, fmt.Errorf("invalid flags: %s cannot be saved, run the batch directly", flags.Batch)
+	}
+	data, err := readMessage(cmd, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	fs := r.newFlagSet()
+	stored := make(map[string][]string)
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || !f.Changed || queueSkipFlags(f.Name) || fs.Lookup(f.Name) == nil {
+			return
+		}
+		values := flagValues(cmd.Flags(), f)
+		for _, v := range values {
+			if err = fs.Set(f.Name, v); err != nil {
+				err = fmt.Errorf("invalid flags %s: %v", f.Name, err)
+				return
+			}
+		}
+		if config.IsProviderSecret(providerOf(cmd), f.Name) {
+			var ref string
+			if ref, err = secretReference(f); err != nil {
+				return
+			}
+			values = []string{ref}
+		}
+		stored[f.Name] = values
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var req *client.DryRunRequest
+	c := newHTTPClient(client.WithDryRun(client.DryRunOption{
+		Print: func(v *client.DryRunRequest) error {
+			req = v
+			return nil
+		},
+	}))
+	err = r.run(cmd.Context(), &sendRequest{
+		flagReader: flagReader{fs},
+		client:     c,
+		printer:    output.New(output.FormatJSON, io.Discard),
+		data:       data,
+	})
+	if !errors.Is(err, client.ErrDryRun) {
+		if err == nil {
+			err = fmt.Errorf("%s: nothing to send", r.path())
+		}
+		return nil, err
+	}
+	return &outbox.Message{
+		Command: r.path(),
+		Flags:   stored,
+		Data:    data,
+		Request: req,
+	}, nil
+}
+
+// secretReference 保存到发件箱的凭证：file:、env:、exec: 来源原样保存，来自环境变量时保存为 env: 来源；
+// 直接写在命令行或配置中的凭证不保存，避免明文写入发件箱文件
+func secretReference(f *pflag.Flag) (string, error) {
+	raw, ok := secretSources[f.Name]
+	if !ok {
+		raw = f.Value.String()
+	}
+	if source.IsSecretSource(raw) {
+		return raw, nil
+	}
+	if name, ok := envSources[f.Name]; ok {
+		return source.SecretEnv + name, nil
+	}
+	return "", fmt.Errorf("invalid flags %s: a literal secret cannot be saved, use a %s, %s or %s source", f.Name, source.SecretFile, source.SecretEnv, source.SecretExec)
+}
+
+// flagValues 参数的值，列表参数逐个保存，key=value 参数按 key 排序后逐个保存
+func flagValues(fs *pflag.FlagSet, f *pflag.Flag) []string {
+	if v, ok := f.Value.(pflag.SliceValue); ok {
+		return v.GetSlice()
+	}
+	if f.Value.Type() != "stringToString" {
+		return []string{f.Value.String()}
+	}
+	m, _ := fs.GetStringToString(f.Name)
+	values := make([]string, 0, len(m))
+	for k, v := range m {
+		values = append(values, k+"="+v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// findSendRoute 按命令路径查找发送消息的命令，如 workweixin/app
+func findSendRoute(path string) (sendRoute, bool) {
+	for _, r := range sendRoutes {
+		if r.path() == path {
+			return r, true
+		}
+	}
+	return sendRoute{}, false
+}
+
+// sendMessage 按保存的命令和参数发送消息，凭证参数在发送时读取来源
+func sendMessage(ctx context.Context, m *outbox.Message) error {
+	route, ok := findSendRoute(m.Command)
+	if !ok {
+		return fmt.Errorf("command %q not supported", m.Command)
+	}
+
+	fs := route.newFlagSet()
+	provider := m.Provider()
+	for name, values := range m.Flags {
+		for _, v := range values {
+			if config.IsProviderSecret(provider, name) {
+				var err error
+				if v, err = source.Secret(ctx, v); err != nil {
+					return fmt.Errorf("invalid flags %s: %v", name, err)
+				}
+			}
+			if err := fs.Set(name, v); err != nil {
+				return fmt.Errorf("invalid flags %s: %v", name, err)
+			}
+		}
+	}
+	return route.run(ctx, &sendRequest{
+		flagReader: flagReader{fs},
+		client:     newHTTPClient(),
+		printer:    output.New(output.FormatJSON, io.Discard),
+		tokenCache: newTokenCache(),
+		data:       m.Data,
+	})
+}
+
+// deliverOutbox 投递发件箱中的消息；发出请求后的未分类错误，消息可能已发送，返回的错误包含 client.ErrMaybeSent
+func deliverOutbox(ctx context.Context, e *outbox.Entry) error {
+	ctx = client.TrackRequests(ctx)
+	err := sendMessage(ctx, &e.Message)
+	if err != nil && client.RequestSent(ctx) && exitCode(err, true) == ExitFailure && !errors.Is(err, client.ErrMaybeSent) {
+		return fmt.Errorf("%w, %w", err, client.ErrMaybeSent)
+	}
+	return err
+}
+
+// permanentError 不可重试的错误：参数或消息内容无效、接口返回错误、认证失败，平台繁忙或限流除外；
+// 发送消息的请求已发出、结果未知时不重试，避免重复发送，转为死信由人工确认，确认未收到后用 outbox retry 重新投递
+func permanentError(err error) bool {
+	if errors.Is(err, client.ErrMaybeSent) {
+		return true
+	}
+	if errors.Is(err, weixin.ErrBusy) || errors.Is(err, dingtalk.ErrBusy) || errors.Is(err, feishu.ErrBusy) {
+		return false
+	}
+	switch exitCode(err, true) {
+	case ExitFailure, ExitAPI, ExitUnauthorized:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/outbox"
+)
+
+// outboxListCmd 列出发件箱中的消息
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list queued messages and dead letters",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := outbox.CmdListParams{
+			Printer: newPrinter(),
+			Store:   outbox.NewStore(outboxFile),
+			State:   outboxState,
+		}
+		return outbox.CmdList(&arg)
+	},
+	Example: "pmsg outbox list --state dead",
+}
+
+func init() {
+	outboxListCmd.Flags().StringVar(&outboxState, flags.State, "", "only list messages in this state: pending or dead")
+}
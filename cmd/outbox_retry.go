@@ -0,0 +1,36 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/outbox"
+)
+
+// outboxRetryCmd 死信重新投递
+var outboxRetryCmd = &cobra.Command{
+	Use:   "retry [id...]",
+	Short: "move dead letters back to the outbox, all of them without ids",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := outbox.CmdRetryParams{
+			Printer: newPrinter(),
+			Store:   outbox.NewStore(outboxFile),
+			IDs:     args,
+		}
+		return outbox.CmdRetry(&arg)
+	},
+	Example: "pmsg outbox retry 20261018T010203-1a2b3c4d",
+}
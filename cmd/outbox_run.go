@@ -0,0 +1,56 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/outbox"
+)
+
+// outboxRunCmd 投递发件箱中的消息
+var outboxRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "deliver queued messages with retries, permanent failures become dead letters",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := outbox.CmdRunParams{
+			Printer:     newPrinter(),
+			Logger:      log.New(os.Stderr, "", log.LstdFlags),
+			Store:       outbox.NewStore(outboxFile),
+			Deliver:     deliverOutbox,
+			Permanent:   permanentError,
+			Interval:    interval,
+			MaxAttempts: maxAttempts,
+			Once:        once,
+		}
+		return outbox.CmdRun(cmd.Context(), &arg)
+	},
+	Example: `pmsg outbox run
+pmsg outbox run --once --max_attempts 5`,
+}
+
+func init() {
+	setHTTPClientFlags(outboxRunCmd)
+	setTokenCacheFlags(outboxRunCmd)
+
+	outboxRunCmd.Flags().DurationVar(&interval, flags.Interval, outbox.Interval, "how often to check the outbox for due messages")
+	outboxRunCmd.Flags().IntVar(&maxAttempts, flags.MaxAttempts, outbox.MaxAttempts, "max delivery attempts before a message becomes a dead letter")
+	outboxRunCmd.Flags().BoolVar(&once, flags.Once, false, "deliver the due messages once and exit")
+}
@@ -0,0 +1,105 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/config"
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// credentialFlags 接口调用凭证参数，命令行设置了其中任一参数时，不使用环境变量和配置中的凭证
+var credentialFlags = []string{flags.AccessToken, flags.AppID, flags.AppSecret, flags.CorpID, flags.CorpSecret}
+
+// configFile 配置文件，默认 ~/.config/pmsg/config.yaml
+func configFile() string {
+	if configPath != "" {
+		return configPath
+	}
+	return config.DefaultFile()
+}
+
+// providerOf 命令所属的平台，即根命令下一级命令的名称
+func providerOf(cmd *cobra.Command) string {
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		if !c.Parent().HasParent() {
+			return c.Name()
+		}
+	}
+	return ""
+}
+
+// applyProfile 使用配置中的参数值，命令行参数优先
+func applyProfile(cmd *cobra.Command) error {
+	if profile == "" {
+		return nil
+	}
+	provider := providerOf(cmd)
+	if notify.ValidateProvider(provider) != nil {
+		return nil
+	}
+
+	cfg, err := config.Load(configFile())
+	if err != nil {
+		return err
+	}
+	p, err := cfg.Profile(profile)
+	if err != nil {
+		return err
+	}
+	if p.Provider != provider {
+		return fmt.Errorf("profile %q is for %s, cannot be used with %s", profile, p.Provider, provider)
+	}
+
+	credentialChanged := credentialFlagChanged(cmd)
+
+	for name, value := range p.Flags {
+		f := cmd.Flags().Lookup(name)
+		// 命令不支持的参数忽略，同一配置可用于平台下的多个命令
+		if f == nil || f.Changed {
+			continue
+		}
+		if credentialChanged && isCredentialFlag(name) {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("invalid profile %q flags %s: %v", profile, name, err)
+		}
+	}
+	return nil
+}
+
+// credentialFlagChanged 命令行或环境变量是否已设置接口调用凭证
+func credentialFlagChanged(cmd *cobra.Command) bool {
+	for _, name := range credentialFlags {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+func isCredentialFlag(name string) bool {
+	for _, v := range credentialFlags {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
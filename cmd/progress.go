@@ -0,0 +1,63 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lenye/pmsg/pkg/batch"
+	"github.com/lenye/pmsg/pkg/file"
+	"github.com/lenye/pmsg/pkg/http/client"
+)
+
+// uploadProgress 在终端显示上传进度，stderr 不是终端时不显示
+func uploadProgress() client.ProgressFunc {
+	fi, err := os.Stderr.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	lastPercent := int64(-1)
+	return func(sent, total int64) {
+		if total <= 0 {
+			return
+		}
+		percent := sent * 100 / total
+		if percent == lastPercent {
+			return
+		}
+		lastPercent = percent
+		fmt.Fprintf(os.Stderr, "\ruploading %3d%% (%v/%v)", percent, file.FormatSize(sent), file.FormatSize(total))
+		if sent >= total {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+}
+
+// batchProgress 在终端显示批量发送进度，stderr 不是终端时不显示
+func batchProgress() batch.ProgressFunc {
+	fi, err := os.Stderr.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	return func(done, failed, total int) {
+		fmt.Fprintf(os.Stderr, "\rsent %d/%d, failed %d", done, total, failed)
+		if done >= total {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+}
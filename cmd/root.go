@@ -15,12 +15,28 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lenye/pmsg/pkg/config"
+	"github.com/lenye/pmsg/pkg/dingtalk"
+	"github.com/lenye/pmsg/pkg/feishu"
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/ratelimit"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/version"
+	"github.com/lenye/pmsg/pkg/weixin"
+	"github.com/lenye/pmsg/pkg/weixin/token"
+	"github.com/lenye/pmsg/pkg/weixin/work"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -30,24 +46,193 @@ var rootCmd = &cobra.Command{
 	CompletionOptions: cobra.CompletionOptions{
 		HiddenDefaultCmd: true,
 	},
+	// 错误和用法由 Execute 按退出码统一输出
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyEnv(cmd); err != nil {
+			return err
+		}
+		if err := output.ValidateFormat(outputFormat); err != nil {
+			return fmt.Errorf("invalid flags %s: %v", flags.Output, err)
+		}
+		if err := applyProfile(cmd); err != nil {
+			return err
+		}
+		if err := applySecretSources(cmd); err != nil {
+			return err
+		}
+		if f := cmd.Flags().Lookup(flags.TokenMode); f != nil {
+			if err := token.ValidateMode(f.Value.String()); err != nil {
+				return fmt.Errorf("invalid flags %s: %v", flags.TokenMode, err)
+			}
+		}
+		return requireSecretFlags(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	tracked := client.TrackRequests(ctx)
+	cmd, err := rootCmd.ExecuteContextC(tracked)
+	interrupted := ctx.Err() != nil
+	stop()
+	if errors.Is(err, client.ErrDryRun) {
+		return
+	}
+	if err != nil {
+		code := exitCode(err, client.RequestSent(tracked))
+		if interrupted {
+			code = ExitInterrupted
+		}
+		errPrinter := output.New(outputFormat, os.Stderr)
+		errPrinter.PrintError(exitTypes[code], code, err)
+		if code == ExitInvalid && !errPrinter.JSON() {
+			fmt.Fprintln(os.Stderr)
+			fmt.Fprint(os.Stderr, cmd.UsageString())
+		}
+		os.Exit(code)
 	}
 }
 
+// newPrinter 按命令行参数输出命令执行结果
+func newPrinter() *output.Printer {
+	return output.New(outputFormat, os.Stdout)
+}
+
+// setDryRunFlags dry run 参数，只输出请求，不发送
+func setDryRunFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&dryRun, flags.DryRun, false, "print the request instead of sending it")
+}
+
+// setTokenCacheFlags access_token 缓存参数
+func setTokenCacheFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&tokenCache, flags.TokenCache, true, "cache access tokens on disk, shared across invocations")
+	cmd.PersistentFlags().StringVar(&tokenServer, flags.TokenServer, "", "get access tokens from a token server started by 'token serve', example: http://127.0.0.1:8960")
+	cmd.PersistentFlags().StringVar(&serverAuth, flags.TokenServerAuth, "", "token server auth token")
+}
+
+// newTokenCache 按命令行参数新建 access_token 缓存，不缓存时返回 nil
+func newTokenCache() tokencache.Cache {
+	if tokenServer != "" {
+		c := client.New(client.WithUserAgent(userAgent), client.WithTimeout(timeout))
+		return tokencache.NewRemote(c, tokenServer, serverAuth)
+	}
+	if !tokenCache {
+		return nil
+	}
+	return tokencache.NewFile(tokencache.DefaultFile(), tokencache.RefreshMargin)
+}
+
+// requireFlags 检查参数是否已设置，参数可来自命令行、环境变量或配置文件
+func requireFlags(cmd *cobra.Command, names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if f := cmd.Flags().Lookup(name); f == nil || f.Value.String() == "" {
+			missing = append(missing, strconv.Quote(name))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required flag(s) %s not set", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// requireSecretFlags 发送消息的命令中 app_id/corp_id 和 app_secret/corp_secret 需要同时设置，
+// 使用 access_token 服务时不需要 secret
+func requireSecretFlags(cmd *cobra.Command) error {
+	if tokenServer != "" || cmd.Flags().Lookup(flags.AccessToken) == nil {
+		return nil
+	}
+	for _, group := range [][2]string{{flags.AppID, flags.AppSecret}, {flags.CorpID, flags.CorpSecret}} {
+		id, secret := cmd.Flags().Lookup(group[0]), cmd.Flags().Lookup(group[1])
+		if id == nil || secret == nil || id.Changed == secret.Changed {
+			continue
+		}
+		missing := group[1]
+		if !id.Changed {
+			missing = group[0]
+		}
+		return fmt.Errorf("if any flags in the group [%s %s] are set they must all be set; missing [%s]", group[0], group[1], missing)
+	}
+	return nil
+}
+
+// setHTTPClientFlags http客户端参数
+func setHTTPClientFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&userAgent, flags.UserAgent, "a", "", "http user agent")
+	cmd.PersistentFlags().DurationVar(&timeout, flags.Timeout, client.Timeout, "http request timeout")
+	cmd.PersistentFlags().IntVar(&retry, flags.Retry, 0, "max retries on connection failures, throttling or busy responses; sends that may have reached the server are not retried")
+	cmd.PersistentFlags().DurationVar(&retryMaxWait, flags.RetryMaxWait, client.RetryMaxWait, "max wait before each retry")
+	cmd.PersistentFlags().BoolVar(&rateLimit, flags.RateLimit, true, "client-side rate limit per bot webhook, shared across invocations")
+}
+
+// setAPIBaseFlag 接口地址参数，用于私有网关、国际版域名或本地模拟服务
+// 环境变量为平台专用的 env，如 PMSG_DINGTALK_API_BASE，同其它参数一样由 applyEnv 设置
+func setAPIBaseFlag(cmd *cobra.Command, p *string, host, env string) {
+	cmd.PersistentFlags().StringVar(p, flags.APIBase, "", fmt.Sprintf("api base url, overrides %s (env %s)", host, env))
+	cmd.PersistentFlags().SetAnnotation(flags.APIBase, envAnnotation, []string{env})
+}
+
+// apiBase 接口地址，命令行参数优先，其次环境变量；
+// send、serve 等没有 --api_base 参数的命令只使用环境变量
+func apiBase(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// newHTTPClient 按命令行参数新建http客户端
+func newHTTPClient(extra ...client.Option) *client.Client {
+	opts := []client.Option{
+		client.WithUserAgent(userAgent),
+		client.WithTimeout(timeout),
+		client.WithRetry(client.RetryPolicy{
+			MaxAttempts: retry + 1,
+			MaxWait:     retryMaxWait,
+		}),
+		weixin.WithHost(apiBase(weiXinAPIBase, weixin.HostEnv)),
+		work.WithHost(apiBase(workWeiXinAPIBase, work.HostEnv)),
+		dingtalk.WithHost(apiBase(dingTalkAPIBase, dingtalk.HostEnv)),
+		feishu.WithHost(apiBase(feiShuAPIBase, feishu.HostEnv)),
+	}
+	if rateLimit {
+		// 多次执行 pmsg 共享限流状态，避免机器人被限流
+		opts = append(opts, client.WithLimiter(ratelimit.NewFile(ratelimit.DefaultFile())))
+	}
+	if dryRun {
+		opts = append(opts, client.WithDryRun(client.DryRunOption{
+			Print: func(req *client.DryRunRequest) error {
+				return newPrinter().Print(req.String(), req)
+			},
+			FetchToken: dryRunToken,
+		}))
+	}
+	return client.New(append(opts, extra...)...)
+}
+
 func init() {
 	rootCmd.SetVersionTemplate(`{{printf "%s" .Version}}`)
 	rootCmd.Version = version.Print()
+	rootCmd.SetHelpTemplate(rootCmd.HelpTemplate() + envHelp + exitCodeHelp)
+
+	rootCmd.PersistentFlags().StringVar(&outputFormat, flags.Output, output.FormatText, "output format: text, json")
+	rootCmd.PersistentFlags().StringVar(&configPath, flags.Config, "", "config file (default "+config.DefaultFile()+")")
+	rootCmd.PersistentFlags().StringVar(&profile, flags.Profile, "", "profile name in the config file, flags override profile values")
 
 	rootCmd.AddCommand(weiXinCmd)
 	rootCmd.AddCommand(workWeiXinCmd)
 	rootCmd.AddCommand(dingTalkCmd)
 	rootCmd.AddCommand(feiShuCmd)
 	rootCmd.AddCommand(slackCmd)
+	rootCmd.AddCommand(sendCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(alertmanagerCmd)
+	rootCmd.AddCommand(webhookCmd)
+	rootCmd.AddCommand(outboxCmd)
+	rootCmd.AddCommand(scheduleCmd)
+	rootCmd.AddCommand(configCmd)
 }
@@ -0,0 +1,82 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/outbox"
+	"github.com/lenye/pmsg/pkg/schedule"
+)
+
+// scheduleCmd 定时发送消息，由 schedule run 按时发送
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "scheduled and recurring messages",
+}
+
+func init() {
+	scheduleCmd.PersistentFlags().StringVar(&scheduleFile, flags.Schedule, schedule.DefaultFile(), "schedule file")
+
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(schedulePauseCmd)
+	scheduleCmd.AddCommand(scheduleResumeCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+	scheduleCmd.AddCommand(scheduleRunCmd)
+}
+
+// parseScheduledCommand 按发送消息的命令解析参数和消息内容，同直接执行该命令，但不发送
+func parseScheduledCommand(cmd *cobra.Command, args []string) (*outbox.Message, error) {
+	target, rest, err := rootCmd.Find(args)
+	if err != nil {
+		return nil, err
+	}
+	var route sendRoute
+	for _, r := range sendRoutes {
+		if r.cmd == target {
+			route = r
+		}
+	}
+	if route.cmd == nil {
+		return nil, fmt.Errorf("invalid arguments: %q is not a supported send command", target.CommandPath())
+	}
+
+	target.SetContext(cmd.Context())
+	if err := target.ParseFlags(rest); err != nil {
+		return nil, err
+	}
+	targetArgs := target.Flags().Args()
+	if err := target.ValidateArgs(targetArgs); err != nil {
+		return nil, err
+	}
+	// 同 cobra 执行命令的顺序，先使用环境变量和配置，再检查必填参数
+	if err := rootCmd.PersistentPreRunE(target, targetArgs); err != nil {
+		return nil, err
+	}
+	if err := target.ValidateRequiredFlags(); err != nil {
+		return nil, err
+	}
+	if err := target.ValidateFlagGroups(); err != nil {
+		return nil, err
+	}
+	if queue || dryRun {
+		return nil, fmt.Errorf("invalid flags: %s and %s are not supported by schedule add, use 'pmsg schedule run --%s'", flags.Queue, flags.DryRun, flags.Queue)
+	}
+	return buildMessage(target, targetArgs, route)
+}
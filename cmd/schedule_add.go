@@ -0,0 +1,58 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/schedule"
+)
+
+// scheduleAddCmd 添加定时发送的任务
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add (--at time | --cron expr) [--] command [flags] message",
+	Short: "schedule a send command once at a time or on a cron schedule",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := parseScheduledCommand(cmd, args)
+		if err != nil {
+			return err
+		}
+		arg := schedule.CmdAddParams{
+			Printer:  newPrinter(),
+			Store:    schedule.NewStore(scheduleFile),
+			Message:  m,
+			Name:     scheduleName,
+			At:       scheduleAt,
+			Cron:     scheduleCron,
+			Timezone: timezone,
+		}
+		return schedule.CmdAdd(&arg)
+	},
+	Example: `pmsg schedule add --cron "0 9 * * 1-5" --timezone Asia/Shanghai --name standup -- workweixin appchat -i corp_id -s env:CORP_SECRET -c chat_id -m text "standup in 15 minutes"
+pmsg schedule add --at 2026-11-01T09:00 -- dingtalk bot -t env:DINGTALK_TOKEN -m markdown @report.json`,
+}
+
+func init() {
+	// 第一个非参数的参数开始为发送消息的命令，其后的参数由该命令解析
+	scheduleAddCmd.Flags().SetInterspersed(false)
+
+	scheduleAddCmd.Flags().StringVar(&scheduleAt, flags.At, "", "send once at this time: 2006-01-02T15:04 in --timezone, or RFC3339")
+	scheduleAddCmd.Flags().StringVar(&scheduleCron, flags.Cron, "", "send repeatedly on this cron schedule: minute hour day-of-month month day-of-week")
+	scheduleAddCmd.MarkFlagsMutuallyExclusive(flags.At, flags.Cron)
+	scheduleAddCmd.Flags().StringVar(&timezone, flags.Timezone, "Local", "time zone of --at and --cron, example: Asia/Shanghai, Local is the system time zone")
+	scheduleAddCmd.Flags().StringVar(&scheduleName, flags.Name, "", "schedule name")
+}
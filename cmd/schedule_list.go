@@ -0,0 +1,36 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/schedule"
+)
+
+// scheduleListCmd 列出定时发送的任务
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list scheduled messages",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := schedule.CmdListParams{
+			Printer: newPrinter(),
+			Store:   schedule.NewStore(scheduleFile),
+		}
+		return schedule.CmdList(&arg)
+	},
+	Example: "pmsg schedule list",
+}
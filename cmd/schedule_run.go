@@ -0,0 +1,64 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/outbox"
+	"github.com/lenye/pmsg/pkg/schedule"
+)
+
+// scheduleRunCmd 按时发送任务的消息
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "send scheduled messages when they are due",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := schedule.CmdRunParams{
+			Printer: newPrinter(),
+			Logger:  log.New(os.Stderr, "", log.LstdFlags),
+			Store:   schedule.NewStore(scheduleFile),
+			Fire:    fireSchedule,
+			Once:    once,
+		}
+		return schedule.CmdRun(cmd.Context(), &arg)
+	},
+	Example: `pmsg schedule run
+pmsg schedule run --queue`,
+}
+
+func init() {
+	setHTTPClientFlags(scheduleRunCmd)
+	setTokenCacheFlags(scheduleRunCmd)
+
+	scheduleRunCmd.Flags().BoolVar(&once, flags.Once, false, "send the due messages once and exit")
+	scheduleRunCmd.Flags().BoolVar(&queue, flags.Queue, false, "append due messages to the outbox instead of sending, 'pmsg outbox run' delivers them with retries")
+	scheduleRunCmd.Flags().StringVar(&outboxFile, flags.Outbox, outbox.DefaultFile(), "outbox file")
+}
+
+// fireSchedule 发送任务的消息，--queue 时保存到发件箱
+func fireSchedule(ctx context.Context, j *schedule.Job) error {
+	if queue {
+		e := outbox.Entry{Message: j.Message}
+		return outbox.NewStore(outboxFile).Add(&e)
+	}
+	return sendMessage(ctx, &j.Message)
+}
@@ -0,0 +1,69 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/fanout"
+	"github.com/lenye/pmsg/pkg/flags"
+)
+
+// sendCmd 同时发送消息到多个机器人
+var sendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "publish a message to multiple bots concurrently",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := readMessage(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		arg := fanout.CmdSendParams{
+			Client:       newHTTPClient(),
+			Printer:      newPrinter(),
+			To:           to,
+			MsgType:      msgType,
+			AtUser:       atUser,
+			AtMobile:     atMobile,
+			IsAtAll:      isAtAll,
+			AllOrNothing: allOrNothing,
+			MinSuccess:   minSuccess,
+			Data:         data,
+		}
+		return fanout.CmdSend(cmd.Context(), &arg)
+	},
+	Example: "pmsg send --to wecom-bot:key --to dingtalk:access_token --to slack:webhook_url '{\"title\":\"hello\",\"body\":\"world\"}'",
+}
+
+func init() {
+	setHTTPClientFlags(sendCmd)
+
+	sendCmd.Flags().StringArrayVar(&to, flags.To, nil, "destination, can be repeated: wecom-bot:key, dingtalk:access_token[:secret], feishu:access_token[:secret], slack:webhook_url (required)")
+	sendCmd.MarkFlagRequired(flags.To)
+
+	sendCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type supported by every destination, default generic")
+
+	sendCmd.Flags().StringVarP(&atUser, flags.AtUser, "o", "", "user id list")
+	sendCmd.Flags().StringVarP(&atMobile, flags.AtMobile, "b", "", "mobile list")
+	sendCmd.Flags().BoolVarP(&isAtAll, flags.IsAtAll, "i", false, "is @all")
+
+	sendCmd.Flags().BoolVar(&allOrNothing, flags.AllOrNothing, false, "fail unless every destination succeeds")
+	sendCmd.Flags().IntVar(&minSuccess, flags.MinSuccess, 0, "fail unless at least this many destinations succeed (default 1)")
+	sendCmd.MarkFlagsMutuallyExclusive(flags.AllOrNothing, flags.MinSuccess)
+
+	setTemplateFlags(sendCmd)
+	setDryRunFlags(sendCmd)
+}
@@ -0,0 +1,131 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
+)
+
+// sendRoute 发送消息的命令：一个声明同时生成命令行的参数和执行函数，以及 serve、发件箱和定时发送每次请求的参数集合和发送函数；
+// serve 的接口路径同命令，如 pmsg workweixin app 对应 POST /v1/send/workweixin/app
+type sendRoute struct {
+	cmd   *cobra.Command
+	flags func(fs *pflag.FlagSet)                           // 消息的参数，不绑定全局变量
+	run   func(ctx context.Context, req *sendRequest) error // 发送一条消息
+	batch func(ctx context.Context, req *sendRequest) error // --batch 批量发送，只用于命令行，可选
+}
+
+// sendRoutes serve 命令提供的发送消息接口，以及支持 --queue 和 schedule add 的命令，由 newSendCommand 注册
+var sendRoutes []sendRoute
+
+// newSendCommand 按声明设置命令的参数和 RunE，并注册为发送消息的命令；
+// 模板、dry run 等只用于命令行的参数由调用方另外设置
+func newSendCommand(r sendRoute) *cobra.Command {
+	r.flags(r.cmd.Flags())
+	r.cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		req := &sendRequest{
+			flagReader: flagReader{cmd.Flags()},
+			client:     newHTTPClient(),
+			printer:    newPrinter(),
+			tokenCache: newTokenCache(),
+			local:      true,
+		}
+		var err error
+		if r.batch != nil && batchFile != "" {
+			if req.data, err = readBatchMessage(cmd, args); err != nil {
+				return err
+			}
+			return r.batch(cmd.Context(), req)
+		}
+		if req.data, err = readMessage(cmd, args[0]); err != nil {
+			return err
+		}
+		return r.run(cmd.Context(), req)
+	}
+	sendRoutes = append(sendRoutes, r)
+	return r.cmd
+}
+
+// path 接口路径 {provider}/{kind}
+func (r sendRoute) path() string {
+	return strings.ReplaceAll(strings.TrimPrefix(r.cmd.CommandPath(), rootCmd.Name()+" "), " ", "/")
+}
+
+// newFlagSet 按声明新建参数集合，每个请求单独使用，不修改命令行参数
+func (r sendRoute) newFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet(r.cmd.Name(), pflag.ContinueOnError)
+	r.flags(fs)
+	return fs
+}
+
+// flagReader 按名称读取参数的值
+type flagReader struct {
+	*pflag.FlagSet
+}
+
+func (r flagReader) str(name string) string {
+	v, _ := r.GetString(name)
+	return v
+}
+
+func (r flagReader) integer(name string) int {
+	v, _ := r.GetInt(name)
+	return v
+}
+
+func (r flagReader) int64(name string) int64 {
+	v, _ := r.GetInt64(name)
+	return v
+}
+
+func (r flagReader) boolean(name string) bool {
+	v, _ := r.GetBool(name)
+	return v
+}
+
+// list 以 ‘|’ 分隔的列表，为空时返回 nil
+func (r flagReader) list(name string) []string {
+	if v := r.str(name); v != "" {
+		return strings.Split(v, "|")
+	}
+	return nil
+}
+
+// stringMap key=value 参数，未设置时返回 nil
+func (r flagReader) stringMap(name string) map[string]string {
+	if f := r.Lookup(name); f == nil || !f.Changed {
+		return nil
+	}
+	v, _ := r.GetStringToString(name)
+	return v
+}
+
+// sendRequest 一次发送消息请求
+type sendRequest struct {
+	flagReader
+	client     *client.Client
+	printer    *output.Printer
+	tokenCache tokencache.Cache
+	data       string
+	local      bool // 命令行直接执行，可以读取本地文件
+}
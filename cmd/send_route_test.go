@@ -0,0 +1,97 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/lenye/pmsg/pkg/flags"
+)
+
+// cliOnlyFlags 只用于命令行的参数，不属于消息的参数
+var cliOnlyFlags = map[string]bool{
+	flags.DryRun:       true,
+	flags.DryRunToken:  true,
+	flags.Template:     true,
+	flags.Var:          true,
+	flags.Vars:         true,
+	flags.Queue:        true,
+	flags.Outbox:       true,
+	flags.Batch:        true,
+	flags.ResultFile:   true,
+	flags.Concurrency:  true,
+	flags.RetryFailed:  true,
+	flags.RetryUnknown: true,
+}
+
+// notSendCommands 有 dry run 参数但不发送消息的命令
+var notSendCommands = map[string]bool{
+	"pmsg send":                  true,
+	"pmsg weixin upload":         true,
+	"pmsg workweixin upload":     true,
+	"pmsg workweixin bot upload": true,
+}
+
+func TestSendRoutes(t *testing.T) {
+	routes := make(map[*cobra.Command]sendRoute)
+	for _, r := range sendRoutes {
+		routes[r.cmd] = r
+	}
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, c := range cmd.Commands() {
+			walk(c)
+		}
+		if cmd.LocalFlags().Lookup(flags.DryRun) == nil || notSendCommands[cmd.CommandPath()] {
+			return
+		}
+		r, ok := routes[cmd]
+		if !ok {
+			t.Errorf("%s: no send route", cmd.CommandPath())
+			return
+		}
+		delete(routes, cmd)
+
+		fs := r.newFlagSet()
+		cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+			if cliOnlyFlags[f.Name] {
+				return
+			}
+			v := fs.Lookup(f.Name)
+			if v == nil {
+				t.Errorf("%s: flag %s not in route", cmd.CommandPath(), f.Name)
+				return
+			}
+			if v.Value.Type() != f.Value.Type() || v.DefValue != f.DefValue || v.Shorthand != f.Shorthand {
+				t.Errorf("%s: flag %s = %s %q -%s, route %s %q -%s", cmd.CommandPath(), f.Name,
+					f.Value.Type(), f.DefValue, f.Shorthand, v.Value.Type(), v.DefValue, v.Shorthand)
+			}
+		})
+		fs.VisitAll(func(f *pflag.Flag) {
+			if cmd.LocalFlags().Lookup(f.Name) == nil {
+				t.Errorf("%s: route flag %s not in command", cmd.CommandPath(), f.Name)
+			}
+		})
+	}
+	walk(rootCmd)
+
+	for cmd := range routes {
+		t.Errorf("%s: route not reachable from %s", cmd.CommandPath(), rootCmd.Name())
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	neturl "net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/lenye/pmsg/pkg/apiserver"
+	"github.com/lenye/pmsg/pkg/config"
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/source"
+	"github.com/lenye/pmsg/pkg/tokencache"
+	"github.com/lenye/pmsg/pkg/weixin/token"
+)
+
+// serveCmd 发送消息的 http 服务
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "serve an http api for sending messages, credentials come from config profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := apiserver.ValidateListen(serveListen, serveAuth); err != nil {
+			return err
+		}
+		profiles, err := loadServeProfiles(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		c := newHTTPClient()
+		cache := newTokenCache()
+		srv := apiserver.NewServer(serveAuth, classifyError, log.New(os.Stderr, "", log.LstdFlags))
+		for _, r := range sendRoutes {
+			srv.Handle(r.path(), r.handler(profiles, c, cache))
+		}
+		return srv.ListenAndServe(cmd.Context(), serveListen)
+	},
+	Example: `pmsg serve --listen 127.0.0.1:8080 --server_auth file:/etc/pmsg/server_auth
+curl -H 'Authorization: Bearer token' -d 'hello world' 'http://127.0.0.1:8080/v1/send/workweixin/app?profile=ops&msg_type=text&to_user=@all'`,
+}
+
+func init() {
+	setHTTPClientFlags(serveCmd)
+	setTokenCacheFlags(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, flags.Listen, "127.0.0.1:8080", "listen address")
+	serveCmd.Flags().StringVar(&serveAuth, flags.ServerAuth, "", "client auth token, clients send it as 'Authorization: Bearer <token>', required unless listening on a loopback address")
+}
+
+// classifyError 错误类型和退出码
+func classifyError(ctx context.Context, err error) (string, int) {
+	code := exitCode(err, client.RequestSent(ctx))
+	return exitTypes[code], code
+}
+
+// loadServeProfiles 读取配置文件中的命名配置，并读取 file:、env:、exec: 来源的凭证；没有配置文件时为空
+func loadServeProfiles(ctx context.Context) (map[string]*config.Profile, error) {
+	cfg, err := config.Load(configFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for name, p := range cfg.Profiles {
+		for _, k := range config.SecretFlags {
+			v, ok := p.Flags[k]
+			if !ok {
+				continue
+			}
+			if p.Flags[k], err = source.Secret(ctx, v); err != nil {
+				return nil, fmt.Errorf("invalid profile %q flags %s: %v", name, k, err)
+			}
+		}
+	}
+	return cfg.Profiles, nil
+}
+
+// serveQueryDenied 不能通过查询参数设置的命令行参数：凭证只能来自服务端的命名配置
+func serveQueryDenied(provider, name string) bool {
+	if isCredentialFlag(name) || config.IsProviderSecret(provider, name) {
+		return true
+	}
+	switch name {
+	case flags.DryRun, flags.DryRunToken, flags.Template, flags.Var, flags.Vars, flags.Queue, flags.Outbox,
+		flags.Batch, flags.ResultFile, flags.Concurrency, flags.RetryFailed, flags.RetryUnknown:
+		return true
+	}
+	return false
+}
+
+// handler 处理请求：命令的默认参数值，依次使用命名配置、查询参数的值
+func (r sendRoute) handler(profiles map[string]*config.Profile, c *client.Client, cache tokencache.Cache) apiserver.HandlerFunc {
+	provider := providerOf(r.cmd)
+	return func(ctx context.Context, req *apiserver.Request) error {
+		fs := r.newFlagSet()
+		query := req.Query
+		if p, err := serveProfile(profiles, provider, query.Get(flags.Profile)); err != nil {
+			return err
+		} else if p != nil {
+			for name, value := range p.Flags {
+				if fs.Lookup(name) == nil {
+					continue
+				}
+				if err := fs.Set(name, value); err != nil {
+					return fmt.Errorf("invalid profile flags %s: %v", name, err)
+				}
+			}
+		}
+		if err := setServeQuery(fs, provider, query); err != nil {
+			return err
+		}
+		if err := requireServeFlags(fs); err != nil {
+			return err
+		}
+		if f := fs.Lookup(flags.TokenMode); f != nil {
+			if err := token.ValidateMode(f.Value.String()); err != nil {
+				return fmt.Errorf("invalid flags %s: %v", flags.TokenMode, err)
+			}
+		}
+
+		return r.run(ctx, &sendRequest{
+			flagReader: flagReader{fs},
+			client:     c,
+			printer:    req.Printer,
+			tokenCache: cache,
+			data:       req.Data,
+		})
+	}
+}
+
+// serveProfile 请求使用的命名配置：查询参数 profile 指定，未指定时为该平台唯一的命名配置，没有时为 nil
+func serveProfile(profiles map[string]*config.Profile, provider, name string) (*config.Profile, error) {
+	if name != "" {
+		p, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found", name)
+		}
+		if p.Provider != provider {
+			return nil, fmt.Errorf("profile %q is for %s, cannot be used with %s", name, p.Provider, provider)
+		}
+		return p, nil
+	}
+
+	var found *config.Profile
+	for _, p := range profiles {
+		if p.Provider != provider {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("more than one %s profile, query parameter %s is required", provider, flags.Profile)
+		}
+		found = p
+	}
+	return found, nil
+}
+
+// setServeQuery 使用查询参数的值
+func setServeQuery(fs *pflag.FlagSet, provider string, query neturl.Values) error {
+	for name, values := range query {
+		if name == flags.Profile {
+			continue
+		}
+		if fs.Lookup(name) == nil || serveQueryDenied(provider, name) {
+			return fmt.Errorf("invalid query parameter %s: not supported", name)
+		}
+		for _, v := range values {
+			if err := fs.Set(name, v); err != nil {
+				return fmt.Errorf("invalid query parameter %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// requireServeFlags 检查命令的必填参数
+func requireServeFlags(fs *pflag.FlagSet) error {
+	var missing []string
+	fs.VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; !ok {
+			return
+		}
+		if v := f.Value.String(); v == "" || v == f.DefValue && !f.Changed {
+			missing = append(missing, f.Name)
+		}
+	})
+	if len(missing) > 0 {
+		return fmt.Errorf("required flag(s) %q not set", missing)
+	}
+	return nil
+}
@@ -16,8 +16,6 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
-
-	"github.com/lenye/pmsg/pkg/flags"
 )
 
 // slackCmd slack
@@ -28,7 +26,7 @@ var slackCmd = &cobra.Command{
 }
 
 func init() {
-	slackCmd.PersistentFlags().StringVarP(&userAgent, flags.UserAgent, "a", "", "http user agent")
+	setHTTPClientFlags(slackCmd)
 
 	slackCmd.AddCommand(slackBotCmd)
 }
@@ -15,34 +15,46 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/slack/bot"
 )
 
 // slackBotCmd slack bot
-var slackBotCmd = &cobra.Command{
-	Use:   "bot",
-	Short: "publish slack bot message",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := bot.CmdSendParams{
-			UserAgent: userAgent,
-			URL:       url,
-			Data:      args[0],
-		}
-		if err := bot.CmdSend(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var slackBotCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "bot",
+		Short:   "publish slack bot message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg slack bot --url webhook_url '{\"text\": \"Hello, World!\"}'",
 	},
-	Example: "pmsg slack bot --url webhook_url '{\"text\": \"Hello, World!\"}'",
-}
+	flags: slackBotFlags,
+	run:   sendSlackBot,
+})
 
 func init() {
-	slackBotCmd.Flags().StringVar(&url, flags.Url, "", "slack webhook url")
-	slackBotCmd.MarkFlagRequired(flags.Url)
+	setTemplateFlags(slackBotCmd)
+	setDryRunFlags(slackBotCmd)
+}
+
+// slackBotFlags slack bot 消息的参数
+func slackBotFlags(fs *pflag.FlagSet) {
+	fs.String(flags.Url, "", "slack webhook url")
+	cobra.MarkFlagRequired(fs, flags.Url)
+	fs.StringP(flags.MsgType, "m", "", "message type: json (default), text, generic")
+}
+
+// sendSlackBot 发送 slack bot 消息
+func sendSlackBot(ctx context.Context, r *sendRequest) error {
+	return bot.CmdSend(ctx, &bot.CmdSendParams{
+		Client:  r.client,
+		Printer: r.printer,
+		URL:     r.str(flags.Url),
+		MsgType: r.str(flags.MsgType),
+		Data:    r.data,
+	})
 }
@@ -0,0 +1,87 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/lenye/pmsg/pkg/config"
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/render"
+	"github.com/lenye/pmsg/pkg/source"
+)
+
+// secretSources 读取来源前的凭证参数，--queue 时保存到发件箱，投递时再读取
+var secretSources = make(map[string]string)
+
+// envSources 由环境变量设置的参数对应的环境变量名称，--queue 时凭证保存为 env: 来源
+var envSources = make(map[string]string)
+
+// setTemplateFlags 模板参数，消息内容按 text/template 渲染
+func setTemplateFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&tplEnabled, flags.Template, false, "render the message as a go text/template")
+	cmd.Flags().StringArrayVar(&tplVar, flags.Var, nil, "template variable in k=v format, can be repeated")
+	cmd.Flags().StringVar(&tplVarsFile, flags.Vars, "", "template variables json or yaml file")
+}
+
+// readMessage 读取消息内容，支持 "-" 从 stdin 读取、"@path" 从文件读取，按参数渲染模板
+func readMessage(cmd *cobra.Command, arg string) (string, error) {
+	data, err := source.Message(arg, cmd.InOrStdin())
+	if err != nil {
+		return "", err
+	}
+	if !tplEnabled {
+		return data, nil
+	}
+
+	vars := render.Vars{}
+	if tplVarsFile != "" {
+		if vars, err = render.LoadVars(tplVarsFile); err != nil {
+			return "", err
+		}
+	}
+	for _, v := range tplVar {
+		k, value, err := render.ParseVar(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid flags %s: %v", flags.Var, err)
+		}
+		vars[k] = value
+	}
+	return render.Render(providerOf(cmd), data, vars)
+}
+
+// applySecretSources 从 file:、env:、exec: 来源读取凭证，凭证不出现在命令行中
+func applySecretSources(cmd *cobra.Command) error {
+	provider := providerOf(cmd)
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || !f.Changed || !config.IsProviderSecret(provider, f.Name) {
+			return
+		}
+		secretSources[f.Name] = f.Value.String()
+		value, readErr := source.Secret(cmd.Context(), f.Value.String())
+		if readErr != nil {
+			err = fmt.Errorf("invalid flags %s: %v", f.Name, readErr)
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid flags %s: %v", f.Name, setErr)
+		}
+	})
+	return err
+}
@@ -14,53 +14,78 @@
 
 package cmd
 
+import "time"
+
 var (
-	userAgent string
+	userAgent    string
+	timeout      time.Duration
+	retry        int
+	retryMaxWait time.Duration
+	rateLimit    bool
+	outputFormat string
+	dryRun       bool
+	dryRunToken  bool
+	configPath   string
+	profile      string
+	tplEnabled   bool
+	tplVar       []string
+	tplVarsFile  string
+	tokenCache   bool
+	cacheAction  string
+	tokenServer  string
+	serverAuth   string
+	listen       string
+	serveListen  string
+	serveAuth    string
+	forceRefresh bool
 
-	secret      string
-	accessToken string
+	weiXinAPIBase     string
+	workWeiXinAPIBase string
+	dingTalkAPIBase   string
+	feiShuAPIBase     string
 
 	appID     string
 	appSecret string
 
-	toUser          string
-	toParty         string
-	toTag           string
-	toParentUserID  string
-	toStudentUserID string
-	toMobile        string
-
-	mini map[string]string
+	toMobile string
 
-	templateID       string
-	url              string
-	clientMsgID      string
-	color            string
-	msgType          string
-	kfAccount        string
-	page             string
-	miniProgramState string
-	language         string
-	scene            string
-	title            string
-	mediaType        string
-	mediaID          string
+	msgType   string
+	mediaType string
+	mediaID   string
 
 	corpID     string
 	corpSecret string
 
-	agentID                int64
-	safe                   int
-	enableIDTrans          int
-	enableDuplicateCheck   int
-	duplicateCheckInterval int
-	chatID                 string
-	toAll                  int
-	recvScope              int
-	openKfID               string
-	msgID                  string
-
 	atUser   string
 	atMobile string
 	isAtAll  bool
+
+	to           []string
+	allOrNothing bool
+	minSuccess   int
+
+	templateFile  string
+	atUserLabel   string
+	atMobileLabel string
+	githubSecret  string
+	gitlabToken   string
+
+	queue       bool
+	outboxFile  string
+	maxAttempts int
+	interval    time.Duration
+	once        bool
+	outboxState string
+
+	scheduleFile string
+	scheduleAt   string
+	scheduleCron string
+	timezone     string
+	scheduleName string
+
+	batchFile    string
+	resultFile   string
+	concurrency  int
+	retryFailed  bool
+	retryUnknown bool
 )
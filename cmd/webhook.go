@@ -0,0 +1,73 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/webhook"
+)
+
+// webhookCmd 接收 Grafana、GitHub、GitLab webhook，发送到多个机器人
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "receive grafana, github and gitlab webhooks and publish events to bots",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := webhook.CmdServeParams{
+			Client:        newHTTPClient(),
+			Logger:        log.New(os.Stderr, "", log.LstdFlags),
+			Classify:      classifyError,
+			Listen:        serveListen,
+			Auth:          serveAuth,
+			To:            to,
+			GitHubSecret:  githubSecret,
+			GitLabToken:   gitlabToken,
+			TemplateFile:  templateFile,
+			AtUserLabel:   atUserLabel,
+			AtMobileLabel: atMobileLabel,
+			AllOrNothing:  allOrNothing,
+			MinSuccess:    minSuccess,
+		}
+		return webhook.CmdServe(cmd.Context(), &arg)
+	},
+	Example: `pmsg webhook --listen 127.0.0.1:8080 --to wecom-bot:env:WECOM_BOT_KEY --github_secret env:GITHUB_WEBHOOK_SECRET --gitlab_token env:GITLAB_WEBHOOK_TOKEN
+github: http://host:8080/v1/webhook/github, gitlab: http://host:8080/v1/webhook/gitlab, grafana: http://host:8080/v1/webhook/grafana`,
+}
+
+func init() {
+	setHTTPClientFlags(webhookCmd)
+
+	webhookCmd.Flags().StringVar(&serveListen, flags.Listen, "127.0.0.1:8080", "listen address")
+	webhookCmd.Flags().StringVar(&serveAuth, flags.ServerAuth, "", "client auth token for grafana, set as the authorization credentials of the grafana webhook contact point, required unless listening on a loopback address")
+
+	webhookCmd.Flags().StringArrayVar(&to, flags.To, nil, "destination, can be repeated: wecom-bot:key, dingtalk:access_token[:secret], feishu:access_token[:secret], slack:webhook_url (required)")
+	webhookCmd.MarkFlagRequired(flags.To)
+
+	webhookCmd.Flags().StringVar(&githubSecret, flags.GitHubSecret, "", "github webhook secret, enables /v1/webhook/github")
+	webhookCmd.Flags().StringVar(&gitlabToken, flags.GitLabToken, "", "gitlab webhook secret token, enables /v1/webhook/gitlab")
+
+	webhookCmd.Flags().StringVar(&templateFile, flags.TemplateFile, "", "go text/template file, overrides templates of the default such as \"github.push.body\"")
+	webhookCmd.Flags().StringVar(&atUserLabel, flags.AtUserLabel, "", "grafana alert label holding user ids to @, multiple separated by '|' or ','")
+	webhookCmd.Flags().StringVar(&atMobileLabel, flags.AtMobileLabel, "", "grafana alert label holding mobiles to @, multiple separated by '|' or ','")
+
+	webhookCmd.Flags().BoolVar(&allOrNothing, flags.AllOrNothing, false, "fail unless every destination succeeds")
+	webhookCmd.Flags().IntVar(&minSuccess, flags.MinSuccess, 0, "fail unless at least this many destinations succeed (default 1)")
+	webhookCmd.MarkFlagsMutuallyExclusive(flags.AllOrNothing, flags.MinSuccess)
+}
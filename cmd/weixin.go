@@ -16,8 +16,11 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/weixin"
+	"github.com/lenye/pmsg/pkg/weixin/token"
 )
 
 // weiXinCmd 微信
@@ -28,7 +31,9 @@ var weiXinCmd = &cobra.Command{
 }
 
 func init() {
-	weiXinCmd.PersistentFlags().StringVarP(&userAgent, flags.UserAgent, "a", "", "http user agent")
+	setHTTPClientFlags(weiXinCmd)
+	setAPIBaseFlag(weiXinCmd, &weiXinAPIBase, weixin.Host, weixin.HostEnv)
+	setTokenCacheFlags(weiXinCmd)
 
 	weiXinCmd.AddCommand(weiXinAccessTokenCmd)
 	weiXinCmd.AddCommand(weiXinMiniProgramCmd)
@@ -37,13 +42,25 @@ func init() {
 
 }
 
-// weiXinSetAccessTokenFlags 设置微信access_token或者app_id/app_secret命令行参数
-func weiXinSetAccessTokenFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&accessToken, flags.AccessToken, "t", "", "weixin access token")
+// setTokenModeFlag 获取 access_token 的方式
+func setTokenModeFlag(fs *pflag.FlagSet) {
+	fs.String(flags.TokenMode, token.ModeLegacy, "how to fetch the access token: legacy (/cgi-bin/token) or stable (/cgi-bin/stable_token)")
+}
 
-	cmd.Flags().StringVarP(&appID, flags.AppID, "i", "", "weixin app id (required if app secret is set)")
-	cmd.Flags().StringVarP(&appSecret, flags.AppSecret, "s", "", "weixin app secret (required if app id is set)")
+// weiXinAccessTokenFlags 微信access_token或者app_id/app_secret参数
+func weiXinAccessTokenFlags(fs *pflag.FlagSet) {
+	fs.StringP(flags.AccessToken, "t", "", "weixin access token")
 
+	fs.StringP(flags.AppID, "i", "", "weixin app id (required if app secret is set)")
+	fs.StringP(flags.AppSecret, "s", "", "weixin app secret (required if app id is set, unless --token_server)")
+
+	setTokenModeFlag(fs)
+}
+
+// weiXinSetAccessTokenFlags 设置微信access_token或者app_id/app_secret命令行参数的规则：access_token 和 app_id 互斥，dry run 时可获取真实的access_token；
+// 参数由 weiXinAccessTokenFlags 声明
+func weiXinSetAccessTokenFlags(cmd *cobra.Command) {
 	cmd.MarkFlagsMutuallyExclusive(flags.AccessToken, flags.AppID)
-	cmd.MarkFlagsRequiredTogether(flags.AppID, flags.AppSecret)
+
+	cmd.Flags().BoolVar(&dryRunToken, flags.DryRunToken, false, "fetch a real access token in dry run mode")
 }
@@ -15,12 +15,10 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/spf13/cobra"
 
 	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin/token"
 )
 
@@ -29,23 +27,49 @@ var weiXinAccessTokenCmd = &cobra.Command{
 	Use:   "token",
 	Short: "get weixin access token",
 	Args:  cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := token.CmdTokenParams{
-			UserAgent: userAgent,
-			AppID:     appID,
-			AppSecret: appSecret,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cacheAction != "" {
+			return tokencache.CmdCache(&tokencache.CmdCacheParams{
+				Printer:  newPrinter(),
+				Cache:    tokencache.NewFile(tokencache.DefaultFile(), tokencache.RefreshMargin),
+				Provider: token.Provider,
+				ID:       appID,
+				Action:   cacheAction,
+			})
+		}
+		required := []string{flags.AppID, flags.AppSecret}
+		if tokenServer != "" {
+			// access_token 服务持有 secret
+			required = required[:1]
+		}
+		if err := requireFlags(cmd, required...); err != nil {
+			return err
 		}
-		if err := token.CmdGetAccessToken(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
+
+		arg := token.CmdTokenParams{
+			Client:       newHTTPClient(),
+			Printer:      newPrinter(),
+			TokenCache:   newTokenCache(),
+			AppID:        appID,
+			AppSecret:    appSecret,
+			ForceRefresh: forceRefresh,
+			TokenMode:    flagReader{cmd.Flags()}.str(flags.TokenMode),
 		}
+		return token.CmdGetAccessToken(cmd.Context(), &arg)
 	},
-	Example: "pmsg weixin token -i app_id -s app_secret",
+	Example: `pmsg weixin token -i app_id -s app_secret
+pmsg weixin token --cache show
+pmsg weixin token --cache clear -i app_id`,
 }
 
 func init() {
-	weiXinAccessTokenCmd.Flags().StringVarP(&appID, flags.AppID, "i", "", "weixin app id (required)")
-	weiXinAccessTokenCmd.MarkFlagRequired(flags.AppID)
+	weiXinAccessTokenCmd.AddCommand(weiXinAccessTokenServeCmd)
+
+	weiXinAccessTokenCmd.Flags().StringVarP(&appID, flags.AppID, "i", "", "weixin app id (required unless --cache)")
+
+	weiXinAccessTokenCmd.Flags().StringVarP(&appSecret, flags.AppSecret, "s", "", "weixin app secret (required unless --cache)")
 
-	weiXinAccessTokenCmd.Flags().StringVarP(&appSecret, flags.AppSecret, "s", "", "weixin app secret (required)")
-	weiXinAccessTokenCmd.MarkFlagRequired(flags.AppSecret)
+	setTokenModeFlag(weiXinAccessTokenCmd.Flags())
+	weiXinAccessTokenCmd.Flags().BoolVar(&forceRefresh, flags.ForceRefresh, false, "fetch a new access token instead of the cached one, stable mode sends force_refresh")
+	weiXinAccessTokenCmd.Flags().StringVar(&cacheAction, flags.Cache, "", "manage the access token cache: show or clear, without fetching a token")
 }
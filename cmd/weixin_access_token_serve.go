@@ -0,0 +1,56 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/weixin/token"
+)
+
+// weiXinAccessTokenServeCmd 微信 access_token 服务
+var weiXinAccessTokenServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "serve weixin access tokens to local pmsg clients",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireFlags(cmd, flags.AppID, flags.AppSecret, flags.TokenServerAuth); err != nil {
+			return err
+		}
+		arg := token.CmdServeParams{
+			Client:    newHTTPClient(),
+			Logger:    log.New(os.Stderr, "", log.LstdFlags),
+			Listen:    listen,
+			Auth:      serverAuth,
+			AppID:     appID,
+			AppSecret: appSecret,
+			TokenMode: flagReader{cmd.Flags()}.str(flags.TokenMode),
+		}
+		return token.CmdServe(cmd.Context(), &arg)
+	},
+	Example: `pmsg weixin token serve -i app_id -s app_secret --token_server_auth file:/etc/pmsg/token_server_auth
+pmsg weixin offiaccount template --token_server http://127.0.0.1:8960 --token_server_auth file:/etc/pmsg/token_server_auth -i app_id -p template_id -o open_id '{"first":{"value":"test"}}'`,
+}
+
+func init() {
+	weiXinAccessTokenServeCmd.Flags().StringVarP(&appID, flags.AppID, "i", "", "weixin app id (required)")
+	weiXinAccessTokenServeCmd.Flags().StringVarP(&appSecret, flags.AppSecret, "s", "", "weixin app secret (required)")
+	setTokenModeFlag(weiXinAccessTokenServeCmd.Flags())
+	weiXinAccessTokenServeCmd.Flags().StringVar(&listen, flags.Listen, "127.0.0.1:8960", "listen address")
+}
@@ -15,12 +15,10 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/spf13/cobra"
 
 	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin/asset"
 )
 
@@ -29,25 +27,30 @@ var weiXinMediaUploadCmd = &cobra.Command{
 	Use:   "upload",
 	Short: "weixin media upload",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := flagReader{cmd.Flags()}
 		arg := asset.CmdMediaUploadParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			AppID:       appID,
-			AppSecret:   appSecret,
+			Client:      newHTTPClient(client.WithProgress(uploadProgress())),
+			Printer:     newPrinter(),
+			TokenCache:  newTokenCache(),
+			AccessToken: f.str(flags.AccessToken),
+			AppID:       f.str(flags.AppID),
+			AppSecret:   f.str(flags.AppSecret),
+			TokenMode:   f.str(flags.TokenMode),
 			MediaType:   mediaType,
 			File:        args[0],
 		}
-		if err := asset.CmdMediaUpload(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+		return asset.CmdMediaUpload(cmd.Context(), &arg)
 	},
 	Example: "pmsg weixin upload -i app_id -s app_secret -m image /img/app.png",
 }
 
 func init() {
+	weiXinAccessTokenFlags(weiXinMediaUploadCmd.Flags())
 	weiXinSetAccessTokenFlags(weiXinMediaUploadCmd)
 
 	weiXinMediaUploadCmd.Flags().StringVarP(&mediaType, flags.MediaType, "m", "", "media type (required)")
 	weiXinMediaUploadCmd.MarkFlagRequired(flags.MediaType)
+
+	setDryRunFlags(weiXinMediaUploadCmd)
 }
@@ -15,44 +15,57 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/customer/message"
 )
 
 // weiXinMiniProgramCustomerCmd 发送微信小程序客服消息
-var weiXinMiniProgramCustomerCmd = &cobra.Command{
-	Use:     "customer",
-	Aliases: []string{"kf"},
-	Short:   "publish weixin miniprogram customer message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdMiniSendCustomerParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			AppID:       appID,
-			AppSecret:   appSecret,
-			ToUser:      toUser,
-			MsgType:     msgType,
-			Data:        args[0],
-		}
-		if err := message.CmdMiniSendCustomer(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var weiXinMiniProgramCustomerCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "customer",
+		Aliases: []string{"kf"},
+		Short:   "publish weixin miniprogram customer message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg weixin miniprogram customer -i app_id -s app_secret -o open_id -m text 'hello world'",
 	},
-	Example: "pmsg weixin miniprogram customer -i app_id -s app_secret -o open_id -m text 'hello world'",
-}
+	flags: weiXinMiniProgramCustomerFlags,
+	run:   sendWeiXinMiniProgramCustomer,
+})
 
 func init() {
 	weiXinSetAccessTokenFlags(weiXinMiniProgramCustomerCmd)
+	setTemplateFlags(weiXinMiniProgramCustomerCmd)
+	setDryRunFlags(weiXinMiniProgramCustomerCmd)
+}
+
+// weiXinMiniProgramCustomerFlags 微信小程序客服消息的参数
+func weiXinMiniProgramCustomerFlags(fs *pflag.FlagSet) {
+	weiXinAccessTokenFlags(fs)
 
-	weiXinMiniProgramCustomerCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "weixin user open id (required)")
-	weiXinMiniProgramCustomerCmd.MarkFlagRequired(flags.ToUser)
+	fs.StringP(flags.ToUser, "o", "", "weixin user open id (required)")
+	cobra.MarkFlagRequired(fs, flags.ToUser)
+
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
+}
 
-	weiXinMiniProgramCustomerCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	weiXinMiniProgramCustomerCmd.MarkFlagRequired(flags.MsgType)
+// sendWeiXinMiniProgramCustomer 发送微信小程序客服消息
+func sendWeiXinMiniProgramCustomer(ctx context.Context, r *sendRequest) error {
+	return message.CmdMiniSendCustomer(ctx, &message.CmdMiniSendCustomerParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		AppID:       r.str(flags.AppID),
+		AppSecret:   r.str(flags.AppSecret),
+		TokenMode:   r.str(flags.TokenMode),
+		ToUser:      r.str(flags.ToUser),
+		MsgType:     r.str(flags.MsgType),
+		Data:        r.data,
+	})
 }
@@ -15,51 +15,90 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/miniprogram/message"
 )
 
 // weiXinMiniProgramSubCmd 微信小程序订阅消息
-var weiXinMiniProgramSubCmd = &cobra.Command{
-	Use:     "subscribe",
-	Aliases: []string{"sub"},
-	Short:   "publish weixin miniprogram subscribe message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdMiniSendSubscribeParams{
-			UserAgent:        userAgent,
-			AccessToken:      accessToken,
-			AppID:            appID,
-			AppSecret:        appSecret,
-			ToUser:           toUser,
-			TemplateID:       templateID,
-			MiniProgramState: miniProgramState,
-			Page:             page,
-			Language:         language,
-			Data:             args[0],
-		}
-		if err := message.CmdMiniProgramSendSubscribe(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var weiXinMiniProgramSubCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "subscribe",
+		Aliases: []string{"sub"},
+		Short:   "publish weixin miniprogram subscribe message",
+		Args:    batchArgs,
+		Example: "pmsg weixin miniprogram subscribe -i app_id -s app_secret -p template_id -o open_id '{\"first\":{\"value\":\"test\"}}'\n" +
+			"pmsg weixin miniprogram subscribe -i app_id -s app_secret -p template_id --batch recipients.csv",
 	},
-	Example: "pmsg weixin miniprogram subscribe -i app_id -s app_secret -p template_id -o open_id '{\"first\":{\"value\":\"test\"}}'",
-}
+	flags: weiXinMiniProgramSubFlags,
+	run:   sendWeiXinMiniProgramSub,
+	batch: sendWeiXinMiniProgramSubBatch,
+})
 
 func init() {
 	weiXinSetAccessTokenFlags(weiXinMiniProgramSubCmd)
+	setTemplateFlags(weiXinMiniProgramSubCmd)
+	setBatchFlags(weiXinMiniProgramSubCmd)
+	setDryRunFlags(weiXinMiniProgramSubCmd)
+}
+
+// weiXinMiniProgramSubFlags 微信小程序订阅消息的参数
+func weiXinMiniProgramSubFlags(fs *pflag.FlagSet) {
+	weiXinAccessTokenFlags(fs)
+
+	fs.StringP(flags.ToUser, "o", "", "weixin user open id (required unless --batch)")
 
-	weiXinMiniProgramSubCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "weixin user open id (required)")
-	weiXinMiniProgramSubCmd.MarkFlagRequired(flags.ToUser)
+	fs.StringP(flags.TemplateID, "p", "", "weixin template id (required)")
+	cobra.MarkFlagRequired(fs, flags.TemplateID)
 
-	weiXinMiniProgramSubCmd.Flags().StringVarP(&templateID, flags.TemplateID, "p", "", "weixin template id (required)")
-	weiXinMiniProgramSubCmd.MarkFlagRequired(flags.TemplateID)
+	fs.StringP(flags.MiniProgramState, "g", "", "miniprogram_state")
+	fs.String(flags.Page, "", "page")
+	fs.String(flags.Language, "", "language")
+}
+
+// sendWeiXinMiniProgramSub 发送微信小程序订阅消息
+func sendWeiXinMiniProgramSub(ctx context.Context, r *sendRequest) error {
+	return message.CmdMiniProgramSendSubscribe(ctx, &message.CmdMiniSendSubscribeParams{
+		Client:           r.client,
+		Printer:          r.printer,
+		TokenCache:       r.tokenCache,
+		AccessToken:      r.str(flags.AccessToken),
+		AppID:            r.str(flags.AppID),
+		AppSecret:        r.str(flags.AppSecret),
+		TokenMode:        r.str(flags.TokenMode),
+		ToUser:           r.str(flags.ToUser),
+		TemplateID:       r.str(flags.TemplateID),
+		MiniProgramState: r.str(flags.MiniProgramState),
+		Page:             r.str(flags.Page),
+		Language:         r.str(flags.Language),
+		Data:             r.data,
+	})
+}
 
-	weiXinMiniProgramSubCmd.Flags().StringVarP(&miniProgramState, flags.MiniProgramState, "g", "", "miniprogram_state")
-	weiXinMiniProgramSubCmd.Flags().StringVar(&page, flags.Page, "", "page")
-	weiXinMiniProgramSubCmd.Flags().StringVar(&language, flags.Language, "", "language")
+// sendWeiXinMiniProgramSubBatch 按 --batch 文件批量发送微信小程序订阅消息
+func sendWeiXinMiniProgramSubBatch(ctx context.Context, r *sendRequest) error {
+	return message.CmdMiniProgramSendSubscribeBatch(ctx, &message.CmdMiniSendSubscribeBatchParams{
+		Client:           r.client,
+		Printer:          r.printer,
+		TokenCache:       r.tokenCache,
+		AccessToken:      r.str(flags.AccessToken),
+		AppID:            r.str(flags.AppID),
+		AppSecret:        r.str(flags.AppSecret),
+		TokenMode:        r.str(flags.TokenMode),
+		TemplateID:       r.str(flags.TemplateID),
+		MiniProgramState: r.str(flags.MiniProgramState),
+		Page:             r.str(flags.Page),
+		Language:         r.str(flags.Language),
+		Data:             r.data,
+		Batch:            batchFile,
+		ResultFile:       resultFile,
+		Concurrency:      concurrency,
+		RetryFailed:      retryFailed,
+		RetryUnknown:     retryUnknown,
+		Progress:         batchProgress(),
+	})
 }
@@ -15,47 +15,60 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/customer/message"
 )
 
 // weiXinOfficialAccountCustomerCmd 微信公众号客服
-var weiXinOfficialAccountCustomerCmd = &cobra.Command{
-	Use:     "customer",
-	Aliases: []string{"kf"},
-	Short:   "publish weixin official account customer message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdMpSendCustomerParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			AppID:       appID,
-			AppSecret:   appSecret,
-			ToUser:      toUser,
-			MsgType:     msgType,
-			KfAccount:   kfAccount,
-			Data:        args[0],
-		}
-		if err := message.CmdMpSendCustomer(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var weiXinOfficialAccountCustomerCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "customer",
+		Aliases: []string{"kf"},
+		Short:   "publish weixin official account customer message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg weixin offiaccount customer -i app_id -s app_secret -o open_id -m text 'hello world'",
 	},
-	Example: "pmsg weixin offiaccount customer -i app_id -s app_secret -o open_id -m text 'hello world'",
-}
+	flags: weiXinOfficialAccountCustomerFlags,
+	run:   sendWeiXinOfficialAccountCustomer,
+})
 
 func init() {
 	weiXinSetAccessTokenFlags(weiXinOfficialAccountCustomerCmd)
+	setTemplateFlags(weiXinOfficialAccountCustomerCmd)
+	setDryRunFlags(weiXinOfficialAccountCustomerCmd)
+}
+
+// weiXinOfficialAccountCustomerFlags 微信公众号客服消息的参数
+func weiXinOfficialAccountCustomerFlags(fs *pflag.FlagSet) {
+	weiXinAccessTokenFlags(fs)
 
-	weiXinOfficialAccountCustomerCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "weixin user open id (required)")
-	weiXinOfficialAccountCustomerCmd.MarkFlagRequired(flags.ToUser)
+	fs.StringP(flags.ToUser, "o", "", "weixin user open id (required)")
+	cobra.MarkFlagRequired(fs, flags.ToUser)
 
-	weiXinOfficialAccountCustomerCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	weiXinOfficialAccountCustomerCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
+
+	fs.StringP(flags.KfAccount, "k", "", "customer account")
+}
 
-	weiXinOfficialAccountCustomerCmd.Flags().StringVarP(&kfAccount, flags.KfAccount, "k", "", "customer account")
+// sendWeiXinOfficialAccountCustomer 发送微信公众号客服消息
+func sendWeiXinOfficialAccountCustomer(ctx context.Context, r *sendRequest) error {
+	return message.CmdMpSendCustomer(ctx, &message.CmdMpSendCustomerParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		AppID:       r.str(flags.AppID),
+		AppSecret:   r.str(flags.AppSecret),
+		TokenMode:   r.str(flags.TokenMode),
+		ToUser:      r.str(flags.ToUser),
+		MsgType:     r.str(flags.MsgType),
+		KfAccount:   r.str(flags.KfAccount),
+		Data:        r.data,
+	})
 }
@@ -15,49 +15,62 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/offiaccount/message"
 )
 
 // weiXinOfficialAccountSubCmd 微信公众号订阅通知消息
-var weiXinOfficialAccountSubCmd = &cobra.Command{
-	Use:     "subscribe",
-	Aliases: []string{"sub"},
-	Short:   "publish weixin official account subscribe message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdMpBizSendSubscribeParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			AppID:       appID,
-			AppSecret:   appSecret,
-			ToUser:      toUser,
-			TemplateID:  templateID,
-			Page:        page,
-			Mini:        mini,
-			Data:        args[0],
-		}
-		if err := message.CmdMpBizSendSubscribe(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var weiXinOfficialAccountSubCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "subscribe",
+		Aliases: []string{"sub"},
+		Short:   "publish weixin official account subscribe message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg weixin offiaccount subscribe -i app_id -s app_secret -p template_id -o open_id '{\"first\":{\"value\":\"test\"}}'",
 	},
-	Example: "pmsg weixin offiaccount subscribe -i app_id -s app_secret -p template_id -o open_id '{\"first\":{\"value\":\"test\"}}'",
-}
+	flags: weiXinOfficialAccountSubFlags,
+	run:   sendWeiXinOfficialAccountSub,
+})
 
 func init() {
 	weiXinSetAccessTokenFlags(weiXinOfficialAccountSubCmd)
+	setTemplateFlags(weiXinOfficialAccountSubCmd)
+	setDryRunFlags(weiXinOfficialAccountSubCmd)
+}
+
+// weiXinOfficialAccountSubFlags 微信公众号订阅通知消息的参数
+func weiXinOfficialAccountSubFlags(fs *pflag.FlagSet) {
+	weiXinAccessTokenFlags(fs)
 
-	weiXinOfficialAccountSubCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "weixin user open id (required)")
-	weiXinOfficialAccountSubCmd.MarkFlagRequired(flags.ToUser)
+	fs.StringP(flags.ToUser, "o", "", "weixin user open id (required)")
+	cobra.MarkFlagRequired(fs, flags.ToUser)
 
-	weiXinOfficialAccountSubCmd.Flags().StringVarP(&templateID, flags.TemplateID, "p", "", "weixin template id (required)")
-	weiXinOfficialAccountSubCmd.MarkFlagRequired(flags.TemplateID)
+	fs.StringP(flags.TemplateID, "p", "", "weixin template id (required)")
+	cobra.MarkFlagRequired(fs, flags.TemplateID)
+
+	fs.String(flags.Page, "", "page")
+	fs.StringToString(flags.Mini, nil, "weixin mini program, example: app_id=XiaoChengXuAppId,page_path=index?foo=bar")
+}
 
-	weiXinOfficialAccountSubCmd.Flags().StringVar(&page, flags.Page, "", "page")
-	weiXinOfficialAccountSubCmd.Flags().StringToStringVar(&mini, flags.Mini, nil, "weixin mini program, example: app_id=XiaoChengXuAppId,page_path=index?foo=bar")
+// sendWeiXinOfficialAccountSub 发送微信公众号订阅通知消息
+func sendWeiXinOfficialAccountSub(ctx context.Context, r *sendRequest) error {
+	return message.CmdMpBizSendSubscribe(ctx, &message.CmdMpBizSendSubscribeParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		AppID:       r.str(flags.AppID),
+		AppSecret:   r.str(flags.AppSecret),
+		TokenMode:   r.str(flags.TokenMode),
+		ToUser:      r.str(flags.ToUser),
+		TemplateID:  r.str(flags.TemplateID),
+		Page:        r.str(flags.Page),
+		Mini:        r.stringMap(flags.Mini),
+		Data:        r.data,
+	})
 }
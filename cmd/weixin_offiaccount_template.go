@@ -15,56 +15,96 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/offiaccount/message"
 )
 
 // weiXinOfficialAccountTplCmd 微信公众号模板消息
-var weiXinOfficialAccountTplCmd = &cobra.Command{
-	Use:     "template",
-	Aliases: []string{"tpl"},
-	Short:   "publish weixin official account template message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdMpSendTemplateParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			AppID:       appID,
-			AppSecret:   appSecret,
-			ToUser:      toUser,
-			TemplateID:  templateID,
-			Url:         url,
-			Mini:        mini,
-			Color:       color,
-			ClientMsgID: clientMsgID,
-			Data:        args[0],
-		}
-		if err := message.CmdMpSendTemplate(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var weiXinOfficialAccountTplCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "template",
+		Aliases: []string{"tpl"},
+		Short:   "publish weixin official account template message",
+		Args:    batchArgs,
+		Example: "pmsg weixin offiaccount template -i app_id -s app_secret -p template_id -o open_id '{\"first\":{\"value\":\"test\"}}'\n" +
+			"pmsg weixin offiaccount template -i app_id -s app_secret -p template_id --batch recipients.csv",
 	},
-	Example: "pmsg weixin offiaccount template -i app_id -s app_secret -p template_id -o open_id '{\"first\":{\"value\":\"test\"}}'",
-}
+	flags: weiXinOfficialAccountTplFlags,
+	run:   sendWeiXinOfficialAccountTpl,
+	batch: sendWeiXinOfficialAccountTplBatch,
+})
 
 func init() {
 	weiXinOfficialAccountTplCmd.AddCommand(weiXinOfficialAccountTplSubCmd)
 
 	weiXinSetAccessTokenFlags(weiXinOfficialAccountTplCmd)
+	setTemplateFlags(weiXinOfficialAccountTplCmd)
+	setBatchFlags(weiXinOfficialAccountTplCmd)
+	weiXinOfficialAccountTplCmd.MarkFlagsMutuallyExclusive(flags.ClientMsgID, flags.Batch)
+	setDryRunFlags(weiXinOfficialAccountTplCmd)
+}
+
+// weiXinOfficialAccountTplFlags 微信公众号模板消息的参数
+func weiXinOfficialAccountTplFlags(fs *pflag.FlagSet) {
+	weiXinAccessTokenFlags(fs)
+
+	fs.StringP(flags.ToUser, "o", "", "weixin user open id (required unless --batch)")
 
-	weiXinOfficialAccountTplCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "weixin user open id (required)")
-	weiXinOfficialAccountTplCmd.MarkFlagRequired(flags.ToUser)
+	fs.StringP(flags.TemplateID, "p", "", "weixin template id (required)")
+	cobra.MarkFlagRequired(fs, flags.TemplateID)
 
-	weiXinOfficialAccountTplCmd.Flags().StringVarP(&templateID, flags.TemplateID, "p", "", "weixin template id (required)")
-	weiXinOfficialAccountTplCmd.MarkFlagRequired(flags.TemplateID)
+	fs.String(flags.Url, "", "url")
+	fs.StringToString(flags.Mini, nil, "weixin mini program, example: app_id=XiaoChengXuAppId,page_path=index?foo=bar")
 
-	weiXinOfficialAccountTplCmd.Flags().StringVar(&url, flags.Url, "", "url")
-	weiXinOfficialAccountTplCmd.Flags().StringToStringVar(&mini, flags.Mini, nil, "weixin mini program, example: app_id=XiaoChengXuAppId,page_path=index?foo=bar")
+	fs.String(flags.Color, "", "template color")
+	fs.StringP(flags.ClientMsgID, "c", "", "client message id (--batch derives one per row)")
+}
+
+// sendWeiXinOfficialAccountTpl 发送微信公众号模板消息
+func sendWeiXinOfficialAccountTpl(ctx context.Context, r *sendRequest) error {
+	return message.CmdMpSendTemplate(ctx, &message.CmdMpSendTemplateParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		AppID:       r.str(flags.AppID),
+		AppSecret:   r.str(flags.AppSecret),
+		TokenMode:   r.str(flags.TokenMode),
+		ToUser:      r.str(flags.ToUser),
+		TemplateID:  r.str(flags.TemplateID),
+		Url:         r.str(flags.Url),
+		Mini:        r.stringMap(flags.Mini),
+		Color:       r.str(flags.Color),
+		ClientMsgID: r.str(flags.ClientMsgID),
+		Data:        r.data,
+	})
+}
 
-	weiXinOfficialAccountTplCmd.Flags().StringVar(&color, flags.Color, "", "template color")
-	weiXinOfficialAccountTplCmd.Flags().StringVarP(&clientMsgID, flags.ClientMsgID, "c", "", "client message id")
+// sendWeiXinOfficialAccountTplBatch 按 --batch 文件批量发送微信公众号模板消息
+func sendWeiXinOfficialAccountTplBatch(ctx context.Context, r *sendRequest) error {
+	return message.CmdMpSendTemplateBatch(ctx, &message.CmdMpSendTemplateBatchParams{
+		Client:       r.client,
+		Printer:      r.printer,
+		TokenCache:   r.tokenCache,
+		AccessToken:  r.str(flags.AccessToken),
+		AppID:        r.str(flags.AppID),
+		AppSecret:    r.str(flags.AppSecret),
+		TokenMode:    r.str(flags.TokenMode),
+		TemplateID:   r.str(flags.TemplateID),
+		Url:          r.str(flags.Url),
+		Mini:         r.stringMap(flags.Mini),
+		Color:        r.str(flags.Color),
+		Data:         r.data,
+		Batch:        batchFile,
+		ResultFile:   resultFile,
+		Concurrency:  concurrency,
+		RetryFailed:  retryFailed,
+		RetryUnknown: retryUnknown,
+		Progress:     batchProgress(),
+	})
 }
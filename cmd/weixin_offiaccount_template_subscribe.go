@@ -15,57 +15,70 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/offiaccount/message"
 )
 
 // weiXinOfficialAccountTplSubCmd 微信公众号一次性订阅消息
-var weiXinOfficialAccountTplSubCmd = &cobra.Command{
-	Use:     "subscribe",
-	Aliases: []string{"sub"},
-	Short:   "publish weixin official account template subscribe message (onetime)",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdMpSendTemplateSubscribeParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			AppID:       appID,
-			AppSecret:   appSecret,
-			ToUser:      toUser,
-			TemplateID:  templateID,
-			Scene:       scene,
-			Title:       title,
-			Url:         url,
-			Mini:        mini,
-			Data:        args[0],
-		}
-		if err := message.CmdMpSendTemplateSubscribe(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var weiXinOfficialAccountTplSubCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "subscribe",
+		Aliases: []string{"sub"},
+		Short:   "publish weixin official account template subscribe message (onetime)",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg weixin offiaccount template subscribe -i app_id -s app_secret --scene scene --title title -p template_id -o open_id '{\"first\":{\"value\":\"test\"}}'",
 	},
-	Example: "pmsg weixin offiaccount template subscribe -i app_id -s app_secret --scene scene --title title -p template_id -o open_id '{\"first\":{\"value\":\"test\"}}'",
-}
+	flags: weiXinOfficialAccountTplSubFlags,
+	run:   sendWeiXinOfficialAccountTplSub,
+})
 
 func init() {
 	weiXinSetAccessTokenFlags(weiXinOfficialAccountTplSubCmd)
+	setTemplateFlags(weiXinOfficialAccountTplSubCmd)
+	setDryRunFlags(weiXinOfficialAccountTplSubCmd)
+}
+
+// weiXinOfficialAccountTplSubFlags 微信公众号一次性订阅消息的参数
+func weiXinOfficialAccountTplSubFlags(fs *pflag.FlagSet) {
+	weiXinAccessTokenFlags(fs)
 
-	weiXinOfficialAccountTplSubCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "weixin user open id (required)")
-	weiXinOfficialAccountTplSubCmd.MarkFlagRequired(flags.ToUser)
+	fs.StringP(flags.ToUser, "o", "", "weixin user open id (required)")
+	cobra.MarkFlagRequired(fs, flags.ToUser)
 
-	weiXinOfficialAccountTplSubCmd.Flags().StringVarP(&templateID, flags.TemplateID, "p", "", "weixin template id (required)")
-	weiXinOfficialAccountTplSubCmd.MarkFlagRequired(flags.TemplateID)
+	fs.StringP(flags.TemplateID, "p", "", "weixin template id (required)")
+	cobra.MarkFlagRequired(fs, flags.TemplateID)
 
-	weiXinOfficialAccountTplSubCmd.Flags().StringVar(&scene, flags.Scene, "", "weixin subscribe scene (required)")
-	weiXinOfficialAccountTplSubCmd.MarkFlagRequired(flags.Scene)
+	fs.String(flags.Scene, "", "weixin subscribe scene (required)")
+	cobra.MarkFlagRequired(fs, flags.Scene)
 
-	weiXinOfficialAccountTplSubCmd.Flags().StringVar(&title, flags.Title, "", "message title (required)")
-	weiXinOfficialAccountTplSubCmd.MarkFlagRequired(flags.Title)
+	fs.String(flags.Title, "", "message title (required)")
+	cobra.MarkFlagRequired(fs, flags.Title)
+
+	fs.String(flags.Url, "", "url")
+	fs.StringToString(flags.Mini, nil, "weixin mini program, example: app_id=XiaoChengXuAppId,page_path=index?foo=bar")
+}
 
-	weiXinOfficialAccountTplSubCmd.Flags().StringVar(&url, flags.Url, "", "url")
-	weiXinOfficialAccountTplSubCmd.Flags().StringToStringVar(&mini, flags.Mini, nil, "weixin mini program, example: app_id=XiaoChengXuAppId,page_path=index?foo=bar")
+// sendWeiXinOfficialAccountTplSub 发送微信公众号一次性订阅消息
+func sendWeiXinOfficialAccountTplSub(ctx context.Context, r *sendRequest) error {
+	return message.CmdMpSendTemplateSubscribe(ctx, &message.CmdMpSendTemplateSubscribeParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		AppID:       r.str(flags.AppID),
+		AppSecret:   r.str(flags.AppSecret),
+		TokenMode:   r.str(flags.TokenMode),
+		ToUser:      r.str(flags.ToUser),
+		TemplateID:  r.str(flags.TemplateID),
+		Scene:       r.str(flags.Scene),
+		Title:       r.str(flags.Title),
+		Url:         r.str(flags.Url),
+		Mini:        r.stringMap(flags.Mini),
+		Data:        r.data,
+	})
 }
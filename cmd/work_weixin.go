@@ -16,8 +16,10 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/weixin/work"
 )
 
 // workWeiXinCmd 企业微信
@@ -28,7 +30,9 @@ var workWeiXinCmd = &cobra.Command{
 }
 
 func init() {
-	workWeiXinCmd.PersistentFlags().StringVarP(&userAgent, flags.UserAgent, "a", "", "http user agent")
+	setHTTPClientFlags(workWeiXinCmd)
+	setAPIBaseFlag(workWeiXinCmd, &workWeiXinAPIBase, work.Host, work.HostEnv)
+	setTokenCacheFlags(workWeiXinCmd)
 
 	workWeiXinCmd.AddCommand(workWeiXinAccessTokenCmd)
 	workWeiXinCmd.AddCommand(workWeiXinAppCmd)
@@ -41,13 +45,18 @@ func init() {
 
 }
 
-// workWeiXinSetAccessTokenFlags 设置企业微信access_token或者corp_id/corp_secret命令行参数
-func workWeiXinSetAccessTokenFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&accessToken, flags.AccessToken, "t", "", "work weixin access token")
+// workWeiXinAccessTokenFlags 企业微信access_token或者corp_id/corp_secret参数
+func workWeiXinAccessTokenFlags(fs *pflag.FlagSet) {
+	fs.StringP(flags.AccessToken, "t", "", "work weixin access token")
 
-	cmd.Flags().StringVarP(&corpID, flags.CorpID, "i", "", "work weixin corp id (required if corp secret is set)")
-	cmd.Flags().StringVarP(&corpSecret, flags.CorpSecret, "s", "", "work weixin corp secret (required if corp id is set)")
+	fs.StringP(flags.CorpID, "i", "", "work weixin corp id (required if corp secret is set)")
+	fs.StringP(flags.CorpSecret, "s", "", "work weixin corp secret (required if corp id is set, unless --token_server)")
+}
 
+// workWeiXinSetAccessTokenFlags 设置企业微信access_token或者corp_id/corp_secret命令行参数的规则：access_token 和 corp_id 互斥，dry run 时可获取真实的access_token；
+// 参数由 workWeiXinAccessTokenFlags 声明
+func workWeiXinSetAccessTokenFlags(cmd *cobra.Command) {
 	cmd.MarkFlagsMutuallyExclusive(flags.AccessToken, flags.CorpID)
-	cmd.MarkFlagsRequiredTogether(flags.CorpID, flags.CorpSecret)
+
+	cmd.Flags().BoolVar(&dryRunToken, flags.DryRunToken, false, "fetch a real access token in dry run mode")
 }
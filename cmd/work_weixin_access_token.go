@@ -15,12 +15,10 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/spf13/cobra"
 
 	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin/work/token"
 )
 
@@ -29,23 +27,45 @@ var workWeiXinAccessTokenCmd = &cobra.Command{
 	Use:   "token",
 	Short: "get work weixin access token",
 	Args:  cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cacheAction != "" {
+			return tokencache.CmdCache(&tokencache.CmdCacheParams{
+				Printer:  newPrinter(),
+				Cache:    tokencache.NewFile(tokencache.DefaultFile(), tokencache.RefreshMargin),
+				Provider: token.Provider,
+				ID:       corpID,
+				Action:   cacheAction,
+			})
+		}
+		required := []string{flags.CorpID, flags.CorpSecret}
+		if tokenServer != "" {
+			// access_token 服务持有 secret
+			required = required[:1]
+		}
+		if err := requireFlags(cmd, required...); err != nil {
+			return err
+		}
+
 		arg := token.CmdWorkTokenParams{
-			UserAgent:  userAgent,
+			Client:     newHTTPClient(),
+			Printer:    newPrinter(),
+			TokenCache: newTokenCache(),
 			CorpID:     corpID,
 			CorpSecret: corpSecret,
 		}
-		if err := token.CmdWorkGetAccessToken(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+		return token.CmdWorkGetAccessToken(cmd.Context(), &arg)
 	},
-	Example: "pmsg workweixin token -i corp_id -s corp_secret",
+	Example: `pmsg workweixin token -i corp_id -s corp_secret
+pmsg workweixin token --cache show
+pmsg workweixin token --cache clear -i corp_id`,
 }
 
 func init() {
-	workWeiXinAccessTokenCmd.Flags().StringVarP(&corpID, flags.CorpID, "i", "", "work weixin corp id (required)")
-	workWeiXinAccessTokenCmd.MarkFlagRequired(flags.CorpID)
+	workWeiXinAccessTokenCmd.AddCommand(workWeiXinAccessTokenServeCmd)
+
+	workWeiXinAccessTokenCmd.Flags().StringVarP(&corpID, flags.CorpID, "i", "", "work weixin corp id (required unless --cache)")
+
+	workWeiXinAccessTokenCmd.Flags().StringVarP(&corpSecret, flags.CorpSecret, "s", "", "work weixin corp secret (required unless --cache)")
 
-	workWeiXinAccessTokenCmd.Flags().StringVarP(&corpSecret, flags.CorpSecret, "s", "", "work weixin corp secret (required)")
-	workWeiXinAccessTokenCmd.MarkFlagRequired(flags.CorpSecret)
+	workWeiXinAccessTokenCmd.Flags().StringVar(&cacheAction, flags.Cache, "", "manage the access token cache: show or clear, without fetching a token")
 }
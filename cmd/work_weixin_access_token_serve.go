@@ -0,0 +1,54 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/weixin/work/token"
+)
+
+// workWeiXinAccessTokenServeCmd 企业微信 access_token 服务
+var workWeiXinAccessTokenServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "serve work weixin access tokens to local pmsg clients",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireFlags(cmd, flags.CorpID, flags.CorpSecret, flags.TokenServerAuth); err != nil {
+			return err
+		}
+		arg := token.CmdWorkServeParams{
+			Client:     newHTTPClient(),
+			Logger:     log.New(os.Stderr, "", log.LstdFlags),
+			Listen:     listen,
+			Auth:       serverAuth,
+			CorpID:     corpID,
+			CorpSecret: corpSecret,
+		}
+		return token.CmdWorkServe(cmd.Context(), &arg)
+	},
+	Example: `pmsg workweixin token serve -i corp_id -s corp_secret --token_server_auth file:/etc/pmsg/token_server_auth
+pmsg workweixin app --token_server http://127.0.0.1:8960 --token_server_auth file:/etc/pmsg/token_server_auth -i corp_id -e agent_id -o '@all' -m text 'hello world'`,
+}
+
+func init() {
+	workWeiXinAccessTokenServeCmd.Flags().StringVarP(&corpID, flags.CorpID, "i", "", "work weixin corp id (required)")
+	workWeiXinAccessTokenServeCmd.Flags().StringVarP(&corpSecret, flags.CorpSecret, "s", "", "work weixin corp secret (required)")
+	workWeiXinAccessTokenServeCmd.Flags().StringVar(&listen, flags.Listen, "127.0.0.1:8960", "listen address")
+}
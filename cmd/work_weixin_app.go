@@ -15,61 +15,73 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/work/message"
 )
 
 // workWeiXinAppCmd 企业微信应用消息
-var workWeiXinAppCmd = &cobra.Command{
-	Use:   "app",
-	Short: "publish work weixin app message",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdWorkSendAppParams{
-			UserAgent:              userAgent,
-			AccessToken:            accessToken,
-			CorpID:                 corpID,
-			CorpSecret:             corpSecret,
-			ToUser:                 toUser,
-			ToParty:                toParty,
-			ToTag:                  toTag,
-			AgentID:                agentID,
-			MsgType:                msgType,
-			Safe:                   safe,
-			EnableIDTrans:          enableIDTrans,
-			EnableDuplicateCheck:   enableDuplicateCheck,
-			DuplicateCheckInterval: duplicateCheckInterval,
-			Data:                   args[0],
-		}
-		if err := message.CmdWorkSendApp(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var workWeiXinAppCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "app",
+		Short:   "publish work weixin app message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg workweixin app -i corp_id -s corp_secret -e agent_id -o '@all' -m text 'hello world'",
 	},
-	Example: "pmsg workweixin app -i corp_id -s corp_secret -e agent_id -o '@all' -m text 'hello world'",
-}
+	flags: workWeiXinAppFlags,
+	run:   sendWorkWeiXinApp,
+})
 
 func init() {
 	workWeiXinAppCmd.AddCommand(workWeiXinUndoAppCmd)
 
 	workWeiXinSetAccessTokenFlags(workWeiXinAppCmd)
+	setTemplateFlags(workWeiXinAppCmd)
+	setDryRunFlags(workWeiXinAppCmd)
+}
+
+// workWeiXinAppFlags 企业微信应用消息的参数
+func workWeiXinAppFlags(fs *pflag.FlagSet) {
+	workWeiXinAccessTokenFlags(fs)
 
-	workWeiXinAppCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "work weixin user id list")
-	workWeiXinAppCmd.Flags().StringVarP(&toParty, flags.ToParty, "p", "", "work weixin party id list")
-	workWeiXinAppCmd.Flags().StringVarP(&toTag, flags.ToTag, "g", "", "work weixin tag id list")
+	fs.StringP(flags.ToUser, "o", "", "work weixin user id list")
+	fs.StringP(flags.ToParty, "p", "", "work weixin party id list")
+	fs.StringP(flags.ToTag, "g", "", "work weixin tag id list")
 
-	workWeiXinAppCmd.Flags().Int64VarP(&agentID, flags.AgentID, "e", 0, "work weixin agent id (required)")
-	workWeiXinAppCmd.MarkFlagRequired(flags.AgentID)
+	fs.Int64P(flags.AgentID, "e", 0, "work weixin agent id (required)")
+	cobra.MarkFlagRequired(fs, flags.AgentID)
 
-	workWeiXinAppCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	workWeiXinAppCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
+
+	fs.Int(flags.Safe, 0, "safe")
+	fs.IntP(flags.EnableIDTrans, "r", 0, "enable id translated")
+	fs.IntP(flags.EnableDuplicateCheck, "c", 0, "enable duplicate check")
+	fs.IntP(flags.DuplicateCheckInterval, "d", 1800, "duplicate check interval")
+}
 
-	workWeiXinAppCmd.Flags().IntVar(&safe, flags.Safe, 0, "safe")
-	workWeiXinAppCmd.Flags().IntVarP(&enableIDTrans, flags.EnableIDTrans, "r", 0, "enable id translated")
-	workWeiXinAppCmd.Flags().IntVarP(&enableDuplicateCheck, flags.EnableDuplicateCheck, "c", 0, "enable duplicate check")
-	workWeiXinAppCmd.Flags().IntVarP(&duplicateCheckInterval, flags.DuplicateCheckInterval, "d", 1800, "duplicate check interval")
+// sendWorkWeiXinApp 发送企业微信应用消息
+func sendWorkWeiXinApp(ctx context.Context, r *sendRequest) error {
+	return message.CmdWorkSendApp(ctx, &message.CmdWorkSendAppParams{
+		Client:                 r.client,
+		Printer:                r.printer,
+		TokenCache:             r.tokenCache,
+		AccessToken:            r.str(flags.AccessToken),
+		CorpID:                 r.str(flags.CorpID),
+		CorpSecret:             r.str(flags.CorpSecret),
+		ToUser:                 r.str(flags.ToUser),
+		ToParty:                r.str(flags.ToParty),
+		ToTag:                  r.str(flags.ToTag),
+		AgentID:                r.int64(flags.AgentID),
+		MsgType:                r.str(flags.MsgType),
+		Safe:                   r.integer(flags.Safe),
+		EnableIDTrans:          r.integer(flags.EnableIDTrans),
+		EnableDuplicateCheck:   r.integer(flags.EnableDuplicateCheck),
+		DuplicateCheckInterval: r.integer(flags.DuplicateCheckInterval),
+		Data:                   r.data,
+	})
 }
@@ -15,34 +15,41 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/work/message"
 )
 
 // workWeiXinUndoAppCmd 撤回企业微信应用消息
-var workWeiXinUndoAppCmd = &cobra.Command{
-	Use:   "undo",
-	Short: "undo work weixin app message",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdWorkUndoAppParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			CorpID:      corpID,
-			CorpSecret:  corpSecret,
-			MsgID:       args[0],
-		}
-		if err := message.CmdWorkUndoApp(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var workWeiXinUndoAppCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "undo",
+		Short:   "undo work weixin app message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg workweixin app undo -i corp_id -s corp_secret msg_id",
 	},
-	Example: "pmsg workweixin app undo -i corp_id -s corp_secret msg_id",
-}
+	flags: workWeiXinAccessTokenFlags,
+	run:   undoWorkWeiXinApp,
+})
 
 func init() {
 	workWeiXinSetAccessTokenFlags(workWeiXinUndoAppCmd)
+	setDryRunFlags(workWeiXinUndoAppCmd)
+}
+
+// undoWorkWeiXinApp 撤回企业微信应用消息，消息内容为消息id
+func undoWorkWeiXinApp(ctx context.Context, r *sendRequest) error {
+	return message.CmdWorkUndoApp(ctx, &message.CmdWorkUndoAppParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		CorpID:      r.str(flags.CorpID),
+		CorpSecret:  r.str(flags.CorpSecret),
+		MsgID:       strings.TrimSpace(r.data),
+	})
 }
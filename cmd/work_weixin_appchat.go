@@ -15,47 +15,59 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/work/message"
 )
 
 // workWeiXinAppChatCmd 企业微信群聊推送消息
-var workWeiXinAppChatCmd = &cobra.Command{
-	Use:     "appchat",
-	Aliases: []string{"chat"},
-	Short:   "publish work weixin appchat message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdWorkSendAppChatParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			CorpID:      corpID,
-			CorpSecret:  corpSecret,
-			ChatID:      chatID,
-			MsgType:     msgType,
-			Safe:        safe,
-			Data:        args[0],
-		}
-		if err := message.CmdWorkSendAppChat(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var workWeiXinAppChatCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "appchat",
+		Aliases: []string{"chat"},
+		Short:   "publish work weixin appchat message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg workweixin appchat -i corp_id -s corp_secret -c chat_id -m text 'hello world'",
 	},
-	Example: "pmsg workweixin appchat -i corp_id -s corp_secret -c chat_id -m text 'hello world'",
-}
+	flags: workWeiXinAppChatFlags,
+	run:   sendWorkWeiXinAppChat,
+})
 
 func init() {
 	workWeiXinSetAccessTokenFlags(workWeiXinAppChatCmd)
+	setTemplateFlags(workWeiXinAppChatCmd)
+	setDryRunFlags(workWeiXinAppChatCmd)
+}
+
+// workWeiXinAppChatFlags 企业微信群聊推送消息的参数
+func workWeiXinAppChatFlags(fs *pflag.FlagSet) {
+	workWeiXinAccessTokenFlags(fs)
 
-	workWeiXinAppChatCmd.Flags().StringVarP(&chatID, flags.ChatID, "c", "", "work weixin chat id (required)")
-	workWeiXinAppChatCmd.MarkFlagRequired(flags.ChatID)
+	fs.StringP(flags.ChatID, "c", "", "work weixin chat id (required)")
+	cobra.MarkFlagRequired(fs, flags.ChatID)
 
-	workWeiXinAppChatCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	workWeiXinAppChatCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
+
+	fs.Int(flags.Safe, 0, "safe")
+}
 
-	workWeiXinAppChatCmd.Flags().IntVar(&safe, flags.Safe, 0, "safe")
+// sendWorkWeiXinAppChat 发送企业微信群聊推送消息
+func sendWorkWeiXinAppChat(ctx context.Context, r *sendRequest) error {
+	return message.CmdWorkSendAppChat(ctx, &message.CmdWorkSendAppChatParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		CorpID:      r.str(flags.CorpID),
+		CorpSecret:  r.str(flags.CorpSecret),
+		ChatID:      r.str(flags.ChatID),
+		MsgType:     r.str(flags.MsgType),
+		Safe:        r.integer(flags.Safe),
+		Data:        r.data,
+	})
 }
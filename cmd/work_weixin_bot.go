@@ -15,50 +15,64 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/work/bot"
 )
 
 // workWeiXinBotCmd 企业微信群机器人
-var workWeiXinBotCmd = &cobra.Command{
-	Use:   "bot",
-	Short: "publish work weixin group bot message",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := bot.CmdSendParams{
-			UserAgent: userAgent,
-			Key:       secret,
-			MsgType:   msgType,
-			AtUser:    atUser,
-			AtMobile:  atMobile,
-			Data:      args[0],
-		}
-		if err := bot.CmdSend(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var workWeiXinBotCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "bot",
+		Short:   "publish work weixin group bot message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg workweixin bot -k key -m text 'hello world'",
 	},
-	Example: "pmsg workweixin bot -k key -m text 'hello world'",
-}
+	flags: workWeiXinBotFlags,
+	run:   sendWorkWeiXinBot,
+})
 
 func init() {
 	workWeiXinBotCmd.AddCommand(workWeiXinBotUploadCmd)
 
-	workWeiXinBotSetKeyFlags(workWeiXinBotCmd)
+	setTemplateFlags(workWeiXinBotCmd)
+	setDryRunFlags(workWeiXinBotCmd)
+}
+
+// workWeiXinBotKeyFlags 企业微信群机器人key参数
+func workWeiXinBotKeyFlags(fs *pflag.FlagSet) {
+	fs.StringP(flags.Key, "k", "", "work weixin bot key (required)")
+	cobra.MarkFlagRequired(fs, flags.Key)
+}
+
+// workWeiXinBotFlags 企业微信群机器人消息的参数
+func workWeiXinBotFlags(fs *pflag.FlagSet) {
+	workWeiXinBotKeyFlags(fs)
 
-	workWeiXinBotCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	workWeiXinBotCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
 
-	workWeiXinBotCmd.Flags().StringVarP(&atUser, flags.AtUser, "o", "", "work weixin user id list")
-	workWeiXinBotCmd.Flags().StringVarP(&atMobile, flags.AtMobile, "b", "", "mobile list")
+	fs.StringP(flags.AtUser, "o", "", "work weixin user id list")
+	fs.StringP(flags.AtMobile, "b", "", "mobile list")
 }
 
-// workWeiXinBotSetKeyFlags 设置企业微信群机器人key命令行参数
-func workWeiXinBotSetKeyFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&secret, flags.Key, "k", "", "work weixin bot key (required)")
-	cmd.MarkFlagRequired(flags.Key)
+// sendWorkWeiXinBot 发送企业微信群机器人消息；图片消息读取本地文件，只能在命令行直接发送
+func sendWorkWeiXinBot(ctx context.Context, r *sendRequest) error {
+	if !r.local && r.str(flags.MsgType) == bot.MsgTypeImage {
+		return fmt.Errorf("invalid flags %s: %s not supported, it reads a local file", flags.MsgType, bot.MsgTypeImage)
+	}
+	return bot.CmdSend(ctx, &bot.CmdSendParams{
+		Client:   r.client,
+		Printer:  r.printer,
+		Key:      r.str(flags.Key),
+		MsgType:  r.str(flags.MsgType),
+		AtUser:   r.str(flags.AtUser),
+		AtMobile: r.str(flags.AtMobile),
+		Data:     r.data,
+	})
 }
@@ -15,11 +15,10 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/spf13/cobra"
 
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin/work/bot"
 )
 
@@ -28,19 +27,20 @@ var workWeiXinBotUploadCmd = &cobra.Command{
 	Use:   "upload",
 	Short: "work weixin group bot file upload",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		arg := bot.CmdUploadParams{
-			UserAgent: userAgent,
-			Key:       secret,
-			File:      args[0],
-		}
-		if err := bot.CmdUpload(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
+			Client:  newHTTPClient(client.WithProgress(uploadProgress())),
+			Printer: newPrinter(),
+			Key:     flagReader{cmd.Flags()}.str(flags.Key),
+			File:    args[0],
 		}
+		return bot.CmdUpload(cmd.Context(), &arg)
 	},
 	Example: "pmsg workweixin bot upload -k key /img/app.png",
 }
 
 func init() {
-	workWeiXinBotSetKeyFlags(workWeiXinBotUploadCmd)
+	workWeiXinBotKeyFlags(workWeiXinBotUploadCmd.Flags())
+
+	setDryRunFlags(workWeiXinBotUploadCmd)
 }
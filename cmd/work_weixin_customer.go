@@ -15,51 +15,63 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/work/message"
 )
 
 // workWeiXinCustomerCmd 微信客服消息
-var workWeiXinCustomerCmd = &cobra.Command{
-	Use:     "customer",
-	Aliases: []string{"kf"},
-	Short:   "publish work weixin customer message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdWorkSendCustomerParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			CorpID:      corpID,
-			CorpSecret:  corpSecret,
-			ToUser:      toUser,
-			OpenKfID:    openKfID,
-			MsgID:       msgID,
-			MsgType:     msgType,
-			Data:        args[0],
-		}
-		if err := message.CmdWorkSendCustomer(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var workWeiXinCustomerCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "customer",
+		Aliases: []string{"kf"},
+		Short:   "publish work weixin customer message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg workweixin customer -i corp_id -s corp_secret -o user_id -k kf_id -m text 'hello world'",
 	},
-	Example: "pmsg workweixin customer -i corp_id -s corp_secret -o user_id -k kf_id -m text 'hello world'",
-}
+	flags: workWeiXinCustomerFlags,
+	run:   sendWorkWeiXinCustomer,
+})
 
 func init() {
 	workWeiXinSetAccessTokenFlags(workWeiXinCustomerCmd)
+	setTemplateFlags(workWeiXinCustomerCmd)
+	setDryRunFlags(workWeiXinCustomerCmd)
+}
+
+// workWeiXinCustomerFlags 微信客服消息的参数
+func workWeiXinCustomerFlags(fs *pflag.FlagSet) {
+	workWeiXinAccessTokenFlags(fs)
 
-	workWeiXinCustomerCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "work weixin user id (required)")
-	workWeiXinCustomerCmd.MarkFlagRequired(flags.ToUser)
+	fs.StringP(flags.ToUser, "o", "", "work weixin user id (required)")
+	cobra.MarkFlagRequired(fs, flags.ToUser)
 
-	workWeiXinCustomerCmd.Flags().StringVarP(&openKfID, flags.OpenKfID, "k", "", "work weixin customer account id (required)")
-	workWeiXinCustomerCmd.MarkFlagRequired(flags.OpenKfID)
+	fs.StringP(flags.OpenKfID, "k", "", "work weixin customer account id (required)")
+	cobra.MarkFlagRequired(fs, flags.OpenKfID)
 
-	workWeiXinCustomerCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	workWeiXinCustomerCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
+
+	fs.StringP(flags.MsgID, "c", "", "message id")
+}
 
-	workWeiXinCustomerCmd.Flags().StringVarP(&msgID, flags.MsgID, "c", "", "message id")
+// sendWorkWeiXinCustomer 发送微信客服消息
+func sendWorkWeiXinCustomer(ctx context.Context, r *sendRequest) error {
+	return message.CmdWorkSendCustomer(ctx, &message.CmdWorkSendCustomerParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		CorpID:      r.str(flags.CorpID),
+		CorpSecret:  r.str(flags.CorpSecret),
+		ToUser:      r.str(flags.ToUser),
+		OpenKfID:    r.str(flags.OpenKfID),
+		MsgID:       r.str(flags.MsgID),
+		MsgType:     r.str(flags.MsgType),
+		Data:        r.data,
+	})
 }
@@ -15,74 +15,75 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-	"strings"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/work/message"
 )
 
 // workWeiXinExternalContactCmd 企业微信家校消息
-var workWeiXinExternalContactCmd = &cobra.Command{
-	Use:     "externalcontact",
-	Aliases: []string{"ec"},
-	Short:   "publish work weixin external contact message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-
-		arg := message.CmdWorkSendExternalContactParams{
-			UserAgent:              userAgent,
-			AccessToken:            accessToken,
-			CorpID:                 corpID,
-			CorpSecret:             corpSecret,
-			RecvScope:              recvScope,
-			ToAll:                  toAll,
-			MsgType:                msgType,
-			AgentID:                agentID,
-			EnableIDTrans:          enableIDTrans,
-			EnableDuplicateCheck:   enableDuplicateCheck,
-			DuplicateCheckInterval: duplicateCheckInterval,
-			Data:                   args[0],
-		}
-
-		if toParentUserID != "" {
-			arg.ToParentUserID = strings.Split(toParentUserID, "|")
-		}
-		if toStudentUserID != "" {
-			arg.ToStudentUserID = strings.Split(toStudentUserID, "|")
-		}
-		if toParty != "" {
-			arg.ToParty = strings.Split(toParty, "|")
-		}
-
-		if err := message.CmdWorkSendExternalContact(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var workWeiXinExternalContactCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "externalcontact",
+		Aliases: []string{"ec"},
+		Short:   "publish work weixin external contact message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg workweixin externalcontact -i corp_id -s corp_secret -e agent_id -n 'parentuserid1|parentuserid2' -m text 'hello world'",
 	},
-	Example: "pmsg workweixin externalcontact -i corp_id -s corp_secret -e agent_id -n 'parentuserid1|parentuserid2' -m text 'hello world'",
-}
+	flags: workWeiXinExternalContactFlags,
+	run:   sendWorkWeiXinExternalContact,
+})
 
 func init() {
 	workWeiXinSetAccessTokenFlags(workWeiXinExternalContactCmd)
+	setTemplateFlags(workWeiXinExternalContactCmd)
+	setDryRunFlags(workWeiXinExternalContactCmd)
+}
+
+// workWeiXinExternalContactFlags 企业微信家校消息的参数
+func workWeiXinExternalContactFlags(fs *pflag.FlagSet) {
+	workWeiXinAccessTokenFlags(fs)
 
-	workWeiXinExternalContactCmd.Flags().IntVarP(&recvScope, flags.RecvScope, "o", 0, "receive scope")
+	fs.IntP(flags.RecvScope, "o", 0, "receive scope")
 
-	workWeiXinExternalContactCmd.Flags().StringVarP(&toParentUserID, flags.ToParentUserID, "n", "", "work weixin parent user id list")
-	workWeiXinExternalContactCmd.Flags().StringVarP(&toStudentUserID, flags.ToStudentUserID, "u", "", "work weixin student user id list")
-	workWeiXinExternalContactCmd.Flags().StringVarP(&toParty, flags.ToParty, "p", "", "work weixin party id list")
-	workWeiXinExternalContactCmd.Flags().IntVarP(&toAll, flags.ToAll, "l", 0, "send to all user")
+	fs.StringP(flags.ToParentUserID, "n", "", "work weixin parent user id list")
+	fs.StringP(flags.ToStudentUserID, "u", "", "work weixin student user id list")
+	fs.StringP(flags.ToParty, "p", "", "work weixin party id list")
+	fs.IntP(flags.ToAll, "l", 0, "send to all user")
 
-	workWeiXinExternalContactCmd.Flags().Int64VarP(&agentID, flags.AgentID, "e", 0, "work weixin agent id (required)")
-	workWeiXinExternalContactCmd.MarkFlagRequired(flags.AgentID)
+	fs.Int64P(flags.AgentID, "e", 0, "work weixin agent id (required)")
+	cobra.MarkFlagRequired(fs, flags.AgentID)
 
-	workWeiXinExternalContactCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	workWeiXinExternalContactCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
 
-	workWeiXinExternalContactCmd.Flags().IntVarP(&enableIDTrans, flags.EnableIDTrans, "r", 0, "enable id translated")
-	workWeiXinExternalContactCmd.Flags().IntVarP(&enableDuplicateCheck, flags.EnableDuplicateCheck, "c", 0, "enable duplicate check")
-	workWeiXinExternalContactCmd.Flags().IntVarP(&duplicateCheckInterval, flags.DuplicateCheckInterval, "d", 1800, "duplicate check interval")
+	fs.IntP(flags.EnableIDTrans, "r", 0, "enable id translated")
+	fs.IntP(flags.EnableDuplicateCheck, "c", 0, "enable duplicate check")
+	fs.IntP(flags.DuplicateCheckInterval, "d", 1800, "duplicate check interval")
+}
 
+// sendWorkWeiXinExternalContact 发送企业微信家校消息，接收者列表以 ‘|’ 分隔
+func sendWorkWeiXinExternalContact(ctx context.Context, r *sendRequest) error {
+	return message.CmdWorkSendExternalContact(ctx, &message.CmdWorkSendExternalContactParams{
+		Client:                 r.client,
+		Printer:                r.printer,
+		TokenCache:             r.tokenCache,
+		AccessToken:            r.str(flags.AccessToken),
+		CorpID:                 r.str(flags.CorpID),
+		CorpSecret:             r.str(flags.CorpSecret),
+		RecvScope:              r.integer(flags.RecvScope),
+		ToParentUserID:         r.list(flags.ToParentUserID),
+		ToStudentUserID:        r.list(flags.ToStudentUserID),
+		ToParty:                r.list(flags.ToParty),
+		ToAll:                  r.integer(flags.ToAll),
+		MsgType:                r.str(flags.MsgType),
+		AgentID:                r.int64(flags.AgentID),
+		EnableIDTrans:          r.integer(flags.EnableIDTrans),
+		EnableDuplicateCheck:   r.integer(flags.EnableDuplicateCheck),
+		DuplicateCheckInterval: r.integer(flags.DuplicateCheckInterval),
+		Data:                   r.data,
+	})
 }
@@ -15,66 +15,68 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-	"strings"
+	"context"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/weixin/work/message"
 )
 
 // workWeiXinLinkedCorpCmd 企业微信互联企业消息
-var workWeiXinLinkedCorpCmd = &cobra.Command{
-	Use:     "linkedcorp",
-	Aliases: []string{"lc"},
-	Short:   "publish work weixin linked corp message",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		arg := message.CmdWorkSendLinkedCorpParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			CorpID:      corpID,
-			CorpSecret:  corpSecret,
-			ToAll:       toAll,
-			AgentID:     agentID,
-			MsgType:     msgType,
-			Safe:        safe,
-			Data:        args[0],
-		}
-
-		if toUser != "" {
-			arg.ToUser = strings.Split(toUser, "|")
-		}
-		if toParty != "" {
-			arg.ToParty = strings.Split(toParty, "|")
-		}
-		if toTag != "" {
-			arg.ToTag = strings.Split(toTag, "|")
-		}
-
-		if err := message.CmdWorkSendLinkedCorp(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+var workWeiXinLinkedCorpCmd = newSendCommand(sendRoute{
+	cmd: &cobra.Command{
+		Use:     "linkedcorp",
+		Aliases: []string{"lc"},
+		Short:   "publish work weixin linked corp message",
+		Args:    cobra.ExactArgs(1),
+		Example: "pmsg workweixin linkedcorp -i corp_id -s corp_secret -o 'userid1|userid2' -m text 'hello world'",
 	},
-	Example: "pmsg workweixin linkedcorp -i corp_id -s corp_secret -o 'userid1|userid2' -m text 'hello world'",
-}
+	flags: workWeiXinLinkedCorpFlags,
+	run:   sendWorkWeiXinLinkedCorp,
+})
 
 func init() {
 	workWeiXinSetAccessTokenFlags(workWeiXinLinkedCorpCmd)
+	setTemplateFlags(workWeiXinLinkedCorpCmd)
+	setDryRunFlags(workWeiXinLinkedCorpCmd)
+}
+
+// workWeiXinLinkedCorpFlags 企业微信互联企业消息的参数
+func workWeiXinLinkedCorpFlags(fs *pflag.FlagSet) {
+	workWeiXinAccessTokenFlags(fs)
 
-	workWeiXinLinkedCorpCmd.Flags().StringVarP(&toUser, flags.ToUser, "o", "", "work weixin user id list")
-	workWeiXinLinkedCorpCmd.Flags().StringVarP(&toParty, flags.ToParty, "p", "", "work weixin party id list")
-	workWeiXinLinkedCorpCmd.Flags().StringVarP(&toTag, flags.ToTag, "g", "", "work weixin tag id list")
-	workWeiXinLinkedCorpCmd.Flags().IntVarP(&toAll, flags.ToAll, "l", 0, "send to all user")
+	fs.StringP(flags.ToUser, "o", "", "work weixin user id list")
+	fs.StringP(flags.ToParty, "p", "", "work weixin party id list")
+	fs.StringP(flags.ToTag, "g", "", "work weixin tag id list")
+	fs.IntP(flags.ToAll, "l", 0, "send to all user")
 
-	workWeiXinLinkedCorpCmd.Flags().Int64VarP(&agentID, flags.AgentID, "e", 0, "work weixin agent id (required)")
-	workWeiXinLinkedCorpCmd.MarkFlagRequired(flags.AgentID)
+	fs.Int64P(flags.AgentID, "e", 0, "work weixin agent id (required)")
+	cobra.MarkFlagRequired(fs, flags.AgentID)
 
-	workWeiXinLinkedCorpCmd.Flags().StringVarP(&msgType, flags.MsgType, "m", "", "message type (required)")
-	workWeiXinLinkedCorpCmd.MarkFlagRequired(flags.MsgType)
+	fs.StringP(flags.MsgType, "m", "", "message type (required)")
+	cobra.MarkFlagRequired(fs, flags.MsgType)
 
-	workWeiXinLinkedCorpCmd.Flags().IntVar(&safe, flags.Safe, 0, "safe")
+	fs.Int(flags.Safe, 0, "safe")
+}
 
+// sendWorkWeiXinLinkedCorp 发送企业微信互联企业消息，接收者列表以 ‘|’ 分隔
+func sendWorkWeiXinLinkedCorp(ctx context.Context, r *sendRequest) error {
+	return message.CmdWorkSendLinkedCorp(ctx, &message.CmdWorkSendLinkedCorpParams{
+		Client:      r.client,
+		Printer:     r.printer,
+		TokenCache:  r.tokenCache,
+		AccessToken: r.str(flags.AccessToken),
+		CorpID:      r.str(flags.CorpID),
+		CorpSecret:  r.str(flags.CorpSecret),
+		ToUser:      r.list(flags.ToUser),
+		ToParty:     r.list(flags.ToParty),
+		ToTag:       r.list(flags.ToTag),
+		ToAll:       r.integer(flags.ToAll),
+		AgentID:     r.int64(flags.AgentID),
+		MsgType:     r.str(flags.MsgType),
+		Safe:        r.integer(flags.Safe),
+		Data:        r.data,
+	})
 }
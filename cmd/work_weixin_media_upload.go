@@ -15,12 +15,10 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/spf13/cobra"
 
 	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin/work/asset"
 )
 
@@ -29,25 +27,29 @@ var workWeiXinMediaUploadCmd = &cobra.Command{
 	Use:   "upload",
 	Short: "work weixin media upload",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := flagReader{cmd.Flags()}
 		arg := asset.CmdWorkMediaUploadParams{
-			UserAgent:   userAgent,
-			AccessToken: accessToken,
-			CorpID:      corpID,
-			CorpSecret:  corpSecret,
+			Client:      newHTTPClient(client.WithProgress(uploadProgress())),
+			Printer:     newPrinter(),
+			TokenCache:  newTokenCache(),
+			AccessToken: f.str(flags.AccessToken),
+			CorpID:      f.str(flags.CorpID),
+			CorpSecret:  f.str(flags.CorpSecret),
 			MediaType:   mediaType,
 			File:        args[0],
 		}
-		if err := asset.CmdWorkMediaUpload(&arg); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		}
+		return asset.CmdWorkMediaUpload(cmd.Context(), &arg)
 	},
 	Example: "pmsg workweixin upload -i corp_id -m image /img/app.png",
 }
 
 func init() {
+	workWeiXinAccessTokenFlags(workWeiXinMediaUploadCmd.Flags())
 	workWeiXinSetAccessTokenFlags(workWeiXinMediaUploadCmd)
 
 	workWeiXinMediaUploadCmd.Flags().StringVarP(&mediaType, flags.MediaType, "m", "", "media type (required)")
 	workWeiXinMediaUploadCmd.MarkFlagRequired(flags.MediaType)
+
+	setDryRunFlags(workWeiXinMediaUploadCmd)
 }
@@ -0,0 +1,110 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertmanager
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lenye/pmsg/pkg/apiserver"
+	"github.com/lenye/pmsg/pkg/fanout"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// SeverityLabel 告警级别标签
+const SeverityLabel = "severity"
+
+// severities 告警级别标签值对应的消息级别
+var severities = map[string]string{
+	"critical": notify.SeverityCritical,
+	"error":    notify.SeverityCritical,
+	"page":     notify.SeverityCritical,
+	"warning":  notify.SeverityWarning,
+	"warn":     notify.SeverityWarning,
+	"info":     notify.SeverityInfo,
+	"none":     notify.SeverityInfo,
+}
+
+// Severity 消息级别：触发中告警的最高级别，没有 severity 标签或标签值未知的告警为 warning；没有触发中的告警时为 info
+func Severity(firing []Alert) string {
+	severity := notify.SeverityInfo
+	for _, v := range firing {
+		s, ok := severities[strings.ToLower(v.Labels[SeverityLabel])]
+		if !ok {
+			s = notify.SeverityWarning
+		}
+		switch s {
+		case notify.SeverityCritical:
+			return notify.SeverityCritical
+		case notify.SeverityWarning:
+			severity = notify.SeverityWarning
+		}
+	}
+	return severity
+}
+
+// Mentions 从告警标签读取 @ 提醒的用户 id 和手机号，标签值中多个用 ‘|’ 或 ‘,’ 分隔，标签名为空时不读取
+func Mentions(alerts []Alert, userLabel, mobileLabel string) notify.Mentions {
+	var m notify.Mentions
+	seen := make(map[string]bool)
+	add := func(list *[]string, label string, labels map[string]string) {
+		if label == "" {
+			return
+		}
+		for _, v := range strings.FieldsFunc(labels[label], func(r rune) bool { return r == '|' || r == ',' }) {
+			v = strings.TrimSpace(v)
+			if v == "" || seen[label+"\x00"+v] {
+				continue
+			}
+			seen[label+"\x00"+v] = true
+			*list = append(*list, v)
+		}
+	}
+	for _, v := range alerts {
+		add(&m.Users, userLabel, v.Labels)
+		add(&m.Mobiles, mobileLabel, v.Labels)
+	}
+	return m
+}
+
+// Receiver 接收 Alertmanager webhook，渲染后发送到全部目标
+type Receiver struct {
+	Client      *client.Client
+	Dests       []fanout.Destination
+	Template    *Template
+	UserLabel   string // @ 提醒的用户 id 标签
+	MobileLabel string // @ 提醒的手机号标签
+	MinSuccess  int    // 最少成功的目标数，0 时为 1
+}
+
+// Handle 处理 webhook 请求：同一分组中触发中和已恢复的告警合并为一条消息，只 @ 触发中告警的负责人
+func (t *Receiver) Handle(ctx context.Context, req *apiserver.Request) error {
+	msg, err := ParseMessage(req.Data)
+	if err != nil {
+		return err
+	}
+	firing := msg.Firing()
+	g, err := t.Template.Render(NewData(msg), Severity(firing))
+	if err != nil {
+		return err
+	}
+	n := notify.Message{
+		Type:     notify.TypeGeneric,
+		Generic:  g,
+		Mentions: Mentions(firing, t.UserLabel, t.MobileLabel),
+	}
+	return fanout.Deliver(ctx, t.Client, req.Printer, t.Dests, n, t.MinSuccess)
+}
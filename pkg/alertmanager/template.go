@@ -0,0 +1,175 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertmanager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/render"
+)
+
+// MaxAlerts 消息中每种状态最多列出的告警数，其余只计数，避免超过平台的消息长度限制
+const MaxAlerts = 10
+
+// DefaultTemplate 默认模板，"title" 为通用消息标题，"body" 为 CommonMark 格式的正文
+//
+// 自定义模板可只重新定义其中的部分模板，如 "alert"
+const DefaultTemplate = `
+{{- define "title" -}}
+[{{if .Firing}}FIRING:{{.FiringCount}}{{end}}{{if and .Firing .Resolved}}, {{end}}{{if .Resolved}}RESOLVED:{{.ResolvedCount}}{{end}}]
+{{- with .CommonLabels.alertname}} {{.}}{{else}} {{labels .GroupLabels}}{{end}}
+{{- end}}
+
+{{- define "alert" -}}
+- **{{.Labels.alertname}}**{{with .Annotations.summary}} {{.}}{{end}}
+{{- with labels .Labels "alertname"}}
+  {{.}}{{end}}
+  {{if eq .Status "resolved"}}resolved {{formatTime "2006-01-02 15:04:05" .EndsAt.Local}}{{else}}since {{formatTime "2006-01-02 15:04:05" .StartsAt.Local}}{{end}}
+  {{- with .GeneratorURL}} [source]({{.}}){{end}}
+{{- end}}
+
+{{- define "body" -}}
+{{- if .Firing}}**Firing**
+{{range .Firing}}
+{{template "alert" .}}{{end}}
+{{- if .MoreFiring}}
+- and {{.MoreFiring}} more{{end}}
+{{end}}
+{{- if and .Firing .Resolved}}
+{{end}}
+{{- if .Resolved}}**Resolved**
+{{range .Resolved}}
+{{template "alert" .}}{{end}}
+{{- if .MoreResolved}}
+- and {{.MoreResolved}} more{{end}}
+{{end}}
+{{- with .ExternalURL}}
+[Alertmanager]({{.}}){{end}}
+{{- end}}
+`
+
+// Data 模板数据：webhook 请求，以及按状态分组的告警
+type Data struct {
+	*Message
+	Firing        []Alert // 触发中的告警，最多 MaxAlerts 个
+	Resolved      []Alert // 已恢复的告警，最多 MaxAlerts 个
+	FiringCount   int     // 触发中的告警数，含 Alertmanager 截断的告警
+	ResolvedCount int     // 已恢复的告警数
+	MoreFiring    int     // 未列出的触发中告警数
+	MoreResolved  int     // 未列出的已恢复告警数
+}
+
+// NewData 按状态分组告警
+func NewData(msg *Message) *Data {
+	d := Data{Message: msg}
+	firing, resolved := msg.Firing(), msg.Resolved()
+	d.FiringCount = len(firing) + msg.TruncatedAlerts
+	d.ResolvedCount = len(resolved)
+	d.Firing, d.MoreFiring = limit(firing, d.FiringCount)
+	d.Resolved, d.MoreResolved = limit(resolved, d.ResolvedCount)
+	return &d
+}
+
+func limit(alerts []Alert, total int) ([]Alert, int) {
+	if len(alerts) > MaxAlerts {
+		alerts = alerts[:MaxAlerts]
+	}
+	return alerts, total - len(alerts)
+}
+
+// labels 按名称排序的 k=v 列表，跳过 exclude 中的标签
+func labels(m map[string]string, exclude ...string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		skip := false
+		for _, v := range exclude {
+			skip = skip || k == v
+		}
+		if !skip {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = k + "=" + m[k]
+	}
+	return strings.Join(keys, " ")
+}
+
+// Template 告警消息模板
+type Template struct {
+	tpl *template.Template
+}
+
+// NewTemplate 解析模板，text 中的定义覆盖 DefaultTemplate 中的同名模板，text 为空时使用默认模板
+//
+// 模板函数同 --template，另有 labels，如 {{labels .Labels "alertname" "severity"}}
+func NewTemplate(text string) (*Template, error) {
+	funcs := render.Funcs("")
+	funcs["labels"] = labels
+	tpl, err := template.New("alertmanager").Funcs(funcs).Parse(DefaultTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		if tpl, err = tpl.Parse(text); err != nil {
+			return nil, fmt.Errorf("invalid template, %w", err)
+		}
+	}
+	return &Template{tpl: tpl}, nil
+}
+
+// LoadTemplate 从文件读取模板，path 为空时使用默认模板
+func LoadTemplate(path string) (*Template, error) {
+	if path == "" {
+		return NewTemplate("")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read template file failed, %w", err)
+	}
+	return NewTemplate(string(b))
+}
+
+// Render 渲染为通用消息，severity 为消息级别
+func (t *Template) Render(data *Data, severity string) (*notify.Generic, error) {
+	title, err := t.execute("title", data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := t.execute("body", data)
+	if err != nil {
+		return nil, err
+	}
+	g := notify.Generic{Title: title, Body: body, Severity: severity}
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rendered message, %v", err)
+	}
+	return &g, nil
+}
+
+func (t *Template) execute(name string, data *Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("render template %q failed, %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
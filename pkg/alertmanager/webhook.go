@@ -0,0 +1,92 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Version 支持的 webhook 版本
+const Version = "4"
+
+// 告警状态
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Alert 告警
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// Message Alertmanager webhook 请求，同一分组的告警
+//
+// https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+type Message struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"` // max_alerts 截断的告警数
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// ParseMessage 解析 webhook 请求
+func ParseMessage(data string) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return nil, fmt.Errorf("invalid alertmanager webhook json, %v", err)
+	}
+	if msg.Version != Version {
+		return nil, fmt.Errorf("unsupported alertmanager webhook version %q, want %q", msg.Version, Version)
+	}
+	if len(msg.Alerts) == 0 {
+		return nil, fmt.Errorf("alertmanager webhook has no alerts")
+	}
+	return &msg, nil
+}
+
+// Firing 触发中的告警
+func (t *Message) Firing() []Alert {
+	return t.filter(StatusFiring)
+}
+
+// Resolved 已恢复的告警
+func (t *Message) Resolved() []Alert {
+	return t.filter(StatusResolved)
+}
+
+func (t *Message) filter(status string) []Alert {
+	var alerts []Alert
+	for _, v := range t.Alerts {
+		if v.Status == status {
+			alerts = append(alerts, v)
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,268 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+)
+
+// 请求路径
+const (
+	SendPath         = "/v1/send/"        // 发送消息：POST /v1/send/{provider}/{kind}，kind 同命令，如 app、offiaccount/template
+	AlertmanagerPath = "/v1/alertmanager" // 接收 Prometheus Alertmanager webhook
+	WebhookPath      = "/v1/webhook/"     // 接收 webhook：POST /v1/webhook/{source}，如 github、gitlab、grafana
+	HealthPath       = "/healthz"         // 健康检查
+)
+
+// 服务参数
+const (
+	// MaxBodySize 请求体的最大字节数
+	MaxBodySize = 4 << 20
+	// ShutdownTimeout 停止服务时等待处理中请求的最长时间
+	ShutdownTimeout = 30 * time.Second
+)
+
+// ErrUnauthorized 请求签名或认证凭证无效，响应 http 401
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Request 发送消息请求
+type Request struct {
+	Header  http.Header     // 请求头，用于验证签名
+	Query   url.Values      // 查询参数，即命令行参数
+	Data    string          // 请求体，即消息内容
+	Printer *output.Printer // 以 json 格式输出接口响应
+}
+
+// HandlerFunc 处理发送消息请求，成功时由 Request.Printer 输出结果
+type HandlerFunc func(ctx context.Context, req *Request) error
+
+// ClassifyFunc 按错误返回错误类型和退出码，同命令行的错误输出，
+// ctx 记录了处理请求时是否发出过http请求，见 client.RequestSent
+type ClassifyFunc func(ctx context.Context, err error) (errType string, exitCode int)
+
+// Server 发送消息的 http 服务
+type Server struct {
+	auth     string
+	classify ClassifyFunc
+	logger   *log.Logger
+	routes   map[string]HandlerFunc // 请求路径对应的处理函数
+	signed   map[string]bool        // 由处理函数验证签名的请求路径
+}
+
+// NewServer 新建服务，auth 为客户端的认证凭证，为空时不认证
+func NewServer(auth string, classify ClassifyFunc, logger *log.Logger) *Server {
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	return &Server{
+		auth:     auth,
+		classify: classify,
+		logger:   logger,
+		routes:   make(map[string]HandlerFunc),
+		signed:   make(map[string]bool),
+	}
+}
+
+// Handle 注册发送消息的接口，route 为 {provider}/{kind}
+func (s *Server) Handle(route string, h HandlerFunc) {
+	s.HandlePath(SendPath+route, h)
+}
+
+// HandlePath 注册接口，path 为完整的请求路径，如 AlertmanagerPath
+func (s *Server) HandlePath(path string, h HandlerFunc) {
+	s.routes[path] = h
+}
+
+// HandleSigned 注册接口，不使用 Authorization: Bearer 认证，由处理函数验证请求签名，
+// 验证失败时返回 ErrUnauthorized，用于不能设置认证请求头的 webhook，如 GitHub、GitLab
+func (s *Server) HandleSigned(path string, h HandlerFunc) {
+	s.routes[path] = h
+	s.signed[path] = true
+}
+
+// Routes 全部接口的请求路径，已排序
+func (s *Server) Routes() []string {
+	routes := make([]string, 0, len(s.routes))
+	for k := range s.routes {
+		routes = append(routes, k)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// ValidateListen 监听非本机地址时必须设置客户端认证凭证
+func ValidateListen(addr, auth string) error {
+	if auth == "" && !IsLoopback(addr) {
+		return fmt.Errorf("invalid flags %s: required when %s is not a loopback address, got %q", flags.ServerAuth, flags.Listen, addr)
+	}
+	return nil
+}
+
+// IsLoopback 监听地址是否只接受本机连接：localhost 或回环 ip，主机为空时监听全部地址
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ListenAndServe 启动服务，ctx 结束时停止接收请求，等待处理中的请求完成后关闭；
+// 没有客户端认证凭证时只能监听本机地址
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if err := ValidateListen(addr, s.auth); err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	s.logger.Printf("pmsg server listening on %s", addr)
+	if s.auth == "" {
+		s.logger.Printf("warning: server auth is not set, any local client is able to send messages")
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.logger.Printf("pmsg server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// authorized 检查 Authorization: Bearer 认证凭证
+func (s *Server) authorized(r *http.Request) bool {
+	if s.auth == "" {
+		return true
+	}
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(auth), []byte(s.auth)) == 1
+}
+
+// statusCode 错误类型对应的 http 状态码
+func statusCode(errType string) int {
+	switch errType {
+	case "invalid":
+		return http.StatusBadRequest
+	case "interrupted":
+		return http.StatusServiceUnavailable
+	case "failure":
+		return http.StatusInternalServerError
+	}
+	// 网络、平台接口或认证错误都来自平台
+	return http.StatusBadGateway
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+// writeError 输出 json 格式的错误，同命令行 --output json 的错误输出
+func writeError(w http.ResponseWriter, code int, errType string, exitCode int, msg string) {
+	writeJSON(w, code, output.Error{Error: output.ErrorMeta{Type: errType, ExitCode: exitCode, Message: msg}})
+}
+
+// ServeHTTP POST 已注册的接口，GET /healthz 健康检查
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == HealthPath {
+		writeJSON(w, http.StatusOK, output.Result{OK: true})
+		return
+	}
+
+	h, ok := s.routes[r.URL.Path]
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid", 1, "not found: "+r.URL.Path)
+		return
+	}
+	if !s.signed[r.URL.Path] && !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", 4, "unauthorized")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "invalid", 1, "method not allowed")
+		return
+	}
+
+	start := time.Now()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", 1, fmt.Sprintf("read request body failed, %v", err))
+		return
+	}
+
+	var buf bytes.Buffer
+	req := Request{
+		Header:  r.Header,
+		Query:   r.URL.Query(),
+		Data:    string(body),
+		Printer: output.New(output.FormatJSON, &buf),
+	}
+	ctx := client.TrackRequests(r.Context())
+	if err := h(ctx, &req); err != nil {
+		errType, exitCode := s.classify(ctx, err)
+		code := statusCode(errType)
+		if errors.Is(err, ErrUnauthorized) {
+			errType, exitCode, code = "unauthorized", 4, http.StatusUnauthorized
+		}
+		msg := client.RedactError(err)
+		s.logger.Printf("%s %s %d %v: %s", r.Method, r.URL.Path, code, time.Since(start).Round(time.Millisecond), msg)
+		writeError(w, code, errType, exitCode, msg)
+		return
+	}
+	s.logger.Printf("%s %s %d %v", r.Method, r.URL.Path, http.StatusOK, time.Since(start).Round(time.Millisecond))
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
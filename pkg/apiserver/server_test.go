@@ -0,0 +1,113 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lenye/pmsg/pkg/output"
+)
+
+func TestValidateListen(t *testing.T) {
+	tests := []struct {
+		addr string
+		auth string
+		ok   bool
+	}{
+		{"127.0.0.1:8080", "", true},
+		{"localhost:8080", "", true},
+		{"[::1]:8080", "", true},
+		{":8080", "", false},
+		{"0.0.0.0:8080", "", false},
+		{"192.168.1.10:8080", "", false},
+		{"example.com:8080", "", false},
+		{"8080", "", false},
+		{":8080", "secret", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateListen(tt.addr, tt.auth); (err == nil) != tt.ok {
+			t.Errorf("ValidateListen(%q, %q) = %v, want ok %v", tt.addr, tt.auth, err, tt.ok)
+		}
+	}
+
+	// 没有认证凭证时不监听非本机地址
+	srv := NewServer("", nil, nil)
+	if err := srv.ListenAndServe(context.Background(), ":0"); err == nil || !strings.Contains(err.Error(), "server_auth") {
+		t.Errorf("ListenAndServe without auth: %v", err)
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	var calls int
+	srv := NewServer("secret", nil, nil)
+	srv.Handle("test/app", func(ctx context.Context, req *Request) error {
+		calls++
+		return req.Printer.Print("ok", "ok")
+	})
+
+	tests := []struct {
+		name string
+		auth string
+		code int
+	}{
+		{"valid", "Bearer secret", http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "Bearer secret2", http.StatusUnauthorized},
+		{"not bearer", "Basic secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			req := httptest.NewRequest(http.MethodPost, SendPath+"test/app", strings.NewReader("hi"))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != tt.code {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+			if tt.code == http.StatusOK {
+				if calls != 1 {
+					t.Errorf("handler called %d times", calls)
+				}
+				return
+			}
+			if calls != 0 {
+				t.Errorf("unauthorized request handled")
+			}
+			var body output.Error
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %s: %v", w.Body, err)
+			}
+			want := output.ErrorMeta{Type: "unauthorized", ExitCode: 4, Message: "unauthorized"}
+			if body.Error != want {
+				t.Errorf("body %+v, want %+v", body.Error, want)
+			}
+		})
+	}
+
+	// 健康检查不认证
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz status %d", w.Code)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 接收者的列名，其他列为模板数据
+const (
+	ColumnOpenID = "openid"
+	ColumnToUser = "touser"
+)
+
+// Row 批量发送的一行：接收者和模板数据
+type Row struct {
+	Index  int               // 行号，从1开始，不含 csv 的表头
+	ToUser string            // 接收者 openid
+	Data   map[string]string // 模板数据，列名为模板的字段名
+}
+
+// ClientMsgID 行的防重入id：模板id、行号和接收者的 sha256，同一行重新发送时不变
+func (r *Row) ClientMsgID(templateID string) string {
+	sum := sha256.Sum256([]byte(templateID + "\n" + strconv.Itoa(r.Index) + "\n" + r.ToUser))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ReadRows 读取批量发送的文件，支持 .csv（第一行为表头）和 .jsonl（每行一个 json 对象），
+// openid 或 touser 列为接收者，其他列为模板数据
+func ReadRows(path string) ([]Row, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []Row
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = readCSV(f)
+	case ".jsonl", ".ndjson":
+		rows, err = readJSONL(f)
+	default:
+		return nil, fmt.Errorf("unsupported batch file %q, must be .csv or .jsonl", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch file %q, %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("invalid batch file %q, no rows", path)
+	}
+	return rows, nil
+}
+
+// newRow 按列名新建一行，接收者不能为空
+func newRow(index int, fields map[string]string) (Row, error) {
+	r := Row{Index: index, Data: make(map[string]string, len(fields))}
+	for k, v := range fields {
+		switch strings.ToLower(k) {
+		case ColumnOpenID, ColumnToUser:
+			r.ToUser = strings.TrimSpace(v)
+		default:
+			r.Data[k] = v
+		}
+	}
+	if r.ToUser == "" {
+		return r, fmt.Errorf("row %d: %s not set", index, ColumnOpenID)
+	}
+	return r, nil
+}
+
+func readCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Excel 导出的 csv 带有 UTF-8 BOM
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	var rows []Row
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]string, len(header))
+		for i, name := range header {
+			fields[name] = record[i]
+		}
+		row, err := newRow(len(rows)+1, fields)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func readJSONL(r io.Reader) ([]Row, error) {
+	var rows []Row
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		index := len(rows) + 1
+		var obj map[string]any
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("row %d: %v", index, err)
+		}
+		fields := make(map[string]string, len(obj))
+		for k, v := range obj {
+			switch v := v.(type) {
+			case string:
+				fields[k] = v
+			case json.Number, bool:
+				fields[k] = fmt.Sprint(v)
+			case nil:
+			default:
+				return nil, fmt.Errorf("row %d: %s must be a string or number", index, k)
+			}
+		}
+		row, err := newRow(index, fields)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, sc.Err()
+}
+
+// Result 一行的发送结果，结果文件每行一个
+type Result struct {
+	Row     int       `json:"row"`
+	ToUser  string    `json:"touser"`
+	MsgID   string    `json:"msgid,omitempty"`   // 消息id，小程序订阅消息没有
+	Error   string    `json:"error,omitempty"`   // 发送失败的错误
+	Unknown bool      `json:"unknown,omitempty"` // 请求已发出、结果未知，可能已发送，只有 --retry_unknown 时重新发送
+	Time    time.Time `json:"time"`
+}
+
+// ResultFile 默认的结果文件：批量发送的文件名加 .result.jsonl
+func ResultFile(batchFile string) string {
+	return batchFile + ".result.jsonl"
+}
+
+// LoadResults 读取结果文件，同一行有多个结果时以最后一个为准；文件不存在时返回空
+//
+// 写入时被中断的最后一行不完整，忽略
+func LoadResults(path string) (map[int]*Result, error) {
+	results := make(map[int]*Result)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var r Result
+		if err := json.Unmarshal(line, &r); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("invalid result file %q, line %d: %v", path, i+1, err)
+		}
+		results[r.Row] = &r
+	}
+	return results, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadRows(t *testing.T) {
+	csvFile := writeFile(t, "rows.csv", "\ufeffopenid, name ,amount\noA,张三,100\noB,李四,200\n")
+	jsonlFile := writeFile(t, "rows.jsonl", `{"touser":"oA","name":"张三","amount":100}`+"\n\n"+`{"openid":"oB","name":"李四","amount":200}`+"\n")
+	for _, path := range []string{csvFile, jsonlFile} {
+		rows, err := ReadRows(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("%s: %d rows, want 2", path, len(rows))
+		}
+		if r := rows[1]; r.Index != 2 || r.ToUser != "oB" || r.Data["name"] != "李四" || r.Data["amount"] != "200" {
+			t.Errorf("%s: row 2 %+v", path, r)
+		}
+	}
+}
+
+func TestReadRowsInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty.csv":     "openid,name\n",
+		"no_user.csv":   "openid,name\n,张三\n",
+		"object.jsonl":  `{"openid":"oA","name":{"a":1}}`,
+		"rows.txt":      "openid\noA\n",
+		"invalid.jsonl": `{"openid":`,
+	} {
+		if _, err := ReadRows(writeFile(t, name, content)); err == nil {
+			t.Errorf("ReadRows(%s) succeeded, want error", name)
+		}
+	}
+}
+
+func TestRowClientMsgID(t *testing.T) {
+	row := Row{Index: 1, ToUser: "oA"}
+	id := row.ClientMsgID("tpl")
+	if len(id) != 32 {
+		t.Fatalf("client_msg_id %q, want 32 hex", id)
+	}
+	if again := row.ClientMsgID("tpl"); again != id {
+		t.Errorf("client_msg_id changed: %s, %s", id, again)
+	}
+	for _, other := range []string{
+		(&Row{Index: 2, ToUser: "oA"}).ClientMsgID("tpl"),
+		(&Row{Index: 1, ToUser: "oB"}).ClientMsgID("tpl"),
+		row.ClientMsgID("tpl2"),
+	} {
+		if other == id {
+			t.Errorf("client_msg_id %s not unique", id)
+		}
+	}
+}
+
+func TestLoadResults(t *testing.T) {
+	// 最后一行写入时被中断
+	path := writeFile(t, "rows.csv.result.jsonl",
+		`{"row":1,"touser":"oA","error":"timeout"}`+"\n"+
+			`{"row":1,"touser":"oA","msgid":"1"}`+"\n"+
+			`{"row":2,"touser":"oB","ms`)
+	results, err := LoadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[1].MsgID != "1" || results[1].Error != "" {
+		t.Fatalf("results %+v, want row 1 sent", results)
+	}
+
+	bad := writeFile(t, "bad.result.jsonl", "{\n"+`{"row":1,"touser":"oA"}`+"\n")
+	if _, err := LoadResults(bad); err == nil {
+		t.Error("LoadResults succeeded with an invalid line")
+	}
+	if results, err := LoadResults(filepath.Join(t.TempDir(), "none.jsonl")); err != nil || len(results) != 0 {
+		t.Errorf("LoadResults of a missing file: %v, %v", results, err)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/file"
+	"github.com/lenye/pmsg/pkg/http/client"
+)
+
+// Concurrency 默认的并发数
+const Concurrency = 4
+
+// SendFunc 发送一行，返回消息id
+type SendFunc func(ctx context.Context, row *Row) (msgID string, err error)
+
+// ProgressFunc 发送进度，done 包括成功、失败和结果未知，failed 包括结果未知
+type ProgressFunc func(done, failed, total int)
+
+// Summary 批量发送的统计
+type Summary struct {
+	Total      int    `json:"total"`   // 总行数
+	Sent       int    `json:"sent"`    // 本次发送成功
+	Failed     int    `json:"failed"`  // 本次发送失败
+	Unknown    int    `json:"unknown"` // 本次请求已发出、结果未知，可能已发送
+	Skipped    int    `json:"skipped"` // 之前已发送，跳过
+	ResultFile string `json:"result_file,omitempty"`
+}
+
+func (t Summary) String() string {
+	s := fmt.Sprintf("sent %d, failed %d, unknown %d, skipped %d, total %d", t.Sent, t.Failed, t.Unknown, t.Skipped, t.Total)
+	if t.ResultFile != "" {
+		s += "; results: " + t.ResultFile
+	}
+	return s
+}
+
+// Params 批量发送参数
+type Params struct {
+	Rows         []Row
+	Send         SendFunc
+	Concurrency  int          // 并发数，0 时为 Concurrency
+	ResultFile   string       // 结果文件，同时作为断点：已有结果的行跳过
+	RetryFailed  bool         // 重新发送结果文件中失败的行，不含结果未知的行
+	RetryUnknown bool         // 重新发送结果文件中结果未知的行，这些行可能已发送
+	DryRun       bool         // 只输出请求：逐行执行，不读写结果文件
+	Progress     ProgressFunc // 可选
+}
+
+// Run 按并发数发送全部行，每行的结果追加到结果文件；中断后再次执行时，跳过已有结果的行
+//
+// 发送期间对结果文件加锁，同一结果文件的多次执行依次进行，不会重复发送。
+// 请求已发出、结果未知的行（超时、中断等，见 client.ErrMaybeSent）记录为结果未知，
+// 其它被中断的行不记录结果，再次执行时重新发送；有失败的行时返回第一个错误
+func Run(ctx context.Context, p *Params) (Summary, error) {
+	summary := Summary{Total: len(p.Rows)}
+	concurrency := p.Concurrency
+	if concurrency <= 0 {
+		concurrency = Concurrency
+	}
+
+	todo := p.Rows
+	var out *os.File
+	if p.DryRun {
+		concurrency = 1
+	} else {
+		summary.ResultFile = p.ResultFile
+		unlock, err := file.LockPath(p.ResultFile)
+		if err != nil {
+			return summary, err
+		}
+		defer unlock()
+
+		results, err := LoadResults(p.ResultFile)
+		if err != nil {
+			return summary, err
+		}
+		todo = make([]Row, 0, len(p.Rows))
+		for _, row := range p.Rows {
+			r, ok := results[row.Index]
+			if !ok {
+				todo = append(todo, row)
+				continue
+			}
+			if r.ToUser != row.ToUser {
+				return summary, fmt.Errorf("result file %q does not match the batch file: row %d is %s, was %s", p.ResultFile, row.Index, row.ToUser, r.ToUser)
+			}
+			if r.Unknown && p.RetryUnknown || !r.Unknown && r.Error != "" && p.RetryFailed {
+				todo = append(todo, row)
+				continue
+			}
+			summary.Skipped++
+		}
+
+		out, err = os.OpenFile(p.ResultFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return summary, err
+		}
+		defer out.Close()
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		writeErr error
+		done     = summary.Skipped
+	)
+	record := func(row *Row, msgID string, sendErr error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		done++
+		unknown := errors.Is(sendErr, client.ErrMaybeSent)
+		if unknown {
+			summary.Unknown++
+		}
+		if sendErr != nil {
+			summary.Failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("row %d %s: %w", row.Index, row.ToUser, sendErr)
+			}
+		} else {
+			summary.Sent++
+		}
+		if p.Progress != nil {
+			p.Progress(done, summary.Failed, summary.Total)
+		}
+		if out == nil || writeErr != nil {
+			return
+		}
+		r := Result{Row: row.Index, ToUser: row.ToUser, MsgID: msgID, Unknown: unknown, Time: time.Now()}
+		if sendErr != nil {
+			r.Error = sendErr.Error()
+		}
+		line, _ := json.Marshal(r)
+		_, writeErr = out.Write(append(line, '\n'))
+	}
+
+	rows := make(chan *Row)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range rows {
+				msgID, err := p.Send(ctx, row)
+				if err != nil && ctx.Err() != nil && !errors.Is(err, client.ErrMaybeSent) {
+					// 请求未发出时中断的行不记录，再次执行时重新发送
+					continue
+				}
+				record(row, msgID, err)
+			}
+		}()
+	}
+feed:
+	for i := range todo {
+		select {
+		case rows <- &todo[i]:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(rows)
+	wg.Wait()
+
+	if writeErr != nil {
+		return summary, writeErr
+	}
+	if err := ctx.Err(); err != nil {
+		return summary, err
+	}
+	if firstErr != nil {
+		return summary, fmt.Errorf("%w; %d of %d rows failed", firstErr, summary.Failed, len(todo))
+	}
+	return summary, nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/http/client"
+)
+
+// recorder 记录发送的行，fail 中的接收者发送失败，unknown 中的接收者请求已发出、结果未知
+type recorder struct {
+	mu      sync.Mutex
+	sent    []string
+	fail    map[string]bool
+	unknown map[string]bool
+	delay   time.Duration
+}
+
+func (r *recorder) send(ctx context.Context, row *Row) (string, error) {
+	time.Sleep(r.delay)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, row.ToUser)
+	if r.fail[row.ToUser] {
+		return "", errors.New("require subscribe")
+	}
+	if r.unknown[row.ToUser] {
+		return "", fmt.Errorf("%w, %w; timeout", client.ErrRequest, client.ErrMaybeSent)
+	}
+	return "msg-" + row.ToUser, nil
+}
+
+func testRows(users ...string) []Row {
+	rows := make([]Row, len(users))
+	for i, u := range users {
+		rows[i] = Row{Index: i + 1, ToUser: u}
+	}
+	return rows
+}
+
+func TestRunResume(t *testing.T) {
+	resultFile := filepath.Join(t.TempDir(), "rows.csv.result.jsonl")
+	rows := testRows("oA", "oB", "oC")
+
+	first := &recorder{fail: map[string]bool{"oB": true}}
+	summary, err := Run(context.Background(), &Params{Rows: rows, Send: first.send, ResultFile: resultFile})
+	if err == nil || !strings.Contains(err.Error(), "row 2 oB") {
+		t.Fatalf("err %v, want row 2 failed", err)
+	}
+	if summary.Sent != 2 || summary.Failed != 1 || summary.Skipped != 0 {
+		t.Fatalf("first run %v", summary)
+	}
+	results, err := LoadResults(resultFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[1].MsgID != "msg-oA" || results[2].Error == "" {
+		t.Fatalf("checkpoint %+v", results)
+	}
+
+	// 再次执行时跳过已有结果的行，包括失败的行
+	again := &recorder{}
+	summary, err = Run(context.Background(), &Params{Rows: rows, Send: again.send, ResultFile: resultFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Skipped != 3 || len(again.sent) != 0 {
+		t.Fatalf("rerun %v, sent %v", summary, again.sent)
+	}
+
+	// --retry_failed 只重新发送失败的行
+	retry := &recorder{}
+	summary, err = Run(context.Background(), &Params{Rows: rows, Send: retry.send, ResultFile: resultFile, RetryFailed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Sent != 1 || summary.Skipped != 2 || len(retry.sent) != 1 || retry.sent[0] != "oB" {
+		t.Fatalf("retry %v, sent %v", summary, retry.sent)
+	}
+	if results, _ = LoadResults(resultFile); results[2].Error != "" || results[2].MsgID != "msg-oB" {
+		t.Fatalf("retried row 2 %+v", results[2])
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	resultFile := filepath.Join(t.TempDir(), "rows.csv.result.jsonl")
+	rows := testRows("oA", "oB", "oC")
+
+	// 发送第2行时中断，中断的行不记录结果
+	ctx, cancel := context.WithCancel(context.Background())
+	var sent []string
+	send := func(ctx context.Context, row *Row) (string, error) {
+		sent = append(sent, row.ToUser)
+		if row.Index == 2 {
+			cancel()
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return "msg-" + row.ToUser, nil
+	}
+	summary, err := Run(ctx, &Params{Rows: rows, Send: send, Concurrency: 1, ResultFile: resultFile})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err %v, want canceled", err)
+	}
+	if summary.Sent != 1 || summary.Failed != 0 {
+		t.Fatalf("interrupted %v", summary)
+	}
+
+	rest := &recorder{}
+	summary, err = Run(context.Background(), &Params{Rows: rows, Send: rest.send, ResultFile: resultFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Skipped != 1 || summary.Sent != 2 || strings.Join(rest.sent, ",") != "oB,oC" && strings.Join(rest.sent, ",") != "oC,oB" {
+		t.Fatalf("resume %v, sent %v", summary, rest.sent)
+	}
+}
+
+func TestRunUnknown(t *testing.T) {
+	resultFile := filepath.Join(t.TempDir(), "rows.csv.result.jsonl")
+	rows := testRows("oA", "oB", "oC")
+
+	first := &recorder{fail: map[string]bool{"oA": true}, unknown: map[string]bool{"oB": true}}
+	summary, err := Run(context.Background(), &Params{Rows: rows, Send: first.send, Concurrency: 1, ResultFile: resultFile})
+	if err == nil {
+		t.Fatal("err nil, want failed rows")
+	}
+	if summary.Sent != 1 || summary.Failed != 2 || summary.Unknown != 1 {
+		t.Fatalf("first run %v", summary)
+	}
+	if results, _ := LoadResults(resultFile); !results[2].Unknown || results[1].Unknown {
+		t.Fatalf("results %+v %+v", results[1], results[2])
+	}
+
+	// --retry_failed 不重新发送结果未知的行
+	retry := &recorder{}
+	summary, err = Run(context.Background(), &Params{Rows: rows, Send: retry.send, ResultFile: resultFile, RetryFailed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(retry.sent, ",") != "oA" || summary.Skipped != 2 {
+		t.Fatalf("retry failed %v, sent %v", summary, retry.sent)
+	}
+
+	// --retry_unknown 重新发送结果未知的行
+	retry = &recorder{}
+	summary, err = Run(context.Background(), &Params{Rows: rows, Send: retry.send, ResultFile: resultFile, RetryUnknown: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(retry.sent, ",") != "oB" || summary.Sent != 1 {
+		t.Fatalf("retry unknown %v, sent %v", summary, retry.sent)
+	}
+}
+
+func TestRunInterruptedAfterSent(t *testing.T) {
+	resultFile := filepath.Join(t.TempDir(), "rows.csv.result.jsonl")
+	rows := testRows("oA", "oB")
+
+	// 第1行的请求已发出后中断，记录为结果未知，再次执行时不重新发送
+	ctx, cancel := context.WithCancel(context.Background())
+	send := func(ctx context.Context, row *Row) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		cancel()
+		return "", fmt.Errorf("%w, %w; %v", client.ErrRequest, client.ErrMaybeSent, ctx.Err())
+	}
+	summary, err := Run(ctx, &Params{Rows: rows, Send: send, Concurrency: 1, ResultFile: resultFile})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err %v, want canceled", err)
+	}
+	if summary.Unknown != 1 {
+		t.Fatalf("interrupted %v", summary)
+	}
+
+	rest := &recorder{}
+	if _, err := Run(context.Background(), &Params{Rows: rows, Send: rest.send, ResultFile: resultFile}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(rest.sent, ",") != "oB" {
+		t.Fatalf("resume sent %v, want oB", rest.sent)
+	}
+}
+
+func TestRunConcurrent(t *testing.T) {
+	resultFile := filepath.Join(t.TempDir(), "rows.csv.result.jsonl")
+	rows := testRows("oA", "oB", "oC", "oD")
+
+	// 同一结果文件同时执行两次，每行只发送一次
+	r := &recorder{delay: 10 * time.Millisecond}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Run(context.Background(), &Params{Rows: rows, Send: r.send, ResultFile: resultFile}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(r.sent) != len(rows) {
+		t.Fatalf("sent %v, want each row once", r.sent)
+	}
+}
+
+func TestRunMismatch(t *testing.T) {
+	resultFile := filepath.Join(t.TempDir(), "rows.csv.result.jsonl")
+	first := &recorder{}
+	if _, err := Run(context.Background(), &Params{Rows: testRows("oA", "oB"), Send: first.send, ResultFile: resultFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 批量文件修改后，行号对应的接收者不同
+	changed := &recorder{}
+	_, err := Run(context.Background(), &Params{Rows: testRows("oA", "oX", "oC"), Send: changed.send, ResultFile: resultFile})
+	if err == nil || !strings.Contains(err.Error(), "row 2 is oX, was oB") {
+		t.Fatalf("err %v, want mismatch", err)
+	}
+	if len(changed.sent) != 0 {
+		t.Fatalf("sent %v on mismatch", changed.sent)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	resultFile := filepath.Join(t.TempDir(), "rows.csv.result.jsonl")
+	r := &recorder{}
+	summary, err := Run(context.Background(), &Params{Rows: testRows("oA", "oB"), Send: r.send, ResultFile: resultFile, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Sent != 2 || summary.ResultFile != "" {
+		t.Fatalf("dry run %v", summary)
+	}
+	if results, _ := LoadResults(resultFile); len(results) != 0 {
+		t.Fatalf("dry run wrote results %+v", results)
+	}
+}
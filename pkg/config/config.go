@@ -0,0 +1,87 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/version"
+)
+
+// Profile 命名配置：平台、接口调用凭证和默认的命令行参数
+type Profile struct {
+	Provider string            `yaml:"provider" json:"provider"`
+	Flags    map[string]string `yaml:",inline" json:"flags"` // 命令行参数名称及值，如 corp_id、to_party、msg_type
+}
+
+// Config 配置文件
+type Config struct {
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// DefaultFile 默认配置文件，如 linux 下的 ~/.config/pmsg/config.yaml
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, version.AppName, "config.yaml")
+}
+
+// Load 读取配置文件
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file failed, %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %q, %w", path, err)
+	}
+	for name, p := range cfg.Profiles {
+		if p == nil {
+			return nil, fmt.Errorf("invalid config file %q, profile %q is empty", path, name)
+		}
+		if err := notify.ValidateProvider(p.Provider); err != nil {
+			return nil, fmt.Errorf("invalid config file %q, profile %q provider: %v", path, name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// Names 全部配置名称，已排序
+func (t *Config) Names() []string {
+	names := make([]string, 0, len(t.Profiles))
+	for name := range t.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile 按名称查找配置
+func (t *Config) Profile(name string) (*Profile, error) {
+	p, ok := t.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// SecretFlags 凭证参数：输出时隐藏，支持 file:、env:、exec: 来源
+var SecretFlags = []string{flags.AccessToken, flags.Key, flags.Secret, flags.AppSecret, flags.CorpSecret, flags.TokenServerAuth, flags.ServerAuth, flags.GitHubSecret, flags.GitLabToken}
+
+// IsSecret 是否凭证参数
+func IsSecret(name string) bool {
+	for _, v := range SecretFlags {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProviderSecret 是否平台的凭证参数：凭证参数，及 Slack 含凭证的 webhook url
+func IsProviderSecret(provider, name string) bool {
+	return IsSecret(name) || (provider == notify.ProviderSlack && name == flags.Url)
+}
+
+// Mask 隐藏凭证，只保留前4个字符
+func Mask(v string) string {
+	if len(v) <= 8 {
+		return strings.Repeat("*", len(v))
+	}
+	return v[:4] + strings.Repeat("*", 8)
+}
+
+// Names 全部参数名称，已排序
+func (t Profile) Names() []string {
+	names := make([]string, 0, len(t.Flags))
+	for name := range t.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Masked 隐藏凭证后的配置
+func (t Profile) Masked() Profile {
+	m := Profile{Provider: t.Provider, Flags: make(map[string]string, len(t.Flags))}
+	for k, v := range t.Flags {
+		if IsProviderSecret(t.Provider, k) {
+			v = Mask(v)
+		}
+		m.Flags[k] = v
+	}
+	return m
+}
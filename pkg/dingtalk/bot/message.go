@@ -15,6 +15,7 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -22,6 +23,9 @@ import (
 
 	"github.com/lenye/pmsg/pkg/dingtalk"
 	"github.com/lenye/pmsg/pkg/dingtalk/client"
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/ratelimit"
 )
 
 const (
@@ -31,15 +35,16 @@ const (
 	MsgTypeFeedCard         = "feedCard"          // FeedCard
 	MsgTypeActionCard       = "actionCard"        // ActionCard
 	MsgTypeSingleActionCard = "single_actionCard" // Single ActionCard
+	MsgTypeGeneric          = notify.TypeGeneric  // 通用消息，转换为 markdown 或 actionCard
 )
 
 // ValidateMsgType 验证
 func ValidateMsgType(v string) error {
 	switch v {
-	case MsgTypeText, MsgTypeLink, MsgTypeMarkdown, MsgTypeActionCard, MsgTypeSingleActionCard, MsgTypeFeedCard:
+	case MsgTypeText, MsgTypeLink, MsgTypeMarkdown, MsgTypeActionCard, MsgTypeSingleActionCard, MsgTypeFeedCard, MsgTypeGeneric:
 	default:
-		return fmt.Errorf("%s not in [%q %q %q %q %q %q]", v,
-			MsgTypeText, MsgTypeLink, MsgTypeMarkdown, MsgTypeActionCard, MsgTypeSingleActionCard, MsgTypeFeedCard)
+		return fmt.Errorf("%s not in [%q %q %q %q %q %q %q]", v,
+			MsgTypeText, MsgTypeLink, MsgTypeMarkdown, MsgTypeActionCard, MsgTypeSingleActionCard, MsgTypeFeedCard, MsgTypeGeneric)
 	}
 	return nil
 }
@@ -55,29 +60,40 @@ type Message struct {
 	FeedCard   *FeedCardMeta `json:"feedCard,omitempty"`   // FeedCard
 }
 
-const sendURL = "https://oapi.dingtalk.com/robot/send?access_token="
+const sendPath = "/robot/send?access_token="
+
+// SendLimit 每个机器人每分钟最多发送20条消息
+var SendLimit = ratelimit.Limit{Burst: 20, Interval: time.Minute}
 
 // Send 发送钉钉自定义机器人消息
 //
 // 消息发送频率限制
 // 每个机器人每分钟最多发送20条消息到群里，如果超过20条，会限流10分钟
-func Send(accessToken, secret string, msg *Message) error {
-	u := sendURL + url.QueryEscape(accessToken)
-	if secret != "" {
+//
+// 加签的时间戳与发送时间相差不能超过1小时，每次请求（含重试）在限流等待之后加签
+func Send(ctx context.Context, c *httpClient.Client, accessToken, secret string, msg *Message) error {
+	newURL := func() (string, error) {
+		if err := c.Wait(ctx, "dingtalk:bot:"+accessToken, SendLimit); err != nil {
+			return "", err
+		}
+		u := dingtalk.URL(c, sendPath) + url.QueryEscape(accessToken)
+		if secret == "" {
+			return u, nil
+		}
 		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
 		sign, err := dingtalk.Sign(timestamp, secret)
 		if err != nil {
-			return fmt.Errorf("sign failed: %w", err)
+			return "", fmt.Errorf("sign failed: %w", err)
 		}
-		u = u + "&timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+		return u + "&timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign), nil
 	}
 	var resp dingtalk.ResponseMeta
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSONURL(ctx, c, newURL, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", dingtalk.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
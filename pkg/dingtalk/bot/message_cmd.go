@@ -15,18 +15,19 @@
 package bot
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"strings"
 
 	"github.com/lenye/pmsg/pkg/dingtalk"
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
 )
 
 type CmdSendParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
 	AccessToken string
 	Secret      string
 	MsgType     string
@@ -45,86 +46,28 @@ func (t *CmdSendParams) Validate() error {
 }
 
 // CmdSend 发送钉钉自定义机器人消息
-func CmdSend(arg *CmdSendParams) error {
+func CmdSend(ctx context.Context, arg *CmdSendParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	msg := Message{
-		MsgType: arg.MsgType,
+	n := Notifier{
+		Client:      arg.Client,
+		AccessToken: arg.AccessToken,
+		Secret:      arg.Secret,
 	}
-
-	buf := new(bytes.Buffer)
-	buf.WriteString(arg.Data)
-	switch arg.MsgType {
-	case MsgTypeText:
-		var msgMeta TextMeta
-		msgMeta.Content = buf.String()
-		msg.Text = &msgMeta
-
-		var at AtMeta
-		if arg.IsAtAll || arg.AtUser != "" || arg.AtMobile != "" {
-			if arg.AtUser != "" {
-				at.AtUserIds = strings.Split(arg.AtUser, "|")
-			}
-			if arg.AtMobile != "" {
-				at.AtMobiles = strings.Split(arg.AtMobile, "|")
-			}
-			at.IsAtAll = arg.IsAtAll
-			msg.At = &at
-		}
-	case MsgTypeLink:
-		var msgMeta LinkMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.Link = &msgMeta
-	case MsgTypeMarkdown:
-		var msgMeta MarkdownMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.Markdown = &msgMeta
-
-		var at AtMeta
-		if arg.IsAtAll || arg.AtUser != "" || arg.AtMobile != "" {
-			if arg.AtUser != "" {
-				at.AtUserIds = strings.Split(arg.AtUser, "|")
-			}
-			if arg.AtMobile != "" {
-				at.AtMobiles = strings.Split(arg.AtMobile, "|")
-			}
-			at.IsAtAll = arg.IsAtAll
-			msg.At = &at
-		}
-	case MsgTypeSingleActionCard:
-		var msgMeta SingleActionCardMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.MsgType = MsgTypeActionCard
-		msg.ActionCard = &msgMeta
-	case MsgTypeActionCard:
-		var msgMeta ActionCardMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.ActionCard = &msgMeta
-	case MsgTypeFeedCard:
-		var msgMeta FeedCardMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.FeedCard = &msgMeta
+	msg := notify.Message{
+		Type:    arg.MsgType,
+		Content: arg.Data,
+		Mentions: notify.Mentions{
+			Users:   notify.Split(arg.AtUser),
+			Mobiles: notify.Split(arg.AtMobile),
+			All:     arg.IsAtAll,
+		},
 	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if err := Send(arg.AccessToken, arg.Secret, &msg); err != nil {
+	if _, err := n.Send(ctx, msg); err != nil {
 		return err
 	}
-	fmt.Println(dingtalk.MessageOK)
-
-	return nil
+	return arg.Printer.Print(dingtalk.MessageOK, nil)
 }
@@ -0,0 +1,86 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/dingtalk"
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/ratelimit"
+)
+
+// slowLimiter 每次等待 delay，记录等待结束的时间
+type slowLimiter struct {
+	mu    sync.Mutex
+	delay time.Duration
+	done  []time.Time
+}
+
+func (t *slowLimiter) Wait(ctx context.Context, key string, limit ratelimit.Limit) error {
+	time.Sleep(t.delay)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = append(t.done, time.Now())
+	return nil
+}
+
+func TestSendSignPerAttempt(t *testing.T) {
+	const secret = "SEC-test"
+	var timestamps []int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		ts, err := strconv.ParseInt(q.Get("timestamp"), 10, 64)
+		if err != nil {
+			t.Errorf("timestamp %q", q.Get("timestamp"))
+		}
+		if sign, _ := dingtalk.Sign(q.Get("timestamp"), secret); q.Get("sign") != sign {
+			t.Errorf("sign %q, want %q", q.Get("sign"), sign)
+		}
+		timestamps = append(timestamps, ts)
+		if len(timestamps) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+
+	limiter := &slowLimiter{delay: 30 * time.Millisecond}
+	c := httpClient.New(
+		httpClient.WithBaseURL(dingtalk.Host, srv.URL),
+		httpClient.WithLimiter(limiter),
+		httpClient.WithRetry(httpClient.RetryPolicy{MaxAttempts: 2, BaseWait: 10 * time.Millisecond}),
+	)
+	if err := Send(context.Background(), c, "token", secret, &Message{MsgType: MsgTypeText, Text: &TextMeta{Content: "hi"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每次请求（含重试）限流一次，在限流等待之后加签
+	if len(timestamps) != 2 || len(limiter.done) != 2 {
+		t.Fatalf("requests %d, waits %d, want 2", len(timestamps), len(limiter.done))
+	}
+	for i, ts := range timestamps {
+		if ts < limiter.done[i].UnixMilli() {
+			t.Errorf("attempt %d signed at %d before the limiter wait ended at %d", i+1, ts, limiter.done[i].UnixMilli())
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// NewMessage 按消息类型新建钉钉自定义机器人消息，text、markdown、generic 支持 @ 提醒，其他消息类型有 @ 提醒时返回错误
+func NewMessage(msgType, content string, mentions notify.Mentions) (*Message, error) {
+	if err := ValidateMsgType(msgType); err != nil {
+		return nil, err
+	}
+	switch msgType {
+	case MsgTypeGeneric, MsgTypeText, MsgTypeMarkdown:
+	default:
+		if !mentions.Empty() {
+			return nil, fmt.Errorf("%s message does not support @ mentions, use %s or %s", msgType, MsgTypeText, MsgTypeMarkdown)
+		}
+	}
+
+	msg := Message{
+		MsgType: msgType,
+	}
+
+	at := newAt(mentions)
+
+	switch msgType {
+	case MsgTypeGeneric:
+		g, err := notify.ParseGeneric(content)
+		if err != nil {
+			return nil, err
+		}
+		return NewGenericMessage(g, mentions), nil
+	case MsgTypeText:
+		msg.Text = &TextMeta{Content: content}
+		msg.At = at
+	case MsgTypeLink:
+		var msgMeta LinkMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		msg.Link = &msgMeta
+	case MsgTypeMarkdown:
+		var msgMeta MarkdownMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		msg.Markdown = &msgMeta
+		msg.At = at
+	case MsgTypeSingleActionCard:
+		var msgMeta SingleActionCardMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		msg.MsgType = MsgTypeActionCard
+		msg.ActionCard = &msgMeta
+	case MsgTypeActionCard:
+		var msgMeta ActionCardMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		msg.ActionCard = &msgMeta
+	case MsgTypeFeedCard:
+		var msgMeta FeedCardMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		msg.FeedCard = &msgMeta
+	}
+	return &msg, nil
+}
+
+func newAt(mentions notify.Mentions) *AtMeta {
+	if mentions.Empty() {
+		return nil
+	}
+	return &AtMeta{
+		AtUserIds: mentions.Users,
+		AtMobiles: mentions.Mobiles,
+		IsAtAll:   mentions.All,
+	}
+}
+
+// NewGenericMessage 通用消息转换为钉钉消息：有按钮时为 actionCard，否则为 markdown；
+// actionCard 不支持 @ 提醒，有 @ 提醒时为 markdown，按钮作为链接
+func NewGenericMessage(g *notify.Generic, mentions notify.Mentions) *Message {
+	title := g.FullTitle()
+	if title == "" {
+		title = notify.Truncate(notify.PlainText(g.Body), 32)
+	}
+
+	if len(g.Buttons) > 0 && mentions.Empty() {
+		card := ActionCardMeta{
+			Title: title,
+			Text:  g.Markdown(true, true, false),
+		}
+		for _, v := range g.Buttons {
+			card.Btns = append(card.Btns, ActionCardBtnMeta{Title: v.Title, ActionURL: v.URL})
+		}
+		return &Message{MsgType: MsgTypeActionCard, ActionCard: &card}
+	}
+
+	// markdown 消息需要在正文中 @ 手机号或用户 id 才会显示提醒
+	text := g.Markdown(true, true, true)
+	for _, v := range mentions.Mobiles {
+		text += " @" + v
+	}
+	for _, v := range mentions.Users {
+		text += " @" + v
+	}
+	return &Message{
+		MsgType:  MsgTypeMarkdown,
+		Markdown: &MarkdownMeta{Title: title, Text: text},
+		At:       newAt(mentions),
+	}
+}
+
+// Notifier 钉钉自定义机器人
+type Notifier struct {
+	Client      *httpClient.Client
+	AccessToken string
+	Secret      string // 加签密钥，没有时不加签
+}
+
+// Send 发送钉钉自定义机器人消息
+func (t *Notifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderDingTalk}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	var m *Message
+	if g != nil {
+		m = NewGenericMessage(g, msg.Mentions)
+	} else if m, err = NewMessage(msg.Type, msg.Content, msg.Mentions); err != nil {
+		return result, err
+	}
+	return result, Send(ctx, t.Client, t.AccessToken, t.Secret, m)
+}
@@ -0,0 +1,77 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+func TestNewGenericMessageMentions(t *testing.T) {
+	g := &notify.Generic{
+		Title:   "部署失败",
+		Body:    "**api** 部署失败",
+		Buttons: []notify.Link{{Title: "查看", URL: "https://example.com/run/1"}},
+	}
+
+	msg := NewGenericMessage(g, notify.Mentions{})
+	if msg.MsgType != MsgTypeActionCard || msg.At != nil {
+		t.Fatalf("no mentions: msgtype %s, at %+v", msg.MsgType, msg.At)
+	}
+
+	// actionCard 不支持 @ 提醒，按钮以链接显示
+	mentions := notify.Mentions{Users: []string{"u1"}, Mobiles: []string{"13800000000"}, All: true}
+	msg = NewGenericMessage(g, mentions)
+	if msg.MsgType != MsgTypeMarkdown {
+		t.Fatalf("mentions: msgtype %s, want %s", msg.MsgType, MsgTypeMarkdown)
+	}
+	want := &AtMeta{AtUserIds: []string{"u1"}, AtMobiles: []string{"13800000000"}, IsAtAll: true}
+	if !reflect.DeepEqual(msg.At, want) {
+		t.Errorf("at %+v, want %+v", msg.At, want)
+	}
+	for _, v := range []string{"[查看](https://example.com/run/1)", "@13800000000", "@u1"} {
+		if !strings.Contains(msg.Markdown.Text, v) {
+			t.Errorf("markdown %q, want %q", msg.Markdown.Text, v)
+		}
+	}
+}
+
+func TestNewMessageMentions(t *testing.T) {
+	card := `{"title":"t","text":"x","btns":[{"title":"b","actionURL":"https://example.com"}]}`
+	tests := []struct {
+		name     string
+		msgType  string
+		content  string
+		mentions notify.Mentions
+		wantErr  bool
+	}{
+		{"text", MsgTypeText, "hi", notify.Mentions{All: true}, false},
+		{"markdown", MsgTypeMarkdown, `{"title":"t","text":"x"}`, notify.Mentions{Users: []string{"u1"}}, false},
+		{"actionCard", MsgTypeActionCard, card, notify.Mentions{}, false},
+		{"actionCard mentions", MsgTypeActionCard, card, notify.Mentions{Mobiles: []string{"13800000000"}}, true},
+		{"link all", MsgTypeLink, `{"title":"t","text":"x","messageUrl":"https://example.com"}`, notify.Mentions{All: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMessage(tt.msgType, tt.content, tt.mentions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
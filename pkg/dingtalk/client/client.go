@@ -16,44 +16,76 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/lenye/pmsg/pkg/dingtalk"
 	httpClient "github.com/lenye/pmsg/pkg/http/client"
 )
 
 // CheckHttpResponseStatusCode 检查HTTP响应状态码
 func CheckHttpResponseStatusCode(method, url string, statusCode int) error {
 	if statusCode/100 != 2 {
-		return fmt.Errorf("%w; http response status code: %v, %s %s", httpClient.ErrRequest, statusCode, method, url)
+		return fmt.Errorf("%w; http response status code: %v, %s %s", httpClient.ErrRequest, statusCode, method, httpClient.RedactURL(url))
 	}
 	return nil
 }
 
-// PostJSON http post json
-func PostJSON(url string, reqBody, respBody any) (http.Header, error) {
-	buf := new(bytes.Buffer)
-	enc := json.NewEncoder(buf)
-	enc.SetEscapeHTML(false)
-	err := enc.Encode(reqBody)
-	if err != nil {
+// decodeJSON 检查http响应并解析json，可重试时返回 *httpClient.RetryableError
+func decodeJSON(method, url string, resp *http.Response, err error, respBody any) (http.Header, error) {
+	if errors.Is(err, httpClient.ErrDryRun) {
 		return nil, err
 	}
-
-	resp, err := httpClient.Post(url, httpClient.HdrValContentTypeJson, bytes.NewReader(buf.Bytes()))
 	if err != nil {
-		return nil, fmt.Errorf("%w; %s %s, %v", httpClient.ErrRequest, http.MethodPost, url, err)
+		return nil, httpClient.RequestError(method, url, err, false)
 	}
 	defer resp.Body.Close()
 
-	if err := CheckHttpResponseStatusCode(http.MethodPost, url, resp.StatusCode); err != nil {
-		return nil, err
+	if err := CheckHttpResponseStatusCode(method, url, resp.StatusCode); err != nil {
+		return nil, httpClient.StatusError(err, resp.StatusCode, resp.Header, false)
 	}
 
 	if respBody == nil {
 		return resp.Header, nil
 	}
 
-	return resp.Header, json.NewDecoder(resp.Body).Decode(respBody)
+	if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+		return resp.Header, fmt.Errorf("%w; invalid response json, %s %s, %v", dingtalk.ErrRequest, method, httpClient.RedactURL(url), err)
+	}
+	if r, ok := respBody.(httpClient.Retryer); ok && r.Retryable() {
+		return resp.Header, httpClient.Retryable(nil, 0)
+	}
+	return resp.Header, nil
+}
+
+// PostJSON http post json
+func PostJSON(ctx context.Context, c *httpClient.Client, url string, reqBody, respBody any) (http.Header, error) {
+	return PostJSONURL(ctx, c, func() (string, error) { return url, nil }, reqBody, respBody)
+}
+
+// PostJSONURL http post json，每次请求（含重试）前调用 newURL 生成请求地址，用于加签等与发送时间有关的地址
+func PostJSONURL(ctx context.Context, c *httpClient.Client, newURL func() (string, error), reqBody, respBody any) (http.Header, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var header http.Header
+	err = c.Retry(ctx, func() error {
+		url, err := newURL()
+		if err != nil {
+			return err
+		}
+		httpClient.ResetBody(respBody)
+		resp, err := c.Post(ctx, url, httpClient.HdrValContentTypeJson, bytes.NewReader(buf.Bytes()))
+		header, err = decodeJSON(http.MethodPost, url, resp, err, respBody)
+		return err
+	})
+	return header, err
 }
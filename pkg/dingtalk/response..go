@@ -24,8 +24,24 @@ const (
 	MessageOK = "ok"
 )
 
+const (
+	CodeSystemBusy  = -1     // 系统繁忙
+	CodeSendTooFast = 130101 // 发送速度太快而限流
+)
+
+const (
+	CodeTokenNotExist = 300001 // 机器人 access_token 不存在
+	CodeSecurity      = 310000 // 不满足机器人安全设置：签名、关键词或IP地址
+)
+
 var ErrRequest = errors.New("dingtalk request error")
 
+// ErrUnauthorized 机器人 access_token 无效或不满足安全设置
+var ErrUnauthorized = fmt.Errorf("%w: unauthorized", ErrRequest)
+
+// ErrBusy 系统繁忙或机器人发送过快，稍后可重试
+var ErrBusy = fmt.Errorf("%w: busy or rate limited", ErrRequest)
+
 // ResponseMeta 响应操作信息
 type ResponseMeta struct {
 	ErrorCode    int64  `json:"errcode"`          // 出错返回码，为0表示成功，非0表示调用失败
@@ -40,3 +56,32 @@ func (t ResponseMeta) String() string {
 func (t ResponseMeta) Succeed() bool {
 	return t.ErrorCode == CodeOK
 }
+
+// Unauthorized 是否为机器人认证错误
+func (t ResponseMeta) Unauthorized() bool {
+	switch t.ErrorCode {
+	case CodeTokenNotExist, CodeSecurity:
+		return true
+	}
+	return false
+}
+
+// Err 返回码对应的错误
+func (t ResponseMeta) Err() error {
+	if t.Unauthorized() {
+		return ErrUnauthorized
+	}
+	if t.Retryable() {
+		return ErrBusy
+	}
+	return ErrRequest
+}
+
+// Retryable 是否可重试
+func (t ResponseMeta) Retryable() bool {
+	switch t.ErrorCode {
+	case CodeSystemBusy, CodeSendTooFast:
+		return true
+	}
+	return false
+}
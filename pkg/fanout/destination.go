@@ -0,0 +1,103 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fanout
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lenye/pmsg/pkg/config"
+	dingTalkBot "github.com/lenye/pmsg/pkg/dingtalk/bot"
+	feiShuBot "github.com/lenye/pmsg/pkg/feishu/bot"
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	slackBot "github.com/lenye/pmsg/pkg/slack/bot"
+	"github.com/lenye/pmsg/pkg/source"
+	workBot "github.com/lenye/pmsg/pkg/weixin/work/bot"
+)
+
+// 发送目标的类型
+const (
+	SchemeWorkWeiXinBot = "wecom-bot" // 企业微信群机器人，wecom-bot:key
+	SchemeDingTalk      = "dingtalk"  // 钉钉自定义机器人，dingtalk:access_token[:secret]
+	SchemeFeiShu        = "feishu"    // 飞书自定义机器人，feishu:access_token[:secret]
+	SchemeSlack         = "slack"     // slack incoming webhook，slack:webhook_url
+)
+
+// Destination 发送目标
+type Destination struct {
+	Scheme string
+	Target string // 机器人 key、access_token 或 webhook url
+	Secret string // 加签密钥，没有时不加签
+}
+
+// ParseDestination 解析发送目标，如 wecom-bot:key、dingtalk:access_token:secret、slack:https://hooks.slack.com/...
+//
+// 类型之后的部分支持 file:、env:、exec: 来源，读取后再拆分 access_token 和 secret
+func ParseDestination(ctx context.Context, v string) (Destination, error) {
+	scheme, value, ok := strings.Cut(v, ":")
+	if !ok || value == "" {
+		return Destination{}, fmt.Errorf("invalid destination %q, format is type:target", v)
+	}
+	value, err := source.Secret(ctx, value)
+	if err != nil {
+		return Destination{}, fmt.Errorf("invalid destination %s:, %v", scheme, err)
+	}
+
+	dest := Destination{Scheme: scheme, Target: value}
+	switch scheme {
+	case SchemeWorkWeiXinBot:
+	case SchemeDingTalk, SchemeFeiShu:
+		dest.Target, dest.Secret, _ = strings.Cut(value, ":")
+	case SchemeSlack:
+		if u, err := url.Parse(value); err != nil || u.Host == "" {
+			return Destination{}, fmt.Errorf("invalid destination %s:, invalid webhook url", scheme)
+		}
+	default:
+		return Destination{}, fmt.Errorf("invalid destination %q, type not in [%q %q %q %q]", v,
+			SchemeWorkWeiXinBot, SchemeDingTalk, SchemeFeiShu, SchemeSlack)
+	}
+	if dest.Target == "" {
+		return Destination{}, fmt.Errorf("invalid destination %s:, target is empty", scheme)
+	}
+	return dest, nil
+}
+
+// String 隐藏凭证的发送目标
+func (t Destination) String() string {
+	if t.Scheme == SchemeSlack {
+		if u, err := url.Parse(t.Target); err == nil {
+			return t.Scheme + ":" + u.Scheme + "://" + u.Host + "/" + config.Mask(strings.TrimPrefix(u.Path, "/"))
+		}
+	}
+	return t.Scheme + ":" + config.Mask(t.Target)
+}
+
+// Notifier 发送目标对应的 Notifier
+func (t Destination) Notifier(c *httpClient.Client) notify.Notifier {
+	switch t.Scheme {
+	case SchemeWorkWeiXinBot:
+		return &workBot.Notifier{Client: c, Key: t.Target}
+	case SchemeDingTalk:
+		return &dingTalkBot.Notifier{Client: c, AccessToken: t.Target, Secret: t.Secret}
+	case SchemeFeiShu:
+		return &feiShuBot.Notifier{Client: c, AccessToken: t.Target, Secret: t.Secret}
+	case SchemeSlack:
+		return &slackBot.Notifier{Client: c, URL: t.Target}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// Result 发送到一个目标的结果
+type Result struct {
+	To    string `json:"to"`              // 隐藏凭证的发送目标
+	OK    bool   `json:"ok"`              // 是否成功
+	Error string `json:"error,omitempty"` // 错误信息
+	notify.Result
+	Err error `json:"-"`
+}
+
+func (t Result) String() string {
+	if t.OK {
+		return "ok     " + t.To
+	}
+	return "failed " + t.To + ": " + t.Error
+}
+
+// Send 并发发送消息到全部目标，结果顺序同 dests
+//
+// dry run 模式下依次发送，避免输出的请求交错
+func Send(ctx context.Context, c *httpClient.Client, dests []Destination, msg notify.Message) []Result {
+	results := make([]Result, len(dests))
+	send := func(i int) {
+		result, err := dests[i].Notifier(c).Send(ctx, msg)
+		results[i] = Result{To: dests[i].String(), OK: err == nil, Result: result, Err: err}
+		if err != nil {
+			results[i].Error = httpClient.RedactError(err)
+		}
+	}
+
+	if c.DryRun() {
+		for i := range dests {
+			send(i)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i := range dests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			send(i)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// Report 发送报告
+type Report struct {
+	Total      int      `json:"total"`       // 目标数
+	Succeeded  int      `json:"succeeded"`   // 成功数
+	MinSuccess int      `json:"min_success"` // 最少成功数
+	Results    []Result `json:"results"`     // 每个目标的结果
+}
+
+// NewReport 汇总发送结果，minSuccess 为最少成功数
+func NewReport(results []Result, minSuccess int) Report {
+	r := Report{Total: len(results), MinSuccess: minSuccess, Results: results}
+	for _, v := range results {
+		if v.OK {
+			r.Succeeded++
+		}
+	}
+	return r
+}
+
+func (t Report) String() string {
+	sb := make([]string, 0, len(t.Results)+1)
+	for _, v := range t.Results {
+		sb = append(sb, v.String())
+	}
+	sb = append(sb, fmt.Sprintf("%d/%d succeeded", t.Succeeded, t.Total))
+	return strings.Join(sb, "\n")
+}
+
+// Err 成功数少于最少成功数时返回错误，包含第一个失败目标的错误，用于确定退出码
+func (t Report) Err() error {
+	if t.Succeeded >= t.MinSuccess {
+		return nil
+	}
+	for _, v := range t.Results {
+		if v.Err != nil {
+			return fmt.Errorf("%d of %d destinations succeeded, required %d; %s: %w", t.Succeeded, t.Total, t.MinSuccess, v.To, v.Err)
+		}
+	}
+	return errors.New("no destination succeeded")
+}
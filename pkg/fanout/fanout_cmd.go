@@ -0,0 +1,129 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
+)
+
+type CmdSendParams struct {
+	Client       *client.Client
+	Printer      *output.Printer
+	To           []string
+	MsgType      string
+	AtUser       string
+	AtMobile     string
+	IsAtAll      bool
+	AllOrNothing bool
+	MinSuccess   int // 最少成功的目标数，0 时为 1
+	Data         string
+}
+
+func (t *CmdSendParams) Validate() error {
+	if len(t.To) == 0 {
+		return fmt.Errorf("invalid flags %s: at least one destination is required", flags.To)
+	}
+	if t.MinSuccess < 0 || t.MinSuccess > len(t.To) {
+		return fmt.Errorf("invalid flags %s: must be between 1 and the number of destinations %d", flags.MinSuccess, len(t.To))
+	}
+	if t.AllOrNothing && t.MinSuccess > 0 {
+		return fmt.Errorf("invalid flags: %s and %s cannot be used together", flags.AllOrNothing, flags.MinSuccess)
+	}
+	return nil
+}
+
+// CmdSend 并发发送消息到多个机器人，输出每个目标的结果，成功数少于要求时返回错误
+func CmdSend(ctx context.Context, arg *CmdSendParams) error {
+
+	if err := arg.Validate(); err != nil {
+		return err
+	}
+
+	dests, err := ParseDestinations(ctx, arg.To)
+	if err != nil {
+		return err
+	}
+
+	msg := notify.Message{
+		Type:    arg.MsgType,
+		Content: arg.Data,
+		Mentions: notify.Mentions{
+			Users:   notify.Split(arg.AtUser),
+			Mobiles: notify.Split(arg.AtMobile),
+			All:     arg.IsAtAll,
+		},
+	}
+	if msg.Type == "" || msg.Type == notify.TypeGeneric {
+		// 通用消息只解析一次，消息内容无效时不发送
+		g, err := notify.ParseGeneric(arg.Data)
+		if err != nil {
+			return err
+		}
+		msg.Generic = g
+	}
+
+	minSuccess := arg.MinSuccess
+	if arg.AllOrNothing {
+		minSuccess = len(dests)
+	}
+	return Deliver(ctx, arg.Client, arg.Printer, dests, msg, minSuccess)
+}
+
+// ParseDestinations 解析 --to 参数的全部发送目标
+func ParseDestinations(ctx context.Context, to []string) ([]Destination, error) {
+	dests := make([]Destination, 0, len(to))
+	for _, v := range to {
+		dest, err := ParseDestination(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flags %s: %v", flags.To, err)
+		}
+		dests = append(dests, dest)
+	}
+	return dests, nil
+}
+
+// Deliver 发送消息到全部目标并输出发送报告，成功数少于 minSuccess 时返回错误，minSuccess 为 0 时为 1
+func Deliver(ctx context.Context, c *client.Client, printer *output.Printer, dests []Destination, msg notify.Message, minSuccess int) error {
+	results := Send(ctx, c, dests, msg)
+	if c.DryRun() {
+		for _, v := range results {
+			if !errors.Is(v.Err, client.ErrDryRun) {
+				return fmt.Errorf("%s: %w", v.To, v.Err)
+			}
+		}
+		return client.ErrDryRun
+	}
+	for _, v := range results {
+		if errors.Is(v.Err, context.Canceled) {
+			return v.Err
+		}
+	}
+
+	if minSuccess == 0 {
+		minSuccess = 1
+	}
+	report := NewReport(results, minSuccess)
+	if err := printer.Print(report.String(), report); err != nil {
+		return err
+	}
+	return report.Err()
+}
@@ -15,10 +15,15 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/lenye/pmsg/pkg/feishu"
 	"github.com/lenye/pmsg/pkg/feishu/client"
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/ratelimit"
 )
 
 const (
@@ -27,15 +32,17 @@ const (
 	MsgTypeImage       = "image"       // 图片
 	MsgTypeShareChat   = "share_chat"  // 分享群名片
 	MsgTypeInteractive = "interactive" // 消息卡片
+
+	MsgTypeGeneric = notify.TypeGeneric // 通用消息，转换为消息卡片
 )
 
 // ValidateMsgType 验证
 func ValidateMsgType(v string) error {
 	switch v {
-	case MsgTypeText, MsgTypePost, MsgTypeImage, MsgTypeShareChat, MsgTypeInteractive:
+	case MsgTypeText, MsgTypePost, MsgTypeImage, MsgTypeShareChat, MsgTypeInteractive, MsgTypeGeneric:
 	default:
-		return fmt.Errorf("%s not in [%q %q %q %q %q]", v,
-			MsgTypeText, MsgTypePost, MsgTypeImage, MsgTypeShareChat, MsgTypeInteractive)
+		return fmt.Errorf("%s not in [%q %q %q %q %q %q]", v,
+			MsgTypeText, MsgTypePost, MsgTypeImage, MsgTypeShareChat, MsgTypeInteractive, MsgTypeGeneric)
 	}
 	return nil
 }
@@ -49,18 +56,22 @@ type Message struct {
 	Card      *CardMeta    `json:"card,omitempty"`      // 消息卡片
 }
 
-const sendURL = "https://open.feishu.cn/open-apis/bot/v2/hook/"
+const sendPath = "/open-apis/bot/v2/hook/"
+
+// SendLimit 每个机器人每分钟最多发送100条消息，每秒最多5条
+var SendLimit = ratelimit.Limit{Burst: 5, Interval: 3 * time.Second}
 
 // Send 发送飞书自定义机器人消息
-func Send(accessToken string, msg *Message) error {
-	u := sendURL + accessToken
+func Send(ctx context.Context, c *httpClient.Client, accessToken string, msg *Message) error {
+	u := feishu.URL(c, sendPath) + accessToken
+	ctx = httpClient.WithRateLimit(ctx, "feishu:bot:"+accessToken, SendLimit)
 	var resp feishu.ResponseMeta
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", feishu.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
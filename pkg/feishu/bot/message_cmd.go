@@ -15,19 +15,19 @@
 package bot
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"strconv"
-	"time"
 
 	"github.com/lenye/pmsg/pkg/feishu"
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
 )
 
 type CmdSendParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
 	AccessToken string
 	Secret      string
 	MsgType     string
@@ -43,64 +43,19 @@ func (t *CmdSendParams) Validate() error {
 }
 
 // CmdSend 发送飞书自定义机器人消息
-func CmdSend(arg *CmdSendParams) error {
+func CmdSend(ctx context.Context, arg *CmdSendParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	msg := Message{
-		MsgType: arg.MsgType,
+	n := Notifier{
+		Client:      arg.Client,
+		AccessToken: arg.AccessToken,
+		Secret:      arg.Secret,
 	}
-
-	if arg.Secret != "" {
-		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-		sign, err := feishu.Sign(timestamp, arg.Secret)
-		if err != nil {
-			return fmt.Errorf("sign failed: %w", err)
-		}
-		msg.TimeStamp = timestamp
-		msg.Sign = sign
-	}
-
-	buf := new(bytes.Buffer)
-	buf.WriteString(arg.Data)
-	switch arg.MsgType {
-	case MsgTypeText:
-		var msgMeta ContentMeta
-		msgMeta.Text = buf.String()
-		msg.Content = &msgMeta
-	case MsgTypeImage:
-		var msgMeta ContentMeta
-		msgMeta.ImageKey = buf.String()
-		msg.Content = &msgMeta
-	case MsgTypeShareChat:
-		var msgMeta ContentMeta
-		msgMeta.ShareChatID = buf.String()
-		msg.Content = &msgMeta
-	case MsgTypePost:
-		var post PostMeta
-		if err := json.Unmarshal(buf.Bytes(), &post); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msgMeta := &ContentMeta{
-			Post: &post,
-		}
-		msg.Content = msgMeta
-	case MsgTypeInteractive:
-		var msgMeta CardMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.Card = &msgMeta
-	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if err := Send(arg.AccessToken, &msg); err != nil {
+	if _, err := n.Send(ctx, notify.Message{Type: arg.MsgType, Content: arg.Data}); err != nil {
 		return err
 	}
-	fmt.Println(feishu.MessageOK)
-
-	return nil
+	return arg.Printer.Print(feishu.MessageOK, nil)
 }
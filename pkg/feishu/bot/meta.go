@@ -49,7 +49,8 @@ type CardMeta struct {
 }
 
 type CardHeader struct {
-	Title CardHeaderTitle `json:"title"`
+	Title    CardHeaderTitle `json:"title"`
+	Template string          `json:"template,omitempty"` // 标题颜色，如 blue、orange、red
 }
 
 type CardHeaderTitle struct {
@@ -59,7 +60,8 @@ type CardHeaderTitle struct {
 
 type CardElement struct {
 	Tag     string              `json:"tag"`
-	Text    CardElementText     `json:"text,omitempty"`
+	Text    *CardElementText    `json:"text,omitempty"`
+	Content string              `json:"content,omitempty"` // markdown 元素的内容
 	Actions []CardElementAction `json:"actions,omitempty"`
 }
 
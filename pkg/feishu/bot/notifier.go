@@ -0,0 +1,156 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/feishu"
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// NewMessage 按消息类型新建飞书自定义机器人消息
+func NewMessage(msgType, content string) (*Message, error) {
+	if err := ValidateMsgType(msgType); err != nil {
+		return nil, err
+	}
+
+	msg := Message{
+		MsgType: msgType,
+	}
+
+	switch msgType {
+	case MsgTypeGeneric:
+		g, err := notify.ParseGeneric(content)
+		if err != nil {
+			return nil, err
+		}
+		return NewGenericMessage(g, notify.Mentions{}), nil
+	case MsgTypeText:
+		msg.Content = &ContentMeta{Text: content}
+	case MsgTypeImage:
+		msg.Content = &ContentMeta{ImageKey: content}
+	case MsgTypeShareChat:
+		msg.Content = &ContentMeta{ShareChatID: content}
+	case MsgTypePost:
+		var post PostMeta
+		if err := json.Unmarshal([]byte(content), &post); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		msg.Content = &ContentMeta{Post: &post}
+	case MsgTypeInteractive:
+		var msgMeta CardMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		msg.Card = &msgMeta
+	}
+	return &msg, nil
+}
+
+// severityTemplates 消息级别对应卡片标题的颜色
+var severityTemplates = map[string]string{
+	notify.SeverityInfo:     "blue",
+	notify.SeverityWarning:  "orange",
+	notify.SeverityCritical: "red",
+}
+
+// NewGenericMessage 通用消息转换为飞书消息卡片：标题、markdown 正文、按钮，@ 提醒以 <at id=...></at> 附在正文之后
+func NewGenericMessage(g *notify.Generic, mentions notify.Mentions) *Message {
+	title := g.FullTitle()
+	if title == "" {
+		title = notify.Truncate(notify.PlainText(g.Body), 50)
+	}
+	card := CardMeta{
+		Elements: []CardElement{},
+		Header: CardHeader{
+			Title:    CardHeaderTitle{Content: title, Tag: "plain_text"},
+			Template: severityTemplates[g.Severity],
+		},
+	}
+
+	// 卡片的 img 元素需要上传后的 img_key，图片以链接显示
+	text := g.Markdown(false, false, false)
+	if g.Image != "" {
+		text += "\n\n[image](" + g.Image + ")"
+	}
+	for _, v := range mentions.Users {
+		text += "<at id=" + v + "></at>"
+	}
+	if mentions.All {
+		text += "<at id=all></at>"
+	}
+	text = strings.TrimSpace(text)
+	if text != "" {
+		card.Elements = append(card.Elements, CardElement{Tag: "markdown", Content: text})
+	}
+
+	if len(g.Buttons) > 0 {
+		e := CardElement{Tag: "action"}
+		for i, v := range g.Buttons {
+			btn := CardElementAction{
+				Tag:  "button",
+				Text: CardElementActionText{Content: v.Title, Tag: "plain_text"},
+				Url:  v.URL,
+				Type: "default",
+			}
+			if i == 0 {
+				btn.Type = "primary"
+			}
+			e.Actions = append(e.Actions, btn)
+		}
+		card.Elements = append(card.Elements, e)
+	}
+	return &Message{MsgType: MsgTypeInteractive, Card: &card}
+}
+
+// Notifier 飞书自定义机器人
+type Notifier struct {
+	Client      *httpClient.Client
+	AccessToken string
+	Secret      string // 签名校验密钥，没有时不签名
+}
+
+// Send 发送飞书自定义机器人消息，只有通用消息支持 @ 提醒
+func (t *Notifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderFeiShu}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	var m *Message
+	if g != nil {
+		m = NewGenericMessage(g, msg.Mentions)
+	} else if m, err = NewMessage(msg.Type, msg.Content); err != nil {
+		return result, err
+	}
+
+	if t.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		sign, err := feishu.Sign(timestamp, t.Secret)
+		if err != nil {
+			return result, fmt.Errorf("sign failed: %w", err)
+		}
+		m.TimeStamp = timestamp
+		m.Sign = sign
+	}
+	return result, Send(ctx, t.Client, t.AccessToken, m)
+}
@@ -16,32 +16,60 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	httpClient "github.com/lenye/pmsg/pkg/http/client"
 )
 
-// PostJSON http post json
-func PostJSON(url string, reqBody, respBody any) (http.Header, error) {
-	buf := new(bytes.Buffer)
-	enc := json.NewEncoder(buf)
-	enc.SetEscapeHTML(false)
-	err := enc.Encode(reqBody)
-	if err != nil {
+// decodeJSON 检查http响应并解析json，可重试时返回 *httpClient.RetryableError
+func decodeJSON(method, url string, resp *http.Response, err error, respBody any) (http.Header, error) {
+	if errors.Is(err, httpClient.ErrDryRun) {
 		return nil, err
 	}
-
-	resp, err := httpClient.Post(url, httpClient.HdrValContentTypeJson, bytes.NewReader(buf.Bytes()))
 	if err != nil {
-		return nil, fmt.Errorf("%w; %s %s, %v", httpClient.ErrRequest, http.MethodPost, url, err)
+		return nil, httpClient.RequestError(method, url, err, false)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode/100 != 2 {
-		return resp.Header, json.NewDecoder(resp.Body).Decode(respBody)
+	if httpClient.IsRetryableStatus(resp.StatusCode, false) {
+		err := fmt.Errorf("%w; http response status code: %v, %s %s", httpClient.ErrRequest, resp.StatusCode, method, httpClient.RedactURL(url))
+		return nil, httpClient.Retryable(err, httpClient.ParseRetryAfter(resp.Header))
 	}
 
+	// 飞书在响应内容中返回错误信息
+	if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+		if resp.StatusCode/100 == 2 {
+			return resp.Header, nil
+		}
+		err := fmt.Errorf("%w; http response status code: %v, %s %s", httpClient.ErrRequest, resp.StatusCode, method, httpClient.RedactURL(url))
+		return resp.Header, httpClient.StatusError(err, resp.StatusCode, resp.Header, false)
+	}
+	if r, ok := respBody.(httpClient.Retryer); ok && r.Retryable() {
+		return resp.Header, httpClient.Retryable(nil, 0)
+	}
 	return resp.Header, nil
 }
+
+// PostJSON http post json
+func PostJSON(ctx context.Context, c *httpClient.Client, url string, reqBody, respBody any) (http.Header, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var header http.Header
+	err = c.Retry(ctx, func() error {
+		httpClient.ResetBody(respBody)
+		resp, err := c.Post(ctx, url, httpClient.HdrValContentTypeJson, bytes.NewReader(buf.Bytes()))
+		header, err = decodeJSON(http.MethodPost, url, resp, err, respBody)
+		return err
+	})
+	return header, err
+}
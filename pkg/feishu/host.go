@@ -0,0 +1,35 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feishu
+
+import (
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+)
+
+// Host 飞书接口默认地址
+const Host = "https://open.feishu.cn"
+
+// HostEnv 替换飞书接口地址的环境变量
+const HostEnv = "PMSG_FEISHU_API_BASE"
+
+// WithHost 替换飞书接口地址
+func WithHost(base string) httpClient.Option {
+	return httpClient.WithBaseURL(Host, base)
+}
+
+// URL 拼接飞书接口地址
+func URL(c *httpClient.Client, path string) string {
+	return c.BaseURL(Host) + path
+}
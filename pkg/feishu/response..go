@@ -24,8 +24,24 @@ const (
 	MessageOK = "ok"
 )
 
+const (
+	CodeTooManyRequest = 9499  // 请求过于频繁
+	CodeRateLimit      = 11232 // 消息发送触发频率限制
+)
+
+const (
+	CodeSignMatchFail = 19021 // 签名校验失败
+	CodeIPNotAllowed  = 19022 // IP地址不在白名单中
+)
+
 var ErrRequest = errors.New("feishu request error")
 
+// ErrUnauthorized 机器人签名校验失败或IP地址不在白名单中
+var ErrUnauthorized = fmt.Errorf("%w: unauthorized", ErrRequest)
+
+// ErrBusy 机器人发送超过频率限制，稍后可重试
+var ErrBusy = fmt.Errorf("%w: busy or rate limited", ErrRequest)
+
 // ResponseMeta 响应操作信息
 type ResponseMeta struct {
 	Code    int64  `json:"code"`          // 出错返回码，为0表示成功，非0表示调用失败
@@ -40,3 +56,32 @@ func (t ResponseMeta) String() string {
 func (t ResponseMeta) Succeed() bool {
 	return t.Code == CodeOK
 }
+
+// Unauthorized 是否为机器人认证错误
+func (t ResponseMeta) Unauthorized() bool {
+	switch t.Code {
+	case CodeSignMatchFail, CodeIPNotAllowed:
+		return true
+	}
+	return false
+}
+
+// Err 返回码对应的错误
+func (t ResponseMeta) Err() error {
+	if t.Unauthorized() {
+		return ErrUnauthorized
+	}
+	if t.Retryable() {
+		return ErrBusy
+	}
+	return ErrRequest
+}
+
+// Retryable 是否可重试
+func (t ResponseMeta) Retryable() bool {
+	switch t.Code {
+	case CodeTooManyRequest, CodeRateLimit:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockPath 对 path 加排他锁，阻塞直到获得锁，返回释放锁的函数；锁加在单独的 path.lock 文件上，path 可以不存在
+func LockPath(path string) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := Lock(lock); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return func() {
+		Unlock(lock)
+		lock.Close()
+	}, nil
+}
+
+// UpdateJSON 加文件锁读取 json 文件到 v，fn 返回 true 时写回，文件不存在时 v 不变
+//
+// 锁加在单独的 .lock 文件上，写入临时文件后替换，写入中断时不会丢失已有的内容。
+// 文件内容无效时返回错误，不覆盖，多个进程可共享同一文件
+func UpdateJSON(path string, v any, fn func() (bool, error)) error {
+	unlock, err := LockPath(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("invalid file %q, %w", path, err)
+		}
+	}
+
+	changed, err := fn()
+	if err != nil || !changed {
+		return err
+	}
+
+	if data, err = json.Marshal(v); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,78 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	KB int64 = 1 << 10
+	MB int64 = 1 << 20
+)
+
+// Limit 文件大小及格式限制
+type Limit struct {
+	MinSize int64    // 最小字节数，0表示不限制
+	MaxSize int64    // 最大字节数，0表示不限制
+	Exts    []string // 允许的扩展名，小写含"."，为空表示不限制
+}
+
+// Check 检查文件大小及格式
+func (t Limit) Check(name string) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fmt.Errorf("stat file failed, %w", err)
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("%v is a directory", name)
+	}
+
+	if len(t.Exts) > 0 {
+		ext := strings.ToLower(filepath.Ext(name))
+		ok := false
+		for _, v := range t.Exts {
+			if ext == v {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("file format %q not in %q", ext, t.Exts)
+		}
+	}
+
+	if t.MinSize > 0 && fi.Size() < t.MinSize {
+		return fmt.Errorf("file size %v is less than %v", FormatSize(fi.Size()), FormatSize(t.MinSize))
+	}
+	if t.MaxSize > 0 && fi.Size() > t.MaxSize {
+		return fmt.Errorf("file size %v exceeds limit %v", FormatSize(fi.Size()), FormatSize(t.MaxSize))
+	}
+	return nil
+}
+
+// FormatSize 格式化文件大小
+func FormatSize(n int64) string {
+	switch {
+	case n >= MB:
+		return fmt.Sprintf("%.1fMB", float64(n)/float64(MB))
+	case n >= KB:
+		return fmt.Sprintf("%.1fKB", float64(n)/float64(KB))
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -15,14 +15,37 @@
 package flags
 
 const (
-	UserAgent = "user_agent"
+	UserAgent    = "user_agent"
+	Timeout      = "timeout"
+	Retry        = "retry"
+	RetryMaxWait = "retry_max_wait"
+	RateLimit    = "rate_limit"
+	APIBase      = "api_base"
+	Output       = "output"
+	DryRun       = "dry_run"
+	DryRunToken  = "dry_run_token"
+	Config       = "config"
+	Profile      = "profile"
+	Template     = "template"
+	Var          = "var"
+	Vars         = "vars"
+	TokenCache   = "token_cache"
+	Cache        = "cache"
+	TokenServer  = "token_server"
+	Listen       = "listen"
 
 	AccessToken = "access_token"
 	Key         = "key"
 	Secret      = "secret"
 
+	TokenServerAuth = "token_server_auth"
+	ServerAuth      = "server_auth"
+
 	AppID     = "app_id"
 	AppSecret = "app_secret"
+	TokenMode = "token_mode"
+
+	ForceRefresh = "force_refresh"
 
 	ToUser           = "to_user"
 	TemplateID       = "template_id"
@@ -68,4 +91,33 @@ const (
 	AtUser   = "at_user"
 	AtMobile = "at_mobile"
 	IsAtAll  = "is_at_all"
+
+	To           = "to"
+	AllOrNothing = "all_or_nothing"
+	MinSuccess   = "min_success"
+
+	TemplateFile  = "template_file"
+	AtUserLabel   = "at_user_label"
+	AtMobileLabel = "at_mobile_label"
+	GitHubSecret  = "github_secret"
+	GitLabToken   = "gitlab_token"
+
+	Queue       = "queue"
+	Outbox      = "outbox"
+	MaxAttempts = "max_attempts"
+	Interval    = "interval"
+	Once        = "once"
+	State       = "state"
+
+	Schedule = "schedule"
+	At       = "at"
+	Cron     = "cron"
+	Timezone = "timezone"
+	Name     = "name"
+
+	Batch        = "batch"
+	ResultFile   = "result_file"
+	Concurrency  = "concurrency"
+	RetryFailed  = "retry_failed"
+	RetryUnknown = "retry_unknown"
 )
@@ -15,17 +15,17 @@
 package client
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"runtime"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/lenye/pmsg/pkg/ratelimit"
 	"github.com/lenye/pmsg/pkg/version"
 )
 
@@ -34,6 +34,7 @@ var ErrRequest = errors.New("http request error")
 const (
 	HdrKeyUserAgent       = "User-Agent"
 	HdrKeyContentType     = "Content-Type"
+	HdrKeyRetryAfter      = "Retry-After"
 	HdrValContentTypeJson = "application/json"
 )
 
@@ -41,128 +42,199 @@ const (
 	Timeout = 5 * time.Second
 )
 
-var DefaultClient = &http.Client{
-	Timeout: Timeout,
-}
-
-var userAgent string
-
 func DefaultUserAgent() string {
 	return fmt.Sprintf("%s/%s (%s; %s) %s/%s", version.AppName, version.Version, runtime.GOOS, runtime.GOARCH, version.BuildGit, version.BuildTime)
 }
 
-func SetUserAgent(value string) {
-	userAgent = value
+// Client http客户端，并发安全
+type Client struct {
+	httpClient *http.Client
+	userAgent  string
+	timeout    time.Duration
+	retry      RetryPolicy
+	limiter    ratelimit.Limiter
+	progress   ProgressFunc
+	baseURLs   map[string]string
+	dryRun     *DryRunOption
 }
 
-func UserAgent() string {
-	if userAgent != "" {
-		return userAgent
+// Option 客户端选项
+type Option func(*Client)
+
+// WithHTTPClient 使用指定的 http.Client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
 	}
-	return DefaultUserAgent()
 }
 
-// Get http get
-func Get(url string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return nil, err
+// WithUserAgent http user agent，为空时使用默认值
+func WithUserAgent(value string) Option {
+	return func(c *Client) {
+		if value != "" {
+			c.userAgent = value
+		}
 	}
-	req.Header.Set(HdrKeyUserAgent, UserAgent())
+}
 
-	return DefaultClient.Do(req)
+// WithTimeout 单次请求超时时间，小于等于0表示不限制
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
 }
 
-// Post http post
-func Post(url, contentType string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodPost, url, body)
-	if err != nil {
-		return nil, err
+// WithLimiter 按目标限流，为 nil 时不限流
+func WithLimiter(l ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.limiter = l
 	}
-	req.Header.Set(HdrKeyUserAgent, UserAgent())
-	req.Header.Set(HdrKeyContentType, contentType)
+}
 
-	return DefaultClient.Do(req)
+// WithBaseURL 将默认接口地址 host 替换为 base，用于私有网关、国际版域名或本地模拟服务，base 为空时不替换
+func WithBaseURL(host, base string) Option {
+	return func(c *Client) {
+		base = strings.TrimRight(base, "/")
+		if base == "" {
+			return
+		}
+		if c.baseURLs == nil {
+			c.baseURLs = make(map[string]string)
+		}
+		c.baseURLs[host] = base
+	}
 }
 
-func fileToBody(bodyWriter *multipart.Writer, formName, fileName string) (err error) {
-	var fileWriter io.Writer
-	fileWriter, err = bodyWriter.CreateFormFile(formName, filepath.Base(fileName))
-	if err != nil {
-		return fmt.Errorf("multipart.Writer.CreateFormFile failed, %w", err)
+// New 新建http客户端
+func New(opts ...Option) *Client {
+	c := &Client{
+		userAgent: DefaultUserAgent(),
+		timeout:   Timeout,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{}
+	}
+	return c
+}
 
-	var f *os.File
-	f, err = os.Open(fileName)
-	if err != nil {
-		return fmt.Errorf("open file failed, %w", err)
+// UserAgent http user agent
+func (c *Client) UserAgent() string {
+	return c.userAgent
+}
+
+// Timeout 单次请求超时时间
+func (c *Client) Timeout() time.Duration {
+	return c.timeout
+}
+
+// BaseURL 返回默认接口地址 host 的替换地址，没有替换时返回 host
+func (c *Client) BaseURL(host string) string {
+	if base, ok := c.baseURLs[host]; ok {
+		return base
 	}
-	defer func() {
-		if tmpErr := f.Close(); tmpErr != nil {
-			err = fmt.Errorf("close file failed, %w", tmpErr)
-		}
-	}()
+	return host
+}
 
-	if _, err := io.Copy(fileWriter, f); err != nil {
-		return fmt.Errorf("io.Copy failed, %w", err)
+// Wait 按目标限流，等待到可以发送为止
+func (c *Client) Wait(ctx context.Context, key string, limit ratelimit.Limit) error {
+	if c.limiter == nil || c.dryRun != nil {
+		return nil
 	}
+	return c.limiter.Wait(ctx, key, limit)
+}
 
-	return nil
+type rateLimitKey struct{}
+
+type rateLimit struct {
+	key   string
+	limit ratelimit.Limit
 }
 
-// MultipartForm 保存文件或其他字段信息
-type MultipartForm struct {
-	params map[string][]string
-	files  map[string]string
+// WithRateLimit 按目标限流，ctx 中的每次请求（含重试）发送前都预约一次发送
+func WithRateLimit(ctx context.Context, key string, limit ratelimit.Limit) context.Context {
+	return context.WithValue(ctx, rateLimitKey{}, rateLimit{key: key, limit: limit})
 }
 
-func NewMultipartForm() *MultipartForm {
-	return &MultipartForm{
-		params: make(map[string][]string),
-		files:  make(map[string]string),
-	}
+type requestSentKey struct{}
+
+// TrackRequests 记录 ctx 中是否发出过http请求，用 RequestSent 查询
+func TrackRequests(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestSentKey{}, new(atomic.Bool))
 }
 
-func (t *MultipartForm) AddFile(name, fileName string) *MultipartForm {
-	t.files[name] = fileName
-	return t
+// RequestSent ctx 中是否发出过http请求，ctx 未记录时返回 true
+func RequestSent(ctx context.Context) bool {
+	v, ok := ctx.Value(requestSentKey{}).(*atomic.Bool)
+	return !ok || v.Load()
 }
 
-func (t *MultipartForm) AddParam(name, value string) *MultipartForm {
-	if param, ok := t.params[name]; ok {
-		t.params[name] = append(param, value)
-	} else {
-		t.params[name] = []string{value}
-	}
-	return t
+// cancelBody 读取完响应后释放超时 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
 }
 
-// PostMultipartForm 上传文件或其他多个字段
-func PostMultipartForm(url string, form *MultipartForm) (*http.Response, error) {
-	bodyBuf := new(bytes.Buffer)
-	bodyWriter := multipart.NewWriter(bodyBuf)
+func (t *cancelBody) Close() error {
+	defer t.cancel()
+	return t.ReadCloser.Close()
+}
+
+// Do 发送http请求
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	req.Header.Set(HdrKeyUserAgent, c.userAgent)
 
-	for formName, fileName := range form.files {
-		if err := fileToBody(bodyWriter, formName, fileName); err != nil {
+	if v, ok := req.Context().Value(rateLimitKey{}).(rateLimit); ok {
+		if err := c.Wait(req.Context(), v.key, v.limit); err != nil {
 			return nil, err
 		}
 	}
-	for k, v := range form.params {
-		for _, vv := range v {
-			if err := bodyWriter.WriteField(k, vv); err != nil {
-				return nil, fmt.Errorf("multipart.Writer.WriteField failed, %w", err)
-			}
-		}
+
+	if v, ok := req.Context().Value(requestSentKey{}).(*atomic.Bool); ok {
+		v.Store(true)
 	}
-	contentType := bodyWriter.FormDataContentType()
-	if err := bodyWriter.Close(); err != nil {
-		return nil, fmt.Errorf("multipart.Writer.Close failed, %w", err)
+
+	if c.timeout <= 0 {
+		return c.httpClient.Do(req)
 	}
-	return Post(url, contentType, bodyBuf)
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
 }
 
-// PostFile 上传文件
-func PostFile(url, formName, fileName string) (*http.Response, error) {
-	form := NewMultipartForm().AddFile(formName, fileName)
-	return PostMultipartForm(url, form)
+// Get http get
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(req)
+}
+
+// Post http post
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	if c.dryRun != nil {
+		return nil, c.dryRunPost(url, contentType, body)
+	}
+	return c.PostNoDryRun(ctx, url, contentType, body)
+}
+
+// PostNoDryRun http post，dry run 模式下同样发送，用于获取 access_token 等不发送消息的请求
+func (c *Client) PostNoDryRun(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(HdrKeyContentType, contentType)
+
+	return c.Do(req)
 }
@@ -0,0 +1,200 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lenye/pmsg/pkg/file"
+)
+
+// ErrDryRun dry run 模式下请求未发送
+var ErrDryRun = errors.New("dry run, request not sent")
+
+// DryRunAccessToken dry run 模式下不获取 access_token 时使用的占位符
+const DryRunAccessToken = "DRY_RUN_ACCESS_TOKEN"
+
+// Redacted 隐藏凭证后的占位符
+const Redacted = "REDACTED"
+
+// redactedQueryKeys url 中需要隐藏的凭证参数
+var redactedQueryKeys = []string{"access_token", "key", "secret", "corpsecret", "sign"}
+
+// RedactURL 隐藏 url 中的凭证
+// 查询参数中的凭证替换为 Redacted；没有查询参数的 webhook url（飞书、Slack）凭证在路径最后一段，同样替换
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Redacted
+	}
+	if u.RawQuery == "" {
+		if i := strings.LastIndex(u.Path, "/"); i >= 0 && i < len(u.Path)-1 {
+			u.Path = u.Path[:i+1] + Redacted
+			u.RawPath = ""
+		}
+		return u.String()
+	}
+	q := u.Query()
+	for _, k := range redactedQueryKeys {
+		if q.Has(k) {
+			q.Set(k, Redacted)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// RedactError 隐藏错误信息中的凭证，net/http 返回的 *url.Error 包含完整的请求地址，替换为 RedactURL 的结果
+func RedactError(err error) string {
+	msg := err.Error()
+	var ue *url.Error
+	if errors.As(err, &ue) && ue.URL != "" {
+		msg = strings.ReplaceAll(msg, ue.URL, RedactURL(ue.URL))
+	}
+	return msg
+}
+
+// DryRunFile dry run 模式下上传的文件
+type DryRunFile struct {
+	Field string `json:"field"` // 表单字段
+	Name  string `json:"name"`  // 文件名称含路径
+	Size  int64  `json:"size"`  // 文件大小
+}
+
+// DryRunRequest dry run 模式下未发送的请求
+type DryRunRequest struct {
+	Method      string              `json:"method"`
+	URL         string              `json:"url"` // 已隐藏凭证
+	ContentType string              `json:"content_type"`
+	Body        json.RawMessage     `json:"body,omitempty"`   // json 请求内容
+	Params      map[string][]string `json:"params,omitempty"` // multipart 表单字段
+	Files       []DryRunFile        `json:"files,omitempty"`  // multipart 上传文件
+}
+
+func (t DryRunRequest) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\n%s: %s\n", t.Method, t.URL, HdrKeyContentType, t.ContentType)
+	if len(t.Body) > 0 {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, t.Body, "", "  "); err != nil {
+			buf.Reset()
+			buf.Write(t.Body)
+		}
+		fmt.Fprintf(&sb, "\n%s\n", bytes.TrimRight(buf.Bytes(), "\n"))
+	}
+	names := make([]string, 0, len(t.Params))
+	for name := range t.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "\n%s: %q", name, t.Params[name])
+	}
+	for _, f := range t.Files {
+		fmt.Fprintf(&sb, "\n%s: %s (%s)", f.Field, f.Name, file.FormatSize(f.Size))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// DryRunFunc 输出 dry run 模式下未发送的请求
+type DryRunFunc func(req *DryRunRequest) error
+
+// DryRunOption dry run 模式
+type DryRunOption struct {
+	Print      DryRunFunc // 输出请求，为 nil 时以文本格式输出到 stdout
+	FetchToken bool       // 是否获取真实的 access_token，默认使用 DryRunAccessToken
+}
+
+// WithDryRun dry run 模式：不发送消息，只输出请求，发送消息的方法返回 ErrDryRun
+func WithDryRun(opt DryRunOption) Option {
+	return func(c *Client) {
+		c.dryRun = &opt
+	}
+}
+
+// DryRun 是否 dry run 模式
+func (c *Client) DryRun() bool {
+	return c.dryRun != nil
+}
+
+// FetchToken 是否获取真实的 access_token，只有获取时才读写 access_token 缓存；
+// 非 dry run 模式时总是返回 true；dry run 模式下默认返回 false，使用 DryRunAccessToken，不读写缓存，设置 DryRunOption.FetchToken 时返回 true
+func (c *Client) FetchToken() bool {
+	return c.dryRun == nil || c.dryRun.FetchToken
+}
+
+// printDryRun 输出未发送的请求，返回 ErrDryRun
+func (c *Client) printDryRun(req *DryRunRequest) error {
+	req.URL = RedactURL(req.URL)
+	fn := c.dryRun.Print
+	if fn == nil {
+		fn = func(req *DryRunRequest) error {
+			_, err := fmt.Fprintln(os.Stdout, req)
+			return err
+		}
+	}
+	if err := fn(req); err != nil {
+		return err
+	}
+	return ErrDryRun
+}
+
+// dryRunPost 输出未发送的 post 请求
+func (c *Client) dryRunPost(url, contentType string, body io.Reader) error {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = io.ReadAll(body); err != nil {
+			return err
+		}
+	}
+	req := &DryRunRequest{Method: "POST", URL: url, ContentType: contentType}
+	if json.Valid(b) {
+		req.Body = b
+	} else if len(b) > 0 {
+		req.Body, _ = json.Marshal(string(b))
+	}
+	return c.printDryRun(req)
+}
+
+// dryRunMultipartForm 输出未发送的文件上传请求，不读取文件内容
+func (c *Client) dryRunMultipartForm(url string, form *MultipartForm) error {
+	req := &DryRunRequest{Method: "POST", URL: url, ContentType: "multipart/form-data"}
+	if len(form.params) > 0 {
+		req.Params = form.params
+	}
+	fields := make([]string, 0, len(form.files))
+	for field := range form.files {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		name := form.files[field]
+		fi, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("stat file failed, %w", err)
+		}
+		req.Files = append(req.Files, DryRunFile{Field: field, Name: name, Size: fi.Size()})
+	}
+	return c.printDryRun(req)
+}
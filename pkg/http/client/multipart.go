@@ -0,0 +1,236 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+func fileToBody(bodyWriter *multipart.Writer, formName, fileName string) (err error) {
+	var fileWriter io.Writer
+	fileWriter, err = bodyWriter.CreateFormFile(formName, filepath.Base(fileName))
+	if err != nil {
+		return fmt.Errorf("multipart.Writer.CreateFormFile failed, %w", err)
+	}
+
+	var f *os.File
+	f, err = os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("open file failed, %w", err)
+	}
+	defer func() {
+		if tmpErr := f.Close(); tmpErr != nil {
+			err = fmt.Errorf("close file failed, %w", tmpErr)
+		}
+	}()
+
+	if _, err := io.Copy(fileWriter, f); err != nil {
+		return fmt.Errorf("io.Copy failed, %w", err)
+	}
+
+	return nil
+}
+
+// MultipartForm 保存文件或其他字段信息
+type MultipartForm struct {
+	params map[string][]string
+	files  map[string]string
+}
+
+func NewMultipartForm() *MultipartForm {
+	return &MultipartForm{
+		params: make(map[string][]string),
+		files:  make(map[string]string),
+	}
+}
+
+func (t *MultipartForm) AddFile(name, fileName string) *MultipartForm {
+	t.files[name] = fileName
+	return t
+}
+
+func (t *MultipartForm) AddParam(name, value string) *MultipartForm {
+	if param, ok := t.params[name]; ok {
+		t.params[name] = append(param, value)
+	} else {
+		t.params[name] = []string{value}
+	}
+	return t
+}
+
+// write 写入全部文件及字段
+func (t *MultipartForm) write(bodyWriter *multipart.Writer) error {
+	for formName, fileName := range t.files {
+		if err := fileToBody(bodyWriter, formName, fileName); err != nil {
+			return err
+		}
+	}
+	for k, v := range t.params {
+		for _, vv := range v {
+			if err := bodyWriter.WriteField(k, vv); err != nil {
+				return fmt.Errorf("multipart.Writer.WriteField failed, %w", err)
+			}
+		}
+	}
+	if err := bodyWriter.Close(); err != nil {
+		return fmt.Errorf("multipart.Writer.Close failed, %w", err)
+	}
+	return nil
+}
+
+// size 计算请求内容长度，文件内容不读入内存
+func (t *MultipartForm) size(boundary string) (int64, error) {
+	cw := new(countWriter)
+	bodyWriter := multipart.NewWriter(cw)
+	if err := bodyWriter.SetBoundary(boundary); err != nil {
+		return 0, err
+	}
+
+	var fileSize int64
+	for formName, fileName := range t.files {
+		fi, err := os.Stat(fileName)
+		if err != nil {
+			return 0, fmt.Errorf("stat file failed, %w", err)
+		}
+		fileSize += fi.Size()
+		if _, err := bodyWriter.CreateFormFile(formName, filepath.Base(fileName)); err != nil {
+			return 0, fmt.Errorf("multipart.Writer.CreateFormFile failed, %w", err)
+		}
+	}
+	for k, v := range t.params {
+		for _, vv := range v {
+			if err := bodyWriter.WriteField(k, vv); err != nil {
+				return 0, fmt.Errorf("multipart.Writer.WriteField failed, %w", err)
+			}
+		}
+	}
+	if err := bodyWriter.Close(); err != nil {
+		return 0, fmt.Errorf("multipart.Writer.Close failed, %w", err)
+	}
+	return cw.n + fileSize, nil
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (t *countWriter) Write(p []byte) (int, error) {
+	t.n += int64(len(p))
+	return len(p), nil
+}
+
+// sizeWriter 按预先计算的请求内容长度写入，超过时返回错误，写入完成后用 done 检查长度
+type sizeWriter struct {
+	w    io.Writer
+	n    int64
+	size int64
+}
+
+func (t *sizeWriter) Write(p []byte) (int, error) {
+	if t.n+int64(len(p)) > t.size {
+		return 0, t.sizeError()
+	}
+	n, err := t.w.Write(p)
+	t.n += int64(n)
+	return n, err
+}
+
+// done 写入的长度与 Content-Length 不同时返回错误
+func (t *sizeWriter) done() error {
+	if t.n != t.size {
+		return t.sizeError()
+	}
+	return nil
+}
+
+func (t *sizeWriter) sizeError() error {
+	return fmt.Errorf("multipart body does not match Content-Length %d, a file changed during upload", t.size)
+}
+
+// ProgressFunc 上传进度，sent 已发送字节数，total 总字节数
+type ProgressFunc func(sent, total int64)
+
+// WithProgress 上传文件时报告进度
+func WithProgress(fn ProgressFunc) Option {
+	return func(c *Client) {
+		c.progress = fn
+	}
+}
+
+// progressReader 统计已发送的字节数
+type progressReader struct {
+	io.ReadCloser
+	sent  int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (t *progressReader) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 && t.fn != nil {
+		t.sent += int64(n)
+		t.fn(t.sent, t.total)
+	}
+	return n, err
+}
+
+// PostMultipartForm 上传文件或其他多个字段，文件内容通过 io.Pipe 边读边发送；
+// Content-Length 按文件大小预先计算，上传期间文件大小改变时请求失败
+func (c *Client) PostMultipartForm(ctx context.Context, url string, form *MultipartForm) (*http.Response, error) {
+	if c.dryRun != nil {
+		return nil, c.dryRunMultipartForm(url, form)
+	}
+	pr, pw := io.Pipe()
+	// 返回时关闭管道：Do 未读完请求内容就返回（如限流等待被取消、服务端提前响应）时，写入随之结束
+	defer pr.Close()
+	sw := &sizeWriter{w: pw}
+	bodyWriter := multipart.NewWriter(sw)
+
+	size, err := form.size(bodyWriter.Boundary())
+	if err != nil {
+		return nil, err
+	}
+	sw.size = size
+
+	body := &progressReader{ReadCloser: pr, total: size, fn: c.progress}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	req.Header.Set(HdrKeyContentType, bodyWriter.FormDataContentType())
+
+	go func() {
+		err := form.write(bodyWriter)
+		if err == nil {
+			err = sw.done()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return c.Do(req)
+}
+
+// PostFile 上传文件
+func (c *Client) PostFile(ctx context.Context, url, formName, fileName string) (*http.Response, error) {
+	form := NewMultipartForm().AddFile(formName, fileName)
+	return c.PostMultipartForm(ctx, url, form)
+}
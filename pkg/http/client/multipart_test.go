@@ -0,0 +1,126 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/ratelimit"
+)
+
+// failLimiter 限流等待失败，请求未发出
+type failLimiter struct{}
+
+func (failLimiter) Wait(ctx context.Context, key string, limit ratelimit.Limit) error {
+	return context.Canceled
+}
+
+func TestPostMultipartForm(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(name, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, _, err := r.FormFile("media")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer srv.Close()
+
+	resp, err := New().PostMultipartForm(context.Background(), srv.URL, NewMultipartForm().AddFile("media", name).AddParam("type", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatalf("status %d, body %q", resp.StatusCode, body)
+	}
+}
+
+func TestPostMultipartFormNotRead(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(name, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	before := runtime.NumGoroutine()
+	c := New(WithLimiter(failLimiter{}))
+	ctx := WithRateLimit(context.Background(), "key", ratelimit.Limit{Burst: 1, Interval: time.Second})
+	if _, err := c.PostFile(ctx, "http://127.0.0.1:1/upload", "media", name); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err %v, want canceled", err)
+	}
+
+	// Do 未读取请求内容就返回，写入文件的 goroutine 应结束
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines %d, want %d: multipart writer leaked", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMultipartSizeChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		change []byte
+	}{
+		{"grown", []byte("hello world")},
+		{"shrunk", []byte("he")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "a.txt")
+			if err := os.WriteFile(name, []byte("hello"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			form := NewMultipartForm().AddFile("media", name)
+			sw := &sizeWriter{w: io.Discard}
+			bodyWriter := multipart.NewWriter(sw)
+			size, err := form.size(bodyWriter.Boundary())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sw.size = size
+
+			// 计算长度后、上传前文件被修改
+			if err := os.WriteFile(name, tt.change, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err = form.write(bodyWriter)
+			if err == nil {
+				err = sw.done()
+			}
+			if err == nil {
+				t.Fatalf("written %d of %d bytes, want size error", sw.n, size)
+			}
+		})
+	}
+}
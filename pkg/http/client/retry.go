@@ -0,0 +1,207 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const (
+	RetryBaseWait = 500 * time.Millisecond
+	RetryMaxWait  = 30 * time.Second
+)
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（含首次请求），小于等于1表示不重试
+	BaseWait    time.Duration // 首次重试的退避时间，之后按指数增长
+	MaxWait     time.Duration // 单次重试最长等待时间，服务端要求的 Retry-After 超过该值时放弃重试
+}
+
+// Backoff 第 attempt 次重试（从1开始）前的等待时间，带随机抖动
+func (t RetryPolicy) Backoff(attempt int) time.Duration {
+	base := t.BaseWait
+	if base <= 0 {
+		base = RetryBaseWait
+	}
+	maxWait := t.MaxWait
+	if maxWait <= 0 {
+		maxWait = RetryMaxWait
+	}
+
+	d := base
+	for i := 1; i < attempt && d < maxWait; i++ {
+		d *= 2
+	}
+	if d > maxWait {
+		d = maxWait
+	}
+	// 随机抖动，取 [d/2, d]
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// RetryableError 可重试的错误
+type RetryableError struct {
+	Err        error         // 放弃重试时返回的错误，为 nil 表示由调用方处理响应结果
+	RetryAfter time.Duration // 服务端要求的等待时间，0 表示按退避时间等待
+}
+
+func (e *RetryableError) Error() string {
+	if e.Err == nil {
+		return "retryable response"
+	}
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 标记错误可重试
+func Retryable(err error, retryAfter time.Duration) error {
+	return &RetryableError{Err: err, RetryAfter: retryAfter}
+}
+
+// Retryer 响应结果是否可重试，由各平台的 ResponseMeta 实现
+type Retryer interface {
+	Retryable() bool
+}
+
+// WithRetry 重试策略
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// RetryPolicy 重试策略
+func (c *Client) RetryPolicy() RetryPolicy {
+	return c.retry
+}
+
+// Retry 执行 fn，fn 返回 *RetryableError 时按重试策略等待后重试
+func (c *Client) Retry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+
+		var re *RetryableError
+		if !errors.As(err, &re) {
+			return err
+		}
+		if attempt >= c.retry.MaxAttempts || ctx.Err() != nil {
+			return re.Err
+		}
+
+		wait := re.RetryAfter
+		if wait <= 0 {
+			wait = c.retry.Backoff(attempt)
+		} else if c.retry.MaxWait > 0 && wait > c.retry.MaxWait {
+			return re.Err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return re.Err
+		case <-timer.C:
+		}
+	}
+}
+
+// ErrMaybeSent 发送消息的请求已发出，结果未知：服务端可能已收到消息，重新发送可能重复
+var ErrMaybeSent = errors.New("request sent, result unknown")
+
+// RequestError http 请求失败的错误，错误信息中的 url 隐藏凭证；幂等的请求或请求确定未发出时可重试；
+// 发送消息的请求超时等错误时服务端可能已收到消息，不重试，避免重复发送，返回的错误包含 ErrMaybeSent
+func RequestError(method, url string, err error, idempotent bool) error {
+	if idempotent || NotSent(err) {
+		return Retryable(fmt.Errorf("%w; %s %s, %s", ErrRequest, method, RedactURL(url), RedactError(err)), 0)
+	}
+	return fmt.Errorf("%w, %w; %s %s, %s", ErrRequest, ErrMaybeSent, method, RedactURL(url), RedactError(err))
+}
+
+// NotSent 请求是否确定未发出：域名解析失败、连接被拒绝等建立连接（含连接代理）时的错误；
+// 按 net.OpError 判断，不依赖各系统不同的 syscall 错误码
+func NotSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
+
+// IsRetryableStatus 可重试的http响应状态码：限流和服务不可用时服务端未处理请求，总是可重试；
+// 500、502、504 时服务端可能已收到消息，只有幂等的请求可重试
+func IsRetryableStatus(statusCode int, idempotent bool) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// StatusError 响应状态码异常的错误：可重试的状态码返回 *RetryableError，按 Retry-After 等待；
+// 发送消息的请求 500、502、504 时服务端可能已收到消息，返回的错误包含 ErrMaybeSent
+func StatusError(err error, statusCode int, header http.Header, idempotent bool) error {
+	if IsRetryableStatus(statusCode, idempotent) {
+		return Retryable(err, ParseRetryAfter(header))
+	}
+	switch statusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return fmt.Errorf("%w, %w", err, ErrMaybeSent)
+	}
+	return err
+}
+
+// ParseRetryAfter 解析 Retry-After 响应头，支持秒数和http日期
+func ParseRetryAfter(header http.Header) time.Duration {
+	v := header.Get(HdrKeyRetryAfter)
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		if sec < 0 {
+			return 0
+		}
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// ResetBody 重试前清空上一次解析的响应结果
+func ResetBody(v any) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv.Elem().SetZero()
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotSent(t *testing.T) {
+	// 端口未监听，连接被拒绝
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c := New()
+	_, err = c.Get(context.Background(), "http://"+addr)
+	if err == nil || !NotSent(err) {
+		t.Errorf("connection refused: NotSent(%v) = false, want true", err)
+	}
+
+	// 请求已发出，等待响应时超时
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+	c = New(WithTimeout(20 * time.Millisecond))
+	_, err = c.Get(context.Background(), srv.URL)
+	if err == nil || NotSent(err) {
+		t.Errorf("response timeout: NotSent(%v) = true, want false", err)
+	}
+}
+
+func TestMaybeSent(t *testing.T) {
+	timeout := &net.OpError{Op: "read", Net: "tcp", Err: context.DeadlineExceeded}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	status := fmt.Errorf("%w; http response status code: 502", ErrRequest)
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+		maybeSent bool
+	}{
+		{"send timeout", RequestError(http.MethodPost, "http://x", timeout, false), false, true},
+		{"send refused", RequestError(http.MethodPost, "http://x", refused, false), true, false},
+		{"get timeout", RequestError(http.MethodGet, "http://x", timeout, true), true, false},
+		{"send 502", StatusError(status, http.StatusBadGateway, nil, false), false, true},
+		{"send 503", StatusError(status, http.StatusServiceUnavailable, nil, false), true, false},
+		{"send 400", StatusError(status, http.StatusBadRequest, nil, false), false, false},
+		{"get 502", StatusError(status, http.StatusBadGateway, nil, true), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var re *RetryableError
+			if got := errors.As(tt.err, &re); got != tt.retryable {
+				t.Errorf("retryable = %v, want %v", got, tt.retryable)
+			}
+			if got := errors.Is(tt.err, ErrMaybeSent); got != tt.maybeSent {
+				t.Errorf("errors.Is(%v, ErrMaybeSent) = %v, want %v", tt.err, got, tt.maybeSent)
+			}
+			if !errors.Is(tt.err, ErrRequest) {
+				t.Errorf("errors.Is(%v, ErrRequest) = false", tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TypeGeneric 通用消息类型，Content 为 Generic 的 json，由各平台转换为原生格式
+const TypeGeneric = "generic"
+
+// 消息级别
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// ValidateSeverity 验证消息级别，空为不指定
+func ValidateSeverity(v string) error {
+	switch v {
+	case "", SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("%s not in [%q %q %q]", v, SeverityInfo, SeverityWarning, SeverityCritical)
+	}
+	return nil
+}
+
+// Link 链接或按钮
+type Link struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Generic 通用消息内容
+type Generic struct {
+	Title    string `json:"title"`              // 标题
+	Body     string `json:"body,omitempty"`     // 正文，CommonMark
+	Links    []Link `json:"links,omitempty"`    // 链接，附在正文之后
+	Buttons  []Link `json:"buttons,omitempty"`  // 按钮
+	Image    string `json:"image,omitempty"`    // 图片 url
+	Severity string `json:"severity,omitempty"` // 级别：info、warning、critical
+}
+
+// ParseGeneric 解析通用消息 json，如 {"title":"磁盘告警","body":"**/data** 使用率 95%","severity":"critical"}
+func ParseGeneric(content string) (*Generic, error) {
+	var g Generic
+	if err := json.Unmarshal([]byte(content), &g); err != nil {
+		return nil, fmt.Errorf("invalid json format, %v", err)
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Validate 验证通用消息
+func (t *Generic) Validate() error {
+	if t.Title == "" && t.Body == "" {
+		return fmt.Errorf("title and body cannot be empty at the same time")
+	}
+	if err := ValidateSeverity(t.Severity); err != nil {
+		return fmt.Errorf("invalid severity: %v", err)
+	}
+	for _, v := range append(append([]Link(nil), t.Links...), t.Buttons...) {
+		if v.URL == "" {
+			return fmt.Errorf("url of link %q cannot be empty", v.Title)
+		}
+	}
+	return nil
+}
+
+// severityIcons 消息级别在标题前的标识
+var severityIcons = map[string]string{
+	SeverityInfo:     "🔵",
+	SeverityWarning:  "🟠",
+	SeverityCritical: "🔴",
+}
+
+// FullTitle 带级别标识的标题
+func (t *Generic) FullTitle() string {
+	if icon, ok := severityIcons[t.Severity]; ok && t.Title != "" {
+		return icon + " " + t.Title
+	}
+	return t.Title
+}
+
+// URL 消息的跳转地址：第一个按钮或链接，没有时为空
+func (t *Generic) URL() string {
+	if len(t.Buttons) > 0 {
+		return t.Buttons[0].URL
+	}
+	if len(t.Links) > 0 {
+		return t.Links[0].URL
+	}
+	return ""
+}
+
+// Markdown 转换为 CommonMark：标题、正文、图片、链接，withButtons 为 true 时按钮也作为链接
+func (t *Generic) Markdown(withTitle, withImage, withButtons bool) string {
+	var sb []string
+	if withTitle && t.Title != "" {
+		sb = append(sb, "**"+t.FullTitle()+"**")
+	}
+	if t.Body != "" {
+		sb = append(sb, t.Body)
+	}
+	if withImage && t.Image != "" {
+		sb = append(sb, "![]("+t.Image+")")
+	}
+	links := t.Links
+	if withButtons {
+		links = append(append([]Link(nil), t.Links...), t.Buttons...)
+	}
+	if len(links) > 0 {
+		items := make([]string, 0, len(links))
+		for _, v := range links {
+			items = append(items, "["+linkTitle(v)+"]("+v.URL+")")
+		}
+		sb = append(sb, strings.Join(items, " | "))
+	}
+	return strings.Join(sb, "\n\n")
+}
+
+// Text 转换为纯文本，用于只支持文本的消息：标题、正文、图片地址，链接和按钮为“标题 地址”
+func (t *Generic) Text() string {
+	var sb []string
+	if t.Title != "" {
+		sb = append(sb, t.FullTitle())
+	}
+	if t.Body != "" {
+		sb = append(sb, PlainText(t.Body))
+	}
+	if t.Image != "" {
+		sb = append(sb, t.Image)
+	}
+	for _, v := range append(append([]Link(nil), t.Links...), t.Buttons...) {
+		if v.Title == "" {
+			sb = append(sb, v.URL)
+		} else {
+			sb = append(sb, v.Title+" "+v.URL)
+		}
+	}
+	return strings.Join(sb, "\n\n")
+}
+
+func linkTitle(v Link) string {
+	if v.Title == "" {
+		return v.URL
+	}
+	return v.Title
+}
+
+var (
+	mdImage    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)]*)\)`)
+	mdLink     = regexp.MustCompile(`\[([^\]]*)\]\(([^)]*)\)`)
+	mdEmphasis = regexp.MustCompile("(\\*\\*|__|~~|`)")
+	mdItalic   = regexp.MustCompile(`\*([^*\n]+)\*`)
+	mdHeading  = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+)
+
+// PlainText 去掉 markdown 标记，链接保留文字，用于不支持 markdown 的消息
+func PlainText(md string) string {
+	s := mdImage.ReplaceAllString(md, "$1")
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdEmphasis.ReplaceAllString(s, "")
+	s = mdItalic.ReplaceAllString(s, "$1")
+	return mdHeading.ReplaceAllString(s, "")
+}
+
+// Truncate 按字符截断，超过 n 个字符时以 … 结尾
+func Truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
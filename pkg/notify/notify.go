@@ -0,0 +1,99 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notify 与平台无关的消息发送接口，供 Go 程序直接使用
+//
+// 各平台的实现在平台的包中，如 dingtalk/bot.Notifier、weixin/work/message.AppNotifier，
+// 命令行也通过这些实现发送消息。
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Message 消息
+type Message struct {
+	Type     string   // 消息类型，即平台的 msgtype，如 text、markdown，generic 为通用消息
+	Content  string   // 消息内容：text 为文本，其他消息类型为该类型的 json
+	Generic  *Generic // 通用消息，不为 nil 时忽略 Type、Content
+	Mentions Mentions // @ 提醒，只有部分平台的部分消息类型支持
+}
+
+// GenericContent 通用消息内容：Generic 不为 nil 时验证后返回，Type 为 generic 时解析 Content，其他消息类型返回 nil
+func (t Message) GenericContent() (*Generic, error) {
+	if t.Generic != nil {
+		if err := t.Generic.Validate(); err != nil {
+			return nil, err
+		}
+		return t.Generic, nil
+	}
+	if t.Type == TypeGeneric {
+		return ParseGeneric(t.Content)
+	}
+	return nil, nil
+}
+
+// Mentions @ 提醒
+type Mentions struct {
+	Users   []string // 用户 id
+	Mobiles []string // 手机号
+	All     bool     // @所有人
+}
+
+// Empty 是否没有 @ 提醒
+func (t Mentions) Empty() bool {
+	return len(t.Users) == 0 && len(t.Mobiles) == 0 && !t.All
+}
+
+// Result 发送结果
+type Result struct {
+	Provider       string   `json:"provider"`                  // 平台
+	MessageID      string   `json:"message_id,omitempty"`      // 消息id
+	InvalidUsers   []string `json:"invalid_users,omitempty"`   // 不合法的用户
+	InvalidParties []string `json:"invalid_parties,omitempty"` // 不合法的部门
+	InvalidTags    []string `json:"invalid_tags,omitempty"`    // 不合法的标签
+	Response       any      `json:"-"`                         // 平台的原始响应，没有时为 nil
+}
+
+func (t Result) String() string {
+	sb := []string{"provider: " + t.Provider}
+	if t.MessageID != "" {
+		sb = append(sb, fmt.Sprintf("message_id: %q", t.MessageID))
+	}
+	if len(t.InvalidUsers) > 0 {
+		sb = append(sb, fmt.Sprintf("invalid_users: %q", t.InvalidUsers))
+	}
+	if len(t.InvalidParties) > 0 {
+		sb = append(sb, fmt.Sprintf("invalid_parties: %q", t.InvalidParties))
+	}
+	if len(t.InvalidTags) > 0 {
+		sb = append(sb, fmt.Sprintf("invalid_tags: %q", t.InvalidTags))
+	}
+	return strings.Join(sb, ", ")
+}
+
+// Notifier 发送消息
+type Notifier interface {
+	Send(ctx context.Context, msg Message) (Result, error)
+}
+
+// Split 按 "|" 拆分列表，如 "user1|user2"，空字符串返回 nil
+func Split(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, "|")
+}
@@ -0,0 +1,37 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import "fmt"
+
+// 平台，用于 Result.Provider 和配置文件的 provider
+const (
+	ProviderWeiXin     = "weixin"
+	ProviderWorkWeiXin = "workweixin"
+	ProviderDingTalk   = "dingtalk"
+	ProviderFeiShu     = "feishu"
+	ProviderSlack      = "slack"
+)
+
+// ValidateProvider 验证平台
+func ValidateProvider(v string) error {
+	switch v {
+	case ProviderWeiXin, ProviderWorkWeiXin, ProviderDingTalk, ProviderFeiShu, ProviderSlack:
+	default:
+		return fmt.Errorf("%s not in [%q %q %q %q %q]", v,
+			ProviderWeiXin, ProviderWorkWeiXin, ProviderDingTalk, ProviderFeiShu, ProviderSlack)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/config"
+	"github.com/lenye/pmsg/pkg/file"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/version"
+)
+
+// 消息状态
+const (
+	StatePending = "pending" // 等待投递，包括等待重试
+	StateDead    = "dead"    // 投递失败且不再重试，可用 outbox retry 重新投递
+)
+
+// ValidateState 验证消息状态，空为全部
+func ValidateState(v string) error {
+	switch v {
+	case "", StatePending, StateDead:
+	default:
+		return fmt.Errorf("%s not in [%q %q]", v, StatePending, StateDead)
+	}
+	return nil
+}
+
+// Message 发送消息的命令、参数和消息内容，发送时按命令重新构建请求
+type Message struct {
+	Command string                `json:"command"`           // 发送消息的命令，如 workweixin/app
+	Flags   map[string][]string   `json:"flags,omitempty"`   // 命令行参数，凭证只保存 file:、env:、exec: 来源，发送时读取
+	Data    string                `json:"data"`              // 消息内容，已按模板渲染
+	Request *client.DryRunRequest `json:"request,omitempty"` // 保存时构建的请求，已隐藏凭证
+}
+
+// Provider 命令所属的平台，如 workweixin
+func (t Message) Provider() string {
+	provider, _, _ := strings.Cut(t.Command, "/")
+	return provider
+}
+
+// Masked 隐藏凭证参数的副本，用于输出
+func (t Message) Masked() Message {
+	provider := t.Provider()
+	flags := make(map[string][]string, len(t.Flags))
+	for name, values := range t.Flags {
+		if config.IsProviderSecret(provider, name) {
+			values = []string{config.Mask(values[0])}
+		}
+		flags[name] = values
+	}
+	t.Flags = flags
+	return t
+}
+
+// Entry 待投递的消息
+type Entry struct {
+	ID string `json:"id"`
+	Message
+	State       string    `json:"state"`                // 状态
+	Attempts    int       `json:"attempts"`             // 已投递次数
+	NextAttempt time.Time `json:"next_attempt"`         // 下次投递时间
+	LastError   string    `json:"last_error,omitempty"` // 最后一次投递的错误
+	CreatedAt   time.Time `json:"created_at"`           // 入队时间
+	UpdatedAt   time.Time `json:"updated_at"`           // 更新时间
+}
+
+func (t *Entry) String() string {
+	s := fmt.Sprintf("%s  %-7s  %-40s  attempts %d", t.ID, t.State, t.Command, t.Attempts)
+	if t.State == StatePending && t.Attempts > 0 {
+		s += ", next " + t.NextAttempt.Local().Format(time.RFC3339)
+	}
+	if t.LastError != "" {
+		s += ": " + t.LastError
+	}
+	return s
+}
+
+// NewID 按时间排序的 id
+func NewID(now time.Time) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return now.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b)
+}
+
+// Store 发件箱，消息保存在本地的一个文件中，读写时加文件锁，多个进程共享
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewStore 新建发件箱
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultFile 默认的发件箱文件，如 linux 下的 ~/.config/pmsg/outbox.json
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, version.AppName, "outbox.json")
+}
+
+// Path 发件箱文件
+func (t *Store) Path() string {
+	return t.path
+}
+
+// Add 消息入队，设置 id 和状态
+func (t *Store) Add(e *Entry) error {
+	now := time.Now()
+	e.ID = NewID(now)
+	e.State = StatePending
+	e.NextAttempt = now
+	e.CreatedAt = now
+	e.UpdatedAt = now
+	return t.update(func(entries map[string]*Entry) (bool, error) {
+		entries[e.ID] = e
+		return true, nil
+	})
+}
+
+// List 按入队时间排序的消息，state 为空时返回全部
+func (t *Store) List(state string) ([]*Entry, error) {
+	var list []*Entry
+	err := t.update(func(entries map[string]*Entry) (bool, error) {
+		for _, v := range entries {
+			if state == "" || v.State == state {
+				list = append(list, v)
+			}
+		}
+		return false, nil
+	})
+	sortEntries(list)
+	return list, err
+}
+
+// Claim 取出一条已到投递时间的消息，最早入队的优先，没有时返回 nil；
+// 投递次数加1，并在 lease 之后才能再次取出，避免多个 worker 重复投递。
+// 每次只取出一条，lease 只需覆盖一次投递的时间
+func (t *Store) Claim(now time.Time, lease time.Duration) (*Entry, error) {
+	var claimed *Entry
+	err := t.update(func(entries map[string]*Entry) (bool, error) {
+		var next *Entry
+		for _, v := range entries {
+			if v.State != StatePending || v.NextAttempt.After(now) {
+				continue
+			}
+			if next == nil || v.CreatedAt.Before(next.CreatedAt) || (v.CreatedAt.Equal(next.CreatedAt) && v.ID < next.ID) {
+				next = v
+			}
+		}
+		if next == nil {
+			return false, nil
+		}
+		next.Attempts++
+		next.NextAttempt = now.Add(lease)
+		next.UpdatedAt = now
+		e := *next
+		claimed = &e
+		return true, nil
+	})
+	return claimed, err
+}
+
+// Done 投递成功，删除消息
+func (t *Store) Done(id string) error {
+	return t.update(func(entries map[string]*Entry) (bool, error) {
+		_, ok := entries[id]
+		delete(entries, id)
+		return ok, nil
+	})
+}
+
+// Fail 投递失败，next 时重试，dead 为 true 时不再重试
+func (t *Store) Fail(id string, cause error, next time.Time, dead bool) error {
+	return t.update(func(entries map[string]*Entry) (bool, error) {
+		e, ok := entries[id]
+		if !ok {
+			return false, nil
+		}
+		e.LastError = client.RedactError(cause)
+		e.NextAttempt = next
+		if dead {
+			e.State = StateDead
+		}
+		e.UpdatedAt = time.Now()
+		return true, nil
+	})
+}
+
+// Release 投递中断，放回发件箱，不计投递次数
+func (t *Store) Release(id string) error {
+	return t.update(func(entries map[string]*Entry) (bool, error) {
+		e, ok := entries[id]
+		if !ok {
+			return false, nil
+		}
+		if e.Attempts > 0 {
+			e.Attempts--
+		}
+		e.NextAttempt = time.Now()
+		e.UpdatedAt = e.NextAttempt
+		return true, nil
+	})
+}
+
+// Retry 死信重新投递，ids 为空时重新投递全部死信，返回数量
+func (t *Store) Retry(ids []string) (int, error) {
+	var n int
+	err := t.update(func(entries map[string]*Entry) (bool, error) {
+		if err := exist(entries, ids); err != nil {
+			return false, err
+		}
+		now := time.Now()
+		for _, e := range selectEntries(entries, ids) {
+			if e.State != StateDead {
+				continue
+			}
+			e.State = StatePending
+			e.Attempts = 0
+			e.NextAttempt = now
+			e.UpdatedAt = now
+			n++
+		}
+		return n > 0, nil
+	})
+	return n, err
+}
+
+// Purge 删除消息，ids 为空时删除全部死信，返回数量
+func (t *Store) Purge(ids []string) (int, error) {
+	var n int
+	err := t.update(func(entries map[string]*Entry) (bool, error) {
+		if err := exist(entries, ids); err != nil {
+			return false, err
+		}
+		for _, e := range selectEntries(entries, ids) {
+			if len(ids) == 0 && e.State != StateDead {
+				continue
+			}
+			delete(entries, e.ID)
+			n++
+		}
+		return n > 0, nil
+	})
+	return n, err
+}
+
+// exist 检查 id 是否都存在
+func exist(entries map[string]*Entry, ids []string) error {
+	for _, id := range ids {
+		if _, ok := entries[id]; !ok {
+			return fmt.Errorf("outbox entry %q not found", id)
+		}
+	}
+	return nil
+}
+
+// selectEntries ids 对应的消息，ids 为空时返回全部
+func selectEntries(entries map[string]*Entry, ids []string) []*Entry {
+	var list []*Entry
+	if len(ids) == 0 {
+		for _, e := range entries {
+			list = append(list, e)
+		}
+		return list
+	}
+	for _, id := range ids {
+		list = append(list, entries[id])
+	}
+	return list
+}
+
+func sortEntries(list []*Entry) {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+}
+
+// update 加文件锁读取发件箱，fn 返回 true 时写回
+func (t *Store) update(fn func(entries map[string]*Entry) (bool, error)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 与 access_token 缓存不同，发件箱损坏时不能丢弃其中的消息，file.UpdateJSON 返回错误
+	entries := make(map[string]*Entry)
+	return file.UpdateJSON(t.path, &entries, func() (bool, error) {
+		for k, v := range entries {
+			if v == nil {
+				delete(entries, k)
+			}
+		}
+		return fn(entries)
+	})
+}
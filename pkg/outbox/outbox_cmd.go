@@ -0,0 +1,148 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/output"
+)
+
+// Queued 入队结果
+type Queued struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	Outbox  string `json:"outbox"`
+}
+
+type CmdRunParams struct {
+	Printer     *output.Printer
+	Logger      *log.Logger
+	Store       *Store
+	Deliver     DeliverFunc
+	Permanent   func(err error) bool
+	Interval    time.Duration
+	MaxAttempts int
+	Once        bool // 投递一次已到投递时间的消息后退出
+}
+
+func (t *CmdRunParams) Validate() error {
+	if t.Interval < 0 {
+		return fmt.Errorf("invalid flags %s: must be greater than 0", flags.Interval)
+	}
+	if t.MaxAttempts < 1 {
+		return fmt.Errorf("invalid flags %s: must be greater than 0", flags.MaxAttempts)
+	}
+	return nil
+}
+
+// CmdRun 投递发件箱中的消息，失败时按退避时间重试，不可重试的错误或超过最多投递次数时转为死信
+func CmdRun(ctx context.Context, arg *CmdRunParams) error {
+	if err := arg.Validate(); err != nil {
+		return err
+	}
+
+	w := Worker{
+		Store:       arg.Store,
+		Deliver:     arg.Deliver,
+		Permanent:   arg.Permanent,
+		MaxAttempts: arg.MaxAttempts,
+		Logger:      arg.Logger,
+	}
+	if !arg.Once {
+		return w.Run(ctx, arg.Interval)
+	}
+	stats, err := w.RunOnce(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return arg.Printer.Print(stats.String(), stats)
+}
+
+type CmdListParams struct {
+	Printer *output.Printer
+	Store   *Store
+	State   string // 为空时列出全部
+}
+
+func (t *CmdListParams) Validate() error {
+	if err := ValidateState(t.State); err != nil {
+		return fmt.Errorf("invalid flags %s: %v", flags.State, err)
+	}
+	return nil
+}
+
+// CmdList 列出发件箱中的消息，隐藏凭证参数
+func CmdList(arg *CmdListParams) error {
+	if err := arg.Validate(); err != nil {
+		return err
+	}
+
+	list, err := arg.Store.List(arg.State)
+	if err != nil {
+		return err
+	}
+	shown := make([]Entry, 0, len(list))
+	lines := make([]string, 0, len(list))
+	for _, v := range list {
+		e := *v
+		e.Message = v.Masked()
+		shown = append(shown, e)
+		lines = append(lines, e.String())
+	}
+	return arg.Printer.Print(strings.Join(lines, "\n"), shown)
+}
+
+type CmdRetryParams struct {
+	Printer *output.Printer
+	Store   *Store
+	IDs     []string // 为空时为全部死信
+}
+
+// CmdRetry 死信重新投递
+func CmdRetry(arg *CmdRetryParams) error {
+	n, err := arg.Store.Retry(arg.IDs)
+	if err != nil {
+		return err
+	}
+	return arg.Printer.Print(fmt.Sprintf("ok; retrying: %v", n), struct {
+		Retrying int `json:"retrying"`
+	}{n})
+}
+
+type CmdPurgeParams struct {
+	Printer *output.Printer
+	Store   *Store
+	IDs     []string // 为空时为全部死信
+}
+
+// CmdPurge 删除消息
+func CmdPurge(arg *CmdPurgeParams) error {
+	n, err := arg.Store.Purge(arg.IDs)
+	if err != nil {
+		return err
+	}
+	return arg.Printer.Print(fmt.Sprintf("ok; purged: %v", n), struct {
+		Purged int `json:"purged"`
+	}{n})
+}
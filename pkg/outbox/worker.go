@@ -0,0 +1,139 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// 投递参数
+const (
+	// MaxAttempts 默认的最多投递次数，超过后转为死信
+	MaxAttempts = 10
+	// Interval 默认的检查发件箱的间隔
+	Interval = 10 * time.Second
+	// RetryBackoff 第一次重试的等待时间，之后每次加倍
+	RetryBackoff = 30 * time.Second
+	// RetryMaxBackoff 重试的最长等待时间
+	RetryMaxBackoff = time.Hour
+	// Lease 取出的消息在此时间内不会被其他 worker 再次取出，每次取出一条，需大于一次投递的时间
+	Lease = 5 * time.Minute
+)
+
+// Backoff 第 attempts 次投递失败后的等待时间
+func Backoff(attempts int) time.Duration {
+	d := RetryBackoff
+	for i := 1; i < attempts && d < RetryMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > RetryMaxBackoff {
+		d = RetryMaxBackoff
+	}
+	return d
+}
+
+// DeliverFunc 投递消息
+type DeliverFunc func(ctx context.Context, e *Entry) error
+
+// Stats 一次投递的统计
+type Stats struct {
+	Sent     int `json:"sent"`     // 成功
+	Retrying int `json:"retrying"` // 失败，等待重试
+	Dead     int `json:"dead"`     // 失败，转为死信
+}
+
+func (t Stats) String() string {
+	return fmt.Sprintf("sent %d, retrying %d, dead %d", t.Sent, t.Retrying, t.Dead)
+}
+
+// Worker 投递发件箱中的消息
+type Worker struct {
+	Store       *Store
+	Deliver     DeliverFunc
+	Permanent   func(err error) bool // 是否不可重试的错误，如消息内容无效、接口返回错误，直接转为死信
+	MaxAttempts int                  // 最多投递次数，0 时为 MaxAttempts
+	Logger      *log.Logger
+}
+
+func (w *Worker) logger() *log.Logger {
+	if w.Logger == nil {
+		return log.New(io.Discard, "", 0)
+	}
+	return w.Logger
+}
+
+// RunOnce 逐条取出并投递已到投递时间的消息，直到没有到期的消息或 ctx 结束
+func (w *Worker) RunOnce(ctx context.Context) (Stats, error) {
+	var stats Stats
+	maxAttempts := w.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = MaxAttempts
+	}
+	logger := w.logger()
+	for ctx.Err() == nil {
+		e, err := w.Store.Claim(time.Now(), Lease)
+		if err != nil || e == nil {
+			return stats, err
+		}
+
+		err = w.Deliver(ctx, e)
+		switch {
+		case err == nil:
+			stats.Sent++
+			logger.Printf("sent %s %s", e.ID, e.Command)
+			err = w.Store.Done(e.ID)
+		case ctx.Err() != nil:
+			logger.Printf("interrupted %s %s: %v", e.ID, e.Command, err)
+			err = w.Store.Release(e.ID)
+		case e.Attempts >= maxAttempts || (w.Permanent != nil && w.Permanent(err)):
+			stats.Dead++
+			logger.Printf("dead %s %s, attempts %d: %v", e.ID, e.Command, e.Attempts, err)
+			err = w.Store.Fail(e.ID, err, time.Now(), true)
+		default:
+			stats.Retrying++
+			wait := Backoff(e.Attempts)
+			logger.Printf("retry %s %s in %v, attempts %d: %v", e.ID, e.Command, wait, e.Attempts, err)
+			err = w.Store.Fail(e.ID, err, time.Now().Add(wait), false)
+		}
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Run 每隔 interval 投递已到投递时间的消息，直到 ctx 结束
+func (w *Worker) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = Interval
+	}
+	w.logger().Printf("outbox worker started, outbox %s, interval %v", w.Store.Path(), interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
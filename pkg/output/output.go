@@ -0,0 +1,104 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ValidateFormat 验证输出格式
+func ValidateFormat(v string) error {
+	switch v {
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("%s not in [%q %q]", v, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// Result json 格式的成功结果
+type Result struct {
+	OK     bool `json:"ok"`
+	Result any  `json:"result,omitempty"` // 接口响应
+}
+
+// ErrorMeta 错误信息
+type ErrorMeta struct {
+	Type     string `json:"type"`      // 错误类型：invalid、network、api、unauthorized、interrupted
+	ExitCode int    `json:"exit_code"` // 退出码
+	Message  string `json:"message"`   // 错误信息
+}
+
+// Error json 格式的失败结果
+type Error struct {
+	OK    bool      `json:"ok"`
+	Error ErrorMeta `json:"error"`
+}
+
+// Printer 按输出格式输出命令执行结果，nil 时以 text 格式输出到 stdout
+type Printer struct {
+	format string
+	out    io.Writer
+}
+
+// New 新建输出
+func New(format string, out io.Writer) *Printer {
+	return &Printer{format: format, out: out}
+}
+
+// JSON 是否 json 格式
+func (p *Printer) JSON() bool {
+	return p != nil && p.format == FormatJSON
+}
+
+func (p *Printer) writer() io.Writer {
+	if p == nil || p.out == nil {
+		return os.Stdout
+	}
+	return p.out
+}
+
+// Print 输出成功结果：text 格式输出 text，json 格式输出 result
+func (p *Printer) Print(text string, result any) error {
+	if !p.JSON() {
+		_, err := fmt.Fprintln(p.writer(), text)
+		return err
+	}
+	return p.encode(Result{OK: true, Result: result})
+}
+
+// PrintError 输出失败结果：text 格式输出错误信息，json 格式输出 Error
+func (p *Printer) PrintError(errType string, exitCode int, err error) error {
+	if !p.JSON() {
+		_, werr := fmt.Fprintln(p.writer(), err)
+		return werr
+	}
+	return p.encode(Error{Error: ErrorMeta{Type: errType, ExitCode: exitCode, Message: err.Error()}})
+}
+
+func (p *Printer) encode(v any) error {
+	enc := json.NewEncoder(p.writer())
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
@@ -0,0 +1,91 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/file"
+	"github.com/lenye/pmsg/pkg/version"
+)
+
+// FileLimiter 限流状态保存在本地文件，多个进程共享同一个限流窗口
+type FileLimiter struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile 新建基于本地文件的限流
+func NewFile(path string) *FileLimiter {
+	return &FileLimiter{path: path}
+}
+
+// DefaultFile 默认的限流状态文件
+func DefaultFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, version.AppName, "ratelimit.json")
+}
+
+func (t *FileLimiter) Wait(ctx context.Context, key string, limit Limit) error {
+	if !limit.Valid() {
+		return nil
+	}
+
+	wait, err := t.reserve(hashKey(key), limit)
+	if err != nil {
+		return fmt.Errorf("rate limit failed, %w", err)
+	}
+	return sleep(ctx, wait)
+}
+
+// reserve 加文件锁读写限流状态，预约一次发送；状态文件无效时返回错误，不覆盖
+func (t *FileLimiter) reserve(key string, limit Limit) (wait time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	buckets := make(map[string]*bucket)
+	err = file.UpdateJSON(t.path, &buckets, func() (bool, error) {
+		now := time.Now()
+		b, ok := buckets[key]
+		if !ok || b == nil {
+			b = new(bucket)
+			buckets[key] = b
+		}
+		wait = b.reserve(now, limit)
+		for k, v := range buckets {
+			if v == nil || !v.FullAt.After(now) {
+				delete(buckets, k)
+			}
+		}
+		return true, nil
+	})
+	return wait, err
+}
+
+// hashKey 不在状态文件中保存 access token 等敏感信息
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
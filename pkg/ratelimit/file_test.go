@@ -0,0 +1,62 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLimiterShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	limit := Limit{Burst: 2, Interval: time.Minute}
+
+	// 两个进程共享状态文件，第3次发送等待到窗口结束
+	a, b := NewFile(path), NewFile(path)
+	for i, l := range []*FileLimiter{a, b} {
+		if wait, err := l.reserve("key", limit); err != nil || wait != 0 {
+			t.Fatalf("reserve %d wait %v, err %v, want no wait", i, wait, err)
+		}
+	}
+	wait, err := a.reserve("key", limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait < limit.Interval-time.Second || wait > limit.Interval {
+		t.Errorf("reserve 2 wait %v, want about %v", wait, limit.Interval)
+	}
+	if wait, err := b.reserve("other", limit); err != nil || wait != 0 {
+		t.Errorf("other key wait %v, err %v, want no wait", wait, err)
+	}
+}
+
+func TestFileLimiterCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 状态文件无效时返回错误，不覆盖
+	_, err := NewFile(path).reserve("key", Limit{Burst: 1, Interval: time.Second})
+	if err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("err %v, want invalid file", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{not json" {
+		t.Errorf("corrupt file overwritten: %q", data)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit 速率限制，Interval 时间内最多 Burst 次
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// Valid 是否有效的速率限制
+func (t Limit) Valid() bool {
+	return t.Burst > 0 && t.Interval > 0
+}
+
+// Limiter 按目标（access token、webhook key、url 等）限流
+type Limiter interface {
+	// Wait 预约一次发送，等待到可以发送为止
+	Wait(ctx context.Context, key string, limit Limit) error
+}
+
+// bucket 滑动窗口，记录最近 Burst 次发送（含已预约）的时间，任意 Interval 时间内最多 Burst 次
+//
+// 不用令牌桶：平台按任意1分钟内的发送次数限制，令牌桶在桶满时 Interval 内最多可发送 2*Burst 次，会超过限制
+type bucket struct {
+	Times  []time.Time `json:"times"`   // 最近的发送时间，按时间排序，可能是预约的未来时间
+	FullAt time.Time   `json:"full_at"` // 窗口内的发送全部过期的时间，之后可删除
+}
+
+// reserve 预约一次发送，返回需要等待的时间
+func (t *bucket) reserve(now time.Time, limit Limit) time.Duration {
+	n := len(t.Times)
+	if n > limit.Burst {
+		t.Times = t.Times[n-limit.Burst:]
+		n = limit.Burst
+	}
+
+	at := now
+	if n == limit.Burst {
+		if v := t.Times[0].Add(limit.Interval); v.After(at) {
+			at = v
+		}
+		t.Times = t.Times[1:]
+	}
+	if len(t.Times) > 0 && t.Times[len(t.Times)-1].After(at) {
+		at = t.Times[len(t.Times)-1]
+	}
+	t.Times = append(t.Times, at)
+	t.FullAt = at.Add(limit.Interval)
+	return at.Sub(now)
+}
+
+// sleep 等待 d，context 取消时提前返回
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
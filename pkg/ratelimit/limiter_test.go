@@ -0,0 +1,80 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketReserve(t *testing.T) {
+	limit := Limit{Burst: 20, Interval: time.Minute}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 每秒发送一次，任意1分钟内不超过20次
+	var b bucket
+	var sent []time.Time
+	for i := 0; i < 100; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		if len(sent) > 0 && sent[len(sent)-1].After(now) {
+			now = sent[len(sent)-1]
+		}
+		sent = append(sent, now.Add(b.reserve(now, limit)))
+	}
+	for i := limit.Burst; i < len(sent); i++ {
+		if d := sent[i].Sub(sent[i-limit.Burst]); d < limit.Interval {
+			t.Fatalf("send %d and %d are %v apart, want >= %v", i-limit.Burst, i, d, limit.Interval)
+		}
+	}
+	if got := sent[limit.Burst-1]; !got.Equal(start.Add(19 * time.Second)) {
+		t.Errorf("send 19 at %v, want no wait", got)
+	}
+	if got := sent[limit.Burst]; !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("send 20 at %v, want %v", got, start.Add(time.Minute))
+	}
+}
+
+func TestBucketReserveConcurrent(t *testing.T) {
+	limit := Limit{Burst: 5, Interval: 3 * time.Second}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 同时预约，超过 Burst 的按窗口排队
+	var b bucket
+	for i := 0; i < 12; i++ {
+		want := time.Duration(i/limit.Burst) * limit.Interval
+		if got := b.reserve(now, limit); got != want {
+			t.Errorf("reserve %d wait %v, want %v", i, got, want)
+		}
+	}
+	if !b.FullAt.Equal(now.Add(3 * limit.Interval)) {
+		t.Errorf("full at %v, want %v", b.FullAt, now.Add(3*limit.Interval))
+	}
+}
+
+func TestBucketReserveBurstOne(t *testing.T) {
+	limit := Limit{Burst: 1, Interval: time.Second}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var b bucket
+	for i := 0; i < 3; i++ {
+		want := time.Duration(i) * limit.Interval
+		if got := b.reserve(now, limit); got != want {
+			t.Errorf("reserve %d wait %v, want %v", i, got, want)
+		}
+	}
+	if got := b.reserve(now.Add(10*time.Second), limit); got != 0 {
+		t.Errorf("reserve after the window wait %v, want 0", got)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter 进程内限流，并发安全
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewMemory 新建进程内限流
+func NewMemory() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (t *MemoryLimiter) Wait(ctx context.Context, key string, limit Limit) error {
+	if !limit.Valid() {
+		return nil
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	b, ok := t.buckets[key]
+	if !ok {
+		b = new(bucket)
+		t.buckets[key] = b
+	}
+	wait := b.reserve(now, limit)
+	for k, v := range t.buckets {
+		if !v.FullAt.After(now) {
+			delete(t.buckets, k)
+		}
+	}
+	t.mu.Unlock()
+
+	return sleep(ctx, wait)
+}
@@ -0,0 +1,98 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// markdownEscaper 钉钉、企业微信、飞书 markdown 转义，转义后按原文显示
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`#`, `\#`, `~`, `\~`, `>`, `\>`, `|`, `\|`,
+)
+
+// slackEscaper Slack mrkdwn 转义，只需转义控制字符
+var slackEscaper = strings.NewReplacer(`&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`)
+
+// Funcs 模板函数
+//
+//	json           json 编码，如 {"content": {{json .text}}}
+//	jsonEscape     转义为 json 字符串的内容，不含引号，如 {"content": "host: {{jsonEscape .host}}"}
+//	escapeMarkdown 按平台转义 markdown
+//	truncate       按字符截断，超出时以 … 结尾，如 {{truncate 20 .summary}}
+//	now            当前时间
+//	formatTime     格式化时间，支持 time.Time、unix 秒、RFC3339 字符串，如 {{formatTime "2006-01-02 15:04" now}}
+//	env            读取环境变量，如 {{env "HOSTNAME"}}
+func Funcs(provider string) template.FuncMap {
+	return template.FuncMap{
+		"json":       jsonString,
+		"jsonEscape": jsonEscape,
+		"escapeMarkdown": func(v any) string {
+			return EscapeMarkdown(provider, fmt.Sprint(v))
+		},
+		"truncate":   truncate,
+		"now":        time.Now,
+		"formatTime": formatTime,
+		"env":        os.Getenv,
+	}
+}
+
+// EscapeMarkdown 按平台转义 markdown
+func EscapeMarkdown(provider, s string) string {
+	if provider == "slack" {
+		return slackEscaper.Replace(s)
+	}
+	return markdownEscaper.Replace(s)
+}
+
+func truncate(n int, v any) string {
+	s := []rune(fmt.Sprint(v))
+	if n <= 0 || len(s) <= n {
+		return string(s)
+	}
+	return string(s[:n-1]) + "…"
+}
+
+func formatTime(layout string, v any) (string, error) {
+	var t time.Time
+	switch x := v.(type) {
+	case time.Time:
+		t = x
+	case int:
+		t = time.Unix(int64(x), 0)
+	case int64:
+		t = time.Unix(x, 0)
+	case float64:
+		t = time.Unix(int64(x), 0)
+	case string:
+		if sec, err := strconv.ParseInt(x, 10, 64); err == nil {
+			t = time.Unix(sec, 0)
+			break
+		}
+		var err error
+		if t, err = time.Parse(time.RFC3339, x); err != nil {
+			return "", fmt.Errorf("formatTime: %v", err)
+		}
+	default:
+		return "", fmt.Errorf("formatTime: unsupported type %T", v)
+	}
+	return t.Format(layout), nil
+}
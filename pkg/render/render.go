@@ -0,0 +1,92 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Vars 模板变量
+type Vars map[string]any
+
+// LoadVars 从 json 或 yaml 文件读取模板变量
+func LoadVars(path string) (Vars, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read vars file failed, %w", err)
+	}
+	// json 是 yaml 的子集
+	var vars Vars
+	if err := yaml.Unmarshal(b, &vars); err != nil {
+		return nil, fmt.Errorf("invalid vars file %q, %w", path, err)
+	}
+	if vars == nil {
+		vars = Vars{}
+	}
+	return vars, nil
+}
+
+// ParseVar 解析 k=v 格式的模板变量
+func ParseVar(s string) (string, string, error) {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return "", "", fmt.Errorf("%q not in k=v format", s)
+	}
+	return k, v, nil
+}
+
+// Render 渲染 text/template 格式的消息内容
+// provider 为平台，决定 escapeMarkdown 的转义规则
+func Render(provider, text string, vars Vars) (string, error) {
+	tpl, err := template.New("message").Option("missingkey=error").Funcs(Funcs(provider)).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template, %w", err)
+	}
+	if vars == nil {
+		vars = Vars{}
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render template failed, %w", err)
+	}
+	return buf.String(), nil
+}
+
+// jsonString json 编码，用于在 json 中插入任意值
+func jsonString(v any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// jsonEscape 转义为 json 字符串的内容，不含首尾的引号，用于在 json 字符串中插入文本
+func jsonEscape(v any) (string, error) {
+	s, err := jsonString(fmt.Sprint(v))
+	if err != nil {
+		return "", err
+	}
+	return s[1 : len(s)-1], nil
+}
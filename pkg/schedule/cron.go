@@ -0,0 +1,216 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronFields cron 表达式字段：分 时 日 月 周
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string // 月份、星期的英文缩写，下标为 min 开始的值
+}{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// cronDescriptors 预定义的 cron 表达式
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// bits 字段的取值集合
+type bits uint64
+
+func (b bits) has(v int) bool {
+	return b&(1<<uint(v)) != 0
+}
+
+// Cron 标准的5字段 cron 表达式：分 时 日 月 周
+//
+// 支持 *、列表 1,3、范围 1-5、步长 */15 和 0-30/10、月份和星期的英文缩写；
+// 星期 0 和 7 都是星期日。日和周都不是 * 时，满足其一即可，同 crontab
+//
+// 夏令时开始时跳过的时间不执行；夏令时结束时重复的时段，小时不是 * 的任务（含 */2 等步长）只在第一次执行
+type Cron struct {
+	minute, hour, dom, month, dow bits
+	hourAny, domAny, dowAny       bool
+}
+
+// ParseCron 解析 cron 表达式，支持 @daily、@weekly 等
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if v, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = v
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	var values [5]bits
+	for i, f := range fields {
+		v, err := parseCronField(f, i)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+		}
+		values[i] = v
+	}
+	c := Cron{
+		minute:  values[0],
+		hour:    values[1],
+		dom:     values[2],
+		month:   values[3],
+		dow:     values[4],
+		hourAny: fields[1] == "*",
+		domAny:  strings.HasPrefix(fields[2], "*"),
+		dowAny:  strings.HasPrefix(fields[4], "*"),
+	}
+	// 星期日为 0 或 7
+	if c.dow.has(7) {
+		c.dow |= 1
+	}
+	return &c, nil
+}
+
+// parseCronField 解析一个字段，多个值用‘,’分隔
+func parseCronField(field string, index int) (bits, error) {
+	def := cronFields[index]
+	var b bits
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: invalid step %q", def.name, stepPart)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*":
+			lo, hi = def.min, def.max
+			if index == 4 {
+				hi = 6
+			}
+		case strings.Contains(rangePart, "-"):
+			a, z, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = cronValue(a, index); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(z, index); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: invalid range %q", def.name, rangePart)
+			}
+		default:
+			v, err := cronValue(rangePart, index)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			if hasStep {
+				hi = def.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			b |= 1 << uint(v)
+		}
+	}
+	return b, nil
+}
+
+// cronValue 字段的一个值，数字或英文缩写
+func cronValue(s string, index int) (int, error) {
+	def := cronFields[index]
+	for i, name := range def.names {
+		if strings.EqualFold(s, name) {
+			return def.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < def.min || v > def.max {
+		return 0, fmt.Errorf("%s: %q not in [%d, %d]", def.name, s, def.min, def.max)
+	}
+	return v, nil
+}
+
+// dayMatches 日期是否匹配日和周字段
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom.has(t.Day())
+	dow := c.dow.has(int(t.Weekday()))
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// repeated t 的本地时间是否在夏令时结束时重复的时段中第二次出现
+func repeated(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+	u := t.Add(-time.Duration(before-offset) * time.Second)
+	return u.Hour() == t.Hour() && u.Minute() == t.Minute()
+}
+
+// Next t 之后的下一次执行时间，按 t 的时区计算，总是晚于 t；5年内没有匹配的时间时返回零值
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// 按绝对时间截断，夏令时结束时重复的时段中 time.Date 可能返回第一次出现的、更早的时间
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		var next time.Time
+		switch {
+		case !c.month.has(int(t.Month())):
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !c.hour.has(t.Hour()):
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !c.minute.has(t.Minute()), !c.hourAny && repeated(t):
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// 夏令时开始时跳过的时间不存在，time.Date 可能返回更早的时间
+		if !next.After(t) {
+			next = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
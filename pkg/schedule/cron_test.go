@@ -0,0 +1,94 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		name  string
+		expr  string
+		after string
+		loc   *time.Location
+		want  string
+	}{
+		{name: "step", expr: "*/15 * * * *", after: "2026-01-01T00:07:30Z", loc: time.UTC, want: "2026-01-01T00:15:00Z"},
+		{name: "step range", expr: "0-30/10 9 * * *", after: "2026-01-01T09:30:00Z", loc: time.UTC, want: "2026-01-02T09:00:00Z"},
+		{name: "weekdays", expr: "0 9 * * 1-5", after: "2026-10-17T12:00:00Z", loc: time.UTC, want: "2026-10-19T09:00:00Z"},
+		{name: "dom and any dow", expr: "0 9 20 * *", after: "2026-10-18T00:00:00Z", loc: time.UTC, want: "2026-10-20T09:00:00Z"},
+		{name: "dom or dow, dow first", expr: "0 9 25 * 5", after: "2026-10-18T00:00:00Z", loc: time.UTC, want: "2026-10-23T09:00:00Z"},
+		{name: "dom or dow, dom first", expr: "0 9 20 * 5", after: "2026-10-18T00:00:00Z", loc: time.UTC, want: "2026-10-20T09:00:00Z"},
+		{name: "sunday 7", expr: "0 8 * * 7", after: "2026-10-14T00:00:00Z", loc: time.UTC, want: "2026-10-18T08:00:00Z"},
+		{name: "sunday 0", expr: "0 8 * * 0", after: "2026-10-14T00:00:00Z", loc: time.UTC, want: "2026-10-18T08:00:00Z"},
+		{name: "sunday name", expr: "0 8 * * sun", after: "2026-10-14T00:00:00Z", loc: time.UTC, want: "2026-10-18T08:00:00Z"},
+		{name: "never", expr: "0 0 31 2 *", after: "2026-01-01T00:00:00Z", loc: time.UTC, want: ""},
+
+		// 夏令时开始，02:00 EST 跳到 03:00 EDT
+		{name: "spring forward skipped", expr: "30 2 * * *", after: "2026-03-08T01:00:00-05:00", loc: newYork, want: "2026-03-09T02:30:00-04:00"},
+		{name: "spring forward step", expr: "*/15 * * * *", after: "2026-03-08T01:50:00-05:00", loc: newYork, want: "2026-03-08T03:00:00-04:00"},
+		{name: "spring forward hourly", expr: "0 * * * *", after: "2026-03-08T01:00:00-05:00", loc: newYork, want: "2026-03-08T03:00:00-04:00"},
+
+		// 夏令时结束，02:00 EDT 回到 01:00 EST
+		{name: "fall back first", expr: "30 1 * * *", after: "2026-11-01T00:00:00-04:00", loc: newYork, want: "2026-11-01T01:30:00-04:00"},
+		{name: "fall back once", expr: "30 1 * * *", after: "2026-11-01T01:30:00-04:00", loc: newYork, want: "2026-11-02T01:30:00-05:00"},
+		{name: "fall back step", expr: "*/15 * * * *", after: "2026-11-01T01:50:00-04:00", loc: newYork, want: "2026-11-01T01:00:00-05:00"},
+		{name: "fall back step repeated", expr: "*/15 * * * *", after: "2026-11-01T01:20:00-05:00", loc: newYork, want: "2026-11-01T01:30:00-05:00"},
+		{name: "fall back hourly", expr: "0 * * * *", after: "2026-11-01T01:00:00-04:00", loc: newYork, want: "2026-11-01T01:00:00-05:00"},
+		{name: "fall back step hour", expr: "30 */1 * * *", after: "2026-11-01T01:30:00-04:00", loc: newYork, want: "2026-11-01T02:30:00-05:00"},
+		{name: "fall back step hour skipped", expr: "0 */2 * * *", after: "2026-11-01T00:00:00-04:00", loc: newYork, want: "2026-11-01T02:00:00-05:00"},
+		{name: "fall back after", expr: "0 2 * * *", after: "2026-11-01T01:10:00-05:00", loc: newYork, want: "2026-11-01T02:00:00-05:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := time.Parse(time.RFC3339, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after = after.In(tt.loc)
+			got := c.Next(after)
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Fatalf("Next(%s) = %s, want zero", tt.after, got.Format(time.RFC3339))
+				}
+				return
+			}
+			if s := got.Format(time.RFC3339); s != tt.want {
+				t.Fatalf("Next(%s) = %s, want %s", tt.after, s, tt.want)
+			}
+			if !got.After(after) {
+				t.Fatalf("Next(%s) = %s, not after", tt.after, got.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
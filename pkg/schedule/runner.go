@@ -0,0 +1,130 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// PollInterval 检查任务的最长间隔，其他进程添加或修改的任务在此时间内生效
+const PollInterval = 30 * time.Second
+
+// FireFunc 发送任务的消息
+type FireFunc func(ctx context.Context, j *Job) error
+
+// Stats 一次检查的统计
+type Stats struct {
+	Sent   int `json:"sent"`   // 发送成功
+	Failed int `json:"failed"` // 发送失败
+	Missed int `json:"missed"` // 错过发送时间，已跳过
+}
+
+func (t Stats) String() string {
+	return fmt.Sprintf("sent %d, failed %d, missed %d", t.Sent, t.Failed, t.Missed)
+}
+
+// Runner 按时发送任务的消息
+type Runner struct {
+	Store  *Store
+	Fire   FireFunc
+	Logger *log.Logger
+}
+
+func (r *Runner) logger() *log.Logger {
+	if r.Logger == nil {
+		return log.New(io.Discard, "", 0)
+	}
+	return r.Logger
+}
+
+// RunOnce 发送已到发送时间的任务的消息
+func (r *Runner) RunOnce(ctx context.Context) (Stats, error) {
+	var stats Stats
+	now := time.Now()
+	due, missed, err := r.Store.Claim(now, MisfireGrace)
+	if err != nil {
+		return stats, err
+	}
+
+	logger := r.logger()
+	for _, j := range missed {
+		stats.Missed++
+		cause := fmt.Errorf("missed run at %s", j.NextRun.Format(time.RFC3339))
+		logger.Printf("missed %s %s: %v", j.ID, j.Command, cause)
+		if err := r.Store.Finish(j.ID, now, cause); err != nil {
+			return stats, err
+		}
+	}
+	for _, j := range due {
+		if ctx.Err() != nil {
+			logger.Printf("interrupted %s %s", j.ID, j.Command)
+			continue
+		}
+		err := r.Fire(ctx, j)
+		if err != nil {
+			stats.Failed++
+			logger.Printf("failed %s %s: %v", j.ID, j.Command, err)
+		} else {
+			stats.Sent++
+			logger.Printf("sent %s %s", j.ID, j.Command)
+		}
+		if err := r.Store.Finish(j.ID, now, err); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// wait 到下一个任务的发送时间的等待时间，最长 PollInterval
+func (r *Runner) wait() time.Duration {
+	d := PollInterval
+	list, err := r.Store.List()
+	if err != nil {
+		return d
+	}
+	for _, j := range list {
+		if j.Paused {
+			continue
+		}
+		if w := time.Until(j.NextRun); w < d {
+			d = w
+		}
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// Run 按时发送任务的消息，直到 ctx 结束
+func (r *Runner) Run(ctx context.Context) error {
+	r.logger().Printf("schedule runner started, schedule %s", r.Store.Path())
+	for {
+		if _, err := r.RunOnce(ctx); err != nil {
+			return err
+		}
+		timer := time.NewTimer(r.wait())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
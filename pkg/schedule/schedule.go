@@ -0,0 +1,285 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/file"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/outbox"
+	"github.com/lenye/pmsg/pkg/version"
+)
+
+// MisfireGrace 错过发送时间（如 schedule run 未运行）不超过此时间时补发，超过时跳过本次
+const MisfireGrace = time.Hour
+
+// Lease 一次性任务取出后的租期，发送成功后删除；进程在发送中退出时，租期过后由 schedule run 重新发送
+const Lease = 5 * time.Minute
+
+// Job 定时发送的消息，At 和 Cron 二选一
+type Job struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"` // 名称，用于 list 时识别
+	outbox.Message
+	At        *time.Time `json:"at,omitempty"`         // 一次性发送的时间，发送成功后删除
+	Cron      string     `json:"cron,omitempty"`       // 周期发送的 cron 表达式
+	Timezone  string     `json:"timezone"`             // 时区，Local 为 schedule run 所在系统的时区
+	Paused    bool       `json:"paused,omitempty"`     // 是否已暂停
+	NextRun   time.Time  `json:"next_run"`             // 下次发送时间
+	LastRun   *time.Time `json:"last_run,omitempty"`   // 最后一次发送时间
+	LastError string     `json:"last_error,omitempty"` // 最后一次发送的错误
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (t *Job) String() string {
+	when := t.Cron
+	if t.At != nil {
+		when = "at " + t.At.Format("2006-01-02T15:04")
+	}
+	s := fmt.Sprintf("%s  %-24s  %-24s  %s", t.ID, t.Command, when, t.Timezone)
+	if t.Name != "" {
+		s += fmt.Sprintf(", name %q", t.Name)
+	}
+	if t.Paused {
+		s += ", paused"
+	} else {
+		s += ", next " + t.NextRun.Format(time.RFC3339)
+	}
+	if t.LastError != "" {
+		s += ": " + t.LastError
+	}
+	return s
+}
+
+// Next after 之后的下一次发送时间，按任务的时区计算；一次性发送的任务返回 At
+func (t *Job) Next(after time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t.At != nil {
+		return t.At.In(loc), nil
+	}
+	c, err := ParseCron(t.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := c.Next(after.In(loc))
+	if next.IsZero() {
+		return next, fmt.Errorf("cron expression %q never fires", t.Cron)
+	}
+	return next, nil
+}
+
+// Store 定时发送的任务，保存在本地的一个文件中，读写时加文件锁，多个进程共享
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewStore 新建任务存储
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultFile 默认的任务文件，如 linux 下的 ~/.config/pmsg/schedule.json
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, version.AppName, "schedule.json")
+}
+
+// Path 任务文件
+func (t *Store) Path() string {
+	return t.path
+}
+
+// Add 添加任务，设置 id，NextRun 由调用方设置
+func (t *Store) Add(j *Job) error {
+	now := time.Now()
+	j.ID = outbox.NewID(now)
+	j.CreatedAt = now
+	j.UpdatedAt = now
+	return t.update(func(jobs map[string]*Job) (bool, error) {
+		jobs[j.ID] = j
+		return true, nil
+	})
+}
+
+// List 按添加时间排序的任务
+func (t *Store) List() ([]*Job, error) {
+	var list []*Job
+	err := t.update(func(jobs map[string]*Job) (bool, error) {
+		for _, v := range jobs {
+			list = append(list, v)
+		}
+		return false, nil
+	})
+	sortJobs(list)
+	return list, err
+}
+
+// Claim 取出已到发送时间的任务：周期任务的下次发送时间设为 now 之后，一次性任务的下次发送时间设为租期之后，
+// 多个 schedule run 进程不会重复发送；错过发送时间超过 grace 的任务跳过本次，在 missed 中返回
+func (t *Store) Claim(now time.Time, grace time.Duration) (due, missed []*Job, err error) {
+	err = t.update(func(jobs map[string]*Job) (bool, error) {
+		for _, v := range jobs {
+			if v.Paused || v.NextRun.After(now) {
+				continue
+			}
+			j := *v
+			if now.Sub(v.NextRun) > grace {
+				missed = append(missed, &j)
+			} else {
+				due = append(due, &j)
+			}
+
+			v.UpdatedAt = now
+			if v.At != nil {
+				v.NextRun = now.Add(Lease)
+				continue
+			}
+			next, err := v.Next(now)
+			if err == nil && !next.After(now) {
+				err = fmt.Errorf("next run %s is not after %s", next.Format(time.RFC3339), now.Format(time.RFC3339))
+			}
+			if err != nil {
+				// 时区或表达式无效时暂停，不影响其他任务
+				v.Paused = true
+				v.LastError = err.Error()
+			}
+			v.NextRun = next
+		}
+		return len(due) > 0 || len(missed) > 0, nil
+	})
+	sortJobs(due)
+	sortJobs(missed)
+	return due, missed, err
+}
+
+// Finish 记录发送结果；一次性任务发送成功后删除，失败或错过时暂停，保留在任务列表中，可 resume 重新发送
+func (t *Store) Finish(id string, run time.Time, cause error) error {
+	return t.update(func(jobs map[string]*Job) (bool, error) {
+		j, ok := jobs[id]
+		if !ok {
+			return false, nil
+		}
+		if j.At != nil {
+			if cause == nil {
+				delete(jobs, id)
+				return true, nil
+			}
+			j.Paused = true
+		}
+		j.LastRun = &run
+		j.LastError = ""
+		if cause != nil {
+			j.LastError = client.RedactError(cause)
+		}
+		j.UpdatedAt = time.Now()
+		return true, nil
+	})
+}
+
+// SetPaused 暂停或恢复任务，返回状态改变的数量；恢复的周期任务从现在开始计算下次发送时间，
+// 已过发送时间的一次性任务立即发送
+func (t *Store) SetPaused(ids []string, paused bool) (int, error) {
+	var n int
+	err := t.update(func(jobs map[string]*Job) (bool, error) {
+		if err := exist(jobs, ids); err != nil {
+			return false, err
+		}
+		now := time.Now()
+		for _, id := range ids {
+			j := jobs[id]
+			if j.Paused == paused {
+				continue
+			}
+			if !paused {
+				next, err := j.Next(now)
+				if err != nil {
+					return false, fmt.Errorf("schedule %q: %w", id, err)
+				}
+				if next.Before(now) {
+					next = now
+				}
+				j.NextRun = next
+				j.LastError = ""
+			}
+			j.Paused = paused
+			j.UpdatedAt = now
+			n++
+		}
+		return n > 0, nil
+	})
+	return n, err
+}
+
+// Remove 删除任务，返回数量
+func (t *Store) Remove(ids []string) (int, error) {
+	var n int
+	err := t.update(func(jobs map[string]*Job) (bool, error) {
+		if err := exist(jobs, ids); err != nil {
+			return false, err
+		}
+		for _, id := range ids {
+			if _, ok := jobs[id]; ok {
+				delete(jobs, id)
+				n++
+			}
+		}
+		return n > 0, nil
+	})
+	return n, err
+}
+
+// exist 检查 id 是否都存在
+func exist(jobs map[string]*Job, ids []string) error {
+	for _, id := range ids {
+		if _, ok := jobs[id]; !ok {
+			return fmt.Errorf("schedule %q not found", id)
+		}
+	}
+	return nil
+}
+
+func sortJobs(list []*Job) {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+}
+
+// update 加文件锁读取任务，fn 返回 true 时写回
+func (t *Store) update(fn func(jobs map[string]*Job) (bool, error)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	jobs := make(map[string]*Job)
+	return file.UpdateJSON(t.path, &jobs, func() (bool, error) {
+		for k, v := range jobs {
+			if v == nil {
+				delete(jobs, k)
+			}
+		}
+		return fn(jobs)
+	})
+}
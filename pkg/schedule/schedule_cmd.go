@@ -0,0 +1,203 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/outbox"
+	"github.com/lenye/pmsg/pkg/output"
+)
+
+// atLayouts --at 支持的时间格式，未指定时区时按 --timezone
+var atLayouts = []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+// ParseAt 解析发送时间，支持 RFC3339 和不带时区的 2006-01-02T15:04
+func ParseAt(v string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range atLayouts {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q not in format 2006-01-02T15:04 or RFC3339", v)
+}
+
+// Scheduled 添加任务的结果
+type Scheduled struct {
+	ID       string    `json:"id"`
+	Command  string    `json:"command"`
+	NextRun  time.Time `json:"next_run"`
+	Schedule string    `json:"schedule"`
+}
+
+type CmdAddParams struct {
+	Printer  *output.Printer
+	Store    *Store
+	Message  *outbox.Message
+	Name     string
+	At       string // 一次性发送的时间
+	Cron     string // 周期发送的 cron 表达式
+	Timezone string // 时区，如 Asia/Shanghai，Local 为系统时区
+}
+
+func (t *CmdAddParams) Validate() error {
+	if (t.At == "") == (t.Cron == "") {
+		return fmt.Errorf("invalid flags: exactly one of %s and %s is required", flags.At, flags.Cron)
+	}
+	if _, err := time.LoadLocation(t.Timezone); err != nil {
+		return fmt.Errorf("invalid flags %s: %v", flags.Timezone, err)
+	}
+	if t.Cron != "" {
+		if _, err := ParseCron(t.Cron); err != nil {
+			return fmt.Errorf("invalid flags %s: %v", flags.Cron, err)
+		}
+	}
+	return nil
+}
+
+// CmdAdd 添加定时发送的任务
+func CmdAdd(arg *CmdAddParams) error {
+	if err := arg.Validate(); err != nil {
+		return err
+	}
+
+	j := Job{
+		Name:     arg.Name,
+		Message:  *arg.Message,
+		Cron:     arg.Cron,
+		Timezone: arg.Timezone,
+	}
+	now := time.Now()
+	if arg.At != "" {
+		loc, _ := time.LoadLocation(arg.Timezone)
+		at, err := ParseAt(arg.At, loc)
+		if err != nil {
+			return fmt.Errorf("invalid flags %s: %v", flags.At, err)
+		}
+		if !at.After(now) {
+			return fmt.Errorf("invalid flags %s: %s is in the past", flags.At, at.Format(time.RFC3339))
+		}
+		j.At = &at
+	}
+	next, err := j.Next(now)
+	if err != nil {
+		return fmt.Errorf("invalid flags %s: %v", flags.Cron, err)
+	}
+	j.NextRun = next
+
+	if err := arg.Store.Add(&j); err != nil {
+		return err
+	}
+	return arg.Printer.Print(fmt.Sprintf("scheduled %s, next run %s", j.ID, j.NextRun.Format(time.RFC3339)),
+		Scheduled{ID: j.ID, Command: j.Command, NextRun: j.NextRun, Schedule: arg.Store.Path()})
+}
+
+type CmdListParams struct {
+	Printer *output.Printer
+	Store   *Store
+}
+
+// CmdList 列出定时发送的任务，隐藏凭证参数
+func CmdList(arg *CmdListParams) error {
+	list, err := arg.Store.List()
+	if err != nil {
+		return err
+	}
+	shown := make([]Job, 0, len(list))
+	lines := make([]string, 0, len(list))
+	for _, v := range list {
+		j := *v
+		j.Message = v.Masked()
+		shown = append(shown, j)
+		lines = append(lines, j.String())
+	}
+	return arg.Printer.Print(strings.Join(lines, "\n"), shown)
+}
+
+type CmdPauseParams struct {
+	Printer *output.Printer
+	Store   *Store
+	IDs     []string
+	Paused  bool // false 时恢复
+}
+
+// CmdPause 暂停或恢复任务
+func CmdPause(arg *CmdPauseParams) error {
+	n, err := arg.Store.SetPaused(arg.IDs, arg.Paused)
+	if err != nil {
+		return err
+	}
+	if arg.Paused {
+		return arg.Printer.Print(fmt.Sprintf("ok; paused: %v", n), struct {
+			Paused int `json:"paused"`
+		}{n})
+	}
+	return arg.Printer.Print(fmt.Sprintf("ok; resumed: %v", n), struct {
+		Resumed int `json:"resumed"`
+	}{n})
+}
+
+type CmdRemoveParams struct {
+	Printer *output.Printer
+	Store   *Store
+	IDs     []string
+}
+
+// CmdRemove 删除任务
+func CmdRemove(arg *CmdRemoveParams) error {
+	n, err := arg.Store.Remove(arg.IDs)
+	if err != nil {
+		return err
+	}
+	return arg.Printer.Print(fmt.Sprintf("ok; removed: %v", n), struct {
+		Removed int `json:"removed"`
+	}{n})
+}
+
+type CmdRunParams struct {
+	Printer *output.Printer
+	Logger  *log.Logger
+	Store   *Store
+	Fire    FireFunc
+	Once    bool // 发送一次已到发送时间的任务后退出
+}
+
+// CmdRun 按时发送任务的消息
+func CmdRun(ctx context.Context, arg *CmdRunParams) error {
+	r := Runner{
+		Store:  arg.Store,
+		Fire:   arg.Fire,
+		Logger: arg.Logger,
+	}
+	if !arg.Once {
+		return r.Run(ctx)
+	}
+	stats, err := r.RunOnce(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return arg.Printer.Print(stats.String(), stats)
+}
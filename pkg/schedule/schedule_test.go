@@ -0,0 +1,112 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreClaimOneOff(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "schedule.json"))
+	at := time.Now().Add(-time.Minute).Truncate(time.Second)
+	j := &Job{At: &at, Timezone: "UTC", NextRun: at}
+	j.Command = "slack/bot"
+	if err := store.Add(j); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	due, missed, err := store.Claim(now, MisfireGrace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || len(missed) != 0 {
+		t.Fatalf("claim due %d, missed %d, want 1, 0", len(due), len(missed))
+	}
+	// 发送前保留，租期内不重复取出
+	due, _, err = store.Claim(now.Add(time.Minute), MisfireGrace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 0 {
+		t.Fatalf("claimed again within lease")
+	}
+	due, _, err = store.Claim(now.Add(Lease), MisfireGrace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 {
+		t.Fatalf("not claimed after lease")
+	}
+
+	// 发送失败时暂停并保留
+	if err := store.Finish(j.ID, now, errors.New("connection refused")); err != nil {
+		t.Fatal(err)
+	}
+	list, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || !list[0].Paused || list[0].LastError == "" {
+		t.Fatalf("failed job %+v, want paused with error", list)
+	}
+
+	// 恢复后立即发送，成功后删除
+	if _, err := store.SetPaused([]string{j.ID}, false); err != nil {
+		t.Fatal(err)
+	}
+	due, _, err = store.Claim(time.Now(), MisfireGrace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 {
+		t.Fatalf("resumed job not claimed")
+	}
+	if err := store.Finish(j.ID, now, nil); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ = store.List(); len(list) != 0 {
+		t.Fatalf("sent job not removed: %+v", list)
+	}
+}
+
+func TestStoreClaimCron(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "schedule.json"))
+	now := time.Date(2026, 10, 18, 9, 0, 30, 0, time.UTC)
+	j := &Job{Cron: "* * * * *", Timezone: "UTC", NextRun: now.Truncate(time.Minute)}
+	if err := store.Add(j); err != nil {
+		t.Fatal(err)
+	}
+	due, _, err := store.Claim(now, MisfireGrace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 {
+		t.Fatalf("claim due %d, want 1", len(due))
+	}
+	list, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := now.Truncate(time.Minute).Add(time.Minute); !list[0].NextRun.Equal(want) {
+		t.Fatalf("next run %s, want %s", list[0].NextRun, want)
+	}
+	if due, _, _ = store.Claim(now, MisfireGrace); len(due) != 0 {
+		t.Fatalf("claimed twice at %s", now)
+	}
+}
@@ -15,12 +15,21 @@
 package bot
 
 import (
+	"context"
+	"time"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/ratelimit"
 	"github.com/lenye/pmsg/pkg/slack/client"
 )
 
+// SendLimit 每个 incoming webhook 每秒1条消息
+var SendLimit = ratelimit.Limit{Burst: 1, Interval: time.Second}
+
 // Send 发送消息
-func Send(webhookUrl, body string) error {
-	_, err := client.PostJSON(webhookUrl, body)
+func Send(ctx context.Context, c *httpClient.Client, webhookUrl, body string) error {
+	ctx = httpClient.WithRateLimit(ctx, "slack:bot:"+webhookUrl, SendLimit)
+	_, err := client.PostJSON(ctx, c, webhookUrl, body)
 	if err != nil {
 		return err
 	}
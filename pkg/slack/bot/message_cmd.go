@@ -15,27 +15,31 @@
 package bot
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
 	"github.com/lenye/pmsg/pkg/slack"
 )
 
 type CmdSendParams struct {
-	UserAgent string
-	URL       string
-	Data      string
+	Client  *client.Client
+	Printer *output.Printer
+	URL     string
+	MsgType string // 消息类型，为空时同 json
+	Data    string
 }
 
 // CmdSend 发送消息
-func CmdSend(arg *CmdSendParams) error {
+func CmdSend(ctx context.Context, arg *CmdSendParams) error {
 
-	client.SetUserAgent(arg.UserAgent)
-
-	if err := Send(arg.URL, arg.Data); err != nil {
+	n := Notifier{
+		Client: arg.Client,
+		URL:    arg.URL,
+	}
+	if _, err := n.Send(ctx, notify.Message{Type: arg.MsgType, Content: arg.Data}); err != nil {
 		return err
 	}
-	fmt.Println(slack.MessageOK)
-
-	return nil
+	return arg.Printer.Print(slack.MessageOK, nil)
 }
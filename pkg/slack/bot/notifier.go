@@ -0,0 +1,206 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+const (
+	MsgTypeText = "text" // 文本，内容为消息文本
+	MsgTypeJSON = "json" // 消息 json，如 Block Kit，原样发送
+
+	MsgTypeGeneric = notify.TypeGeneric // 通用消息，转换为 Block Kit
+)
+
+// NewBody 按消息类型生成 incoming webhook 的请求内容，消息类型为空时同 json
+func NewBody(msgType, content string) (string, error) {
+	switch msgType {
+	case MsgTypeText:
+		b, err := json.Marshal(struct {
+			Text string `json:"text"`
+		}{content})
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	case "", MsgTypeJSON:
+		if !json.Valid([]byte(content)) {
+			return "", fmt.Errorf("invalid json format")
+		}
+		return content, nil
+	case MsgTypeGeneric:
+		g, err := notify.ParseGeneric(content)
+		if err != nil {
+			return "", err
+		}
+		return NewGenericBody(g, notify.Mentions{})
+	}
+	return "", fmt.Errorf("%s not in [%q %q %q]", msgType, MsgTypeText, MsgTypeJSON, MsgTypeGeneric)
+}
+
+// severityColors 消息级别对应 attachment 的颜色
+var severityColors = map[string]string{
+	notify.SeverityInfo:     "#439fe0",
+	notify.SeverityWarning:  "#daa038",
+	notify.SeverityCritical: "#d00000",
+}
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type block struct {
+	Type     string       `json:"type"`
+	Text     *textObject  `json:"text,omitempty"`
+	ImageURL string       `json:"image_url,omitempty"`
+	AltText  string       `json:"alt_text,omitempty"`
+	Elements []blockValue `json:"elements,omitempty"`
+}
+
+type blockValue struct {
+	Type string      `json:"type"`
+	Text *textObject `json:"text"`
+	URL  string      `json:"url"`
+}
+
+type attachment struct {
+	Color  string  `json:"color"`
+	Blocks []block `json:"blocks"`
+}
+
+type genericBody struct {
+	Text        string       `json:"text"` // 通知中显示的文本
+	Blocks      []block      `json:"blocks,omitempty"`
+	Attachments []attachment `json:"attachments,omitempty"`
+}
+
+// NewGenericBody 通用消息转换为 Block Kit，有级别时放入带颜色的 attachment；@ 提醒以 <@user_id> 附在正文之后
+func NewGenericBody(g *notify.Generic, mentions notify.Mentions) (string, error) {
+	var blocks []block
+	if g.Title != "" {
+		blocks = append(blocks, block{
+			Type: "header",
+			Text: &textObject{Type: "plain_text", Text: notify.Truncate(g.FullTitle(), 150)},
+		})
+	}
+
+	text := Mrkdwn(g.Markdown(false, false, false))
+	var at []string
+	for _, v := range mentions.Users {
+		at = append(at, "<@"+v+">")
+	}
+	if mentions.All {
+		at = append(at, "<!channel>")
+	}
+	if len(at) > 0 {
+		text = strings.TrimSpace(text + "\n" + strings.Join(at, " "))
+	}
+	if text != "" {
+		blocks = append(blocks, block{
+			Type: "section",
+			Text: &textObject{Type: "mrkdwn", Text: notify.Truncate(text, 3000)},
+		})
+	}
+
+	if g.Image != "" {
+		alt := g.Title
+		if alt == "" {
+			alt = "image"
+		}
+		blocks = append(blocks, block{Type: "image", ImageURL: g.Image, AltText: alt})
+	}
+
+	if len(g.Buttons) > 0 {
+		b := block{Type: "actions"}
+		for _, v := range g.Buttons {
+			b.Elements = append(b.Elements, blockValue{
+				Type: "button",
+				Text: &textObject{Type: "plain_text", Text: v.Title},
+				URL:  v.URL,
+			})
+		}
+		blocks = append(blocks, b)
+	}
+
+	body := genericBody{Text: g.FullTitle()}
+	if body.Text == "" {
+		body.Text = notify.Truncate(notify.PlainText(g.Body), 150)
+	}
+	if color, ok := severityColors[g.Severity]; ok {
+		body.Attachments = []attachment{{Color: color, Blocks: blocks}}
+	} else {
+		body.Blocks = blocks
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+var (
+	mdImage  = regexp.MustCompile(`!\[([^\]]*)\]\(([^)]*)\)`)
+	mdLink   = regexp.MustCompile(`\[([^\]]*)\]\(([^)]*)\)`)
+	mdBold   = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	mdItalic = regexp.MustCompile(`\*([^*\n]+)\*`)
+	mdStrike = regexp.MustCompile(`~~(.+?)~~`)
+	mdHead   = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+)
+
+// Mrkdwn CommonMark 转换为 Slack mrkdwn：粗体、斜体、删除线、链接、标题
+func Mrkdwn(md string) string {
+	const bold = "\x00"
+	s := mdImage.ReplaceAllString(md, "<$2|$1>")
+	s = mdLink.ReplaceAllString(s, "<$2|$1>")
+	s = mdHead.ReplaceAllString(s, bold+"$1"+bold)
+	s = mdBold.ReplaceAllString(s, bold+"$2"+bold)
+	s = mdItalic.ReplaceAllString(s, "_${1}_")
+	s = mdStrike.ReplaceAllString(s, "~$1~")
+	return strings.ReplaceAll(s, bold, "*")
+}
+
+// Notifier Slack incoming webhook
+type Notifier struct {
+	Client *httpClient.Client
+	URL    string // webhook url
+}
+
+// Send 发送消息，只有通用消息支持 @ 提醒，其他消息可在消息文本中使用 <@user_id>
+func (t *Notifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderSlack}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	var body string
+	if g != nil {
+		body, err = NewGenericBody(g, msg.Mentions)
+	} else {
+		body, err = NewBody(msg.Type, msg.Content)
+	}
+	if err != nil {
+		return result, err
+	}
+	return result, Send(ctx, t.Client, t.URL, body)
+}
@@ -15,6 +15,8 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -24,21 +26,37 @@ import (
 )
 
 // PostJSON http post json
-func PostJSON(url, reqBody string) (http.Header, error) {
-	resp, err := httpClient.Post(url, httpClient.HdrValContentTypeJson, strings.NewReader(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("%w; %s %s, %v", httpClient.ErrRequest, http.MethodPost, url, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return resp.Header, fmt.Errorf("%w; rate limit exceeded, retry after %s second", slack.ErrRequest, resp.Header.Get("Retry-After"))
-	}
-
-	// Slack seems to send an HTML body along with 5xx error codes. Don't parse it.
-	if resp.StatusCode != http.StatusOK {
-		return resp.Header, fmt.Errorf("%w; server error: %s", slack.ErrRequest, resp.Status)
-	}
-
-	return resp.Header, nil
+func PostJSON(ctx context.Context, c *httpClient.Client, url, reqBody string) (http.Header, error) {
+	var header http.Header
+	err := c.Retry(ctx, func() error {
+		resp, err := c.Post(ctx, url, httpClient.HdrValContentTypeJson, strings.NewReader(reqBody))
+		if errors.Is(err, httpClient.ErrDryRun) {
+			return err
+		}
+		if err != nil {
+			return httpClient.RequestError(http.MethodPost, url, err, false)
+		}
+		defer resp.Body.Close()
+		header = resp.Header
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			err := fmt.Errorf("%w; rate limit exceeded, retry after %s second", slack.ErrRequest, resp.Header.Get(httpClient.HdrKeyRetryAfter))
+			return httpClient.Retryable(err, httpClient.ParseRetryAfter(resp.Header))
+		}
+
+		// invalid_token、no_service 等 webhook 无效的响应
+		switch resp.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return fmt.Errorf("%w; %s", slack.ErrUnauthorized, resp.Status)
+		}
+
+		// Slack seems to send an HTML body along with 5xx error codes. Don't parse it.
+		if resp.StatusCode != http.StatusOK {
+			err := fmt.Errorf("%w; server error: %s", slack.ErrRequest, resp.Status)
+			return httpClient.StatusError(err, resp.StatusCode, resp.Header, false)
+		}
+
+		return nil
+	})
+	return header, err
 }
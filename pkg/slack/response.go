@@ -16,6 +16,7 @@ package slack
 
 import (
 	"errors"
+	"fmt"
 )
 
 const (
@@ -23,3 +24,6 @@ const (
 )
 
 var ErrRequest = errors.New("slack request error")
+
+// ErrUnauthorized webhook url 无效或已被撤销
+var ErrUnauthorized = fmt.Errorf("%w: unauthorized", ErrRequest)
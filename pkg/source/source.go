@@ -0,0 +1,131 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Stdin 从标准输入读取消息内容
+const Stdin = "-"
+
+// 消息内容及凭证的来源前缀
+const (
+	PrefixFile = "@"     // 消息内容：@path 从文件读取，@@ 开头表示以 @ 开头的消息内容
+	SecretFile = "file:" // 凭证：file:path 从文件读取
+	SecretEnv  = "env:"  // 凭证：env:NAME 从环境变量读取
+	SecretExec = "exec:" // 凭证：exec:command args 执行命令，读取标准输出
+)
+
+// maxInputSize 从 stdin 或文件读取的最大字节数
+const maxInputSize = 4 << 20
+
+// Message 读取消息内容
+// "-" 从 stdin 读取，"@path" 从文件读取，"@@text" 表示 "@text"，其他原样返回
+// 从 stdin 或文件读取时去掉末尾的换行符
+func Message(arg string, stdin io.Reader) (string, error) {
+	switch {
+	case arg == Stdin:
+		b, err := readAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("read message from stdin failed, %w", err)
+		}
+		return trimNewline(b), nil
+	case strings.HasPrefix(arg, PrefixFile+PrefixFile):
+		return arg[len(PrefixFile):], nil
+	case strings.HasPrefix(arg, PrefixFile):
+		name := arg[len(PrefixFile):]
+		b, err := readFile(name)
+		if err != nil {
+			return "", fmt.Errorf("read message from file %q failed, %w", name, err)
+		}
+		return trimNewline(b), nil
+	}
+	return arg, nil
+}
+
+// IsSecretSource 是否 file:、env:、exec: 来源的凭证
+func IsSecretSource(value string) bool {
+	return strings.HasPrefix(value, SecretFile) || strings.HasPrefix(value, SecretEnv) || strings.HasPrefix(value, SecretExec)
+}
+
+// Secret 读取凭证
+// "file:path" 从文件读取，"env:NAME" 从环境变量读取，"exec:command args" 执行命令读取标准输出，其他原样返回
+// 读取的凭证去掉首尾空白
+func Secret(ctx context.Context, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, SecretFile):
+		name := value[len(SecretFile):]
+		b, err := readFile(name)
+		if err != nil {
+			return "", fmt.Errorf("read secret from file %q failed, %w", name, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	case strings.HasPrefix(value, SecretEnv):
+		name := value[len(SecretEnv):]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("read secret from environment variable %q failed, not set", name)
+		}
+		return strings.TrimSpace(v), nil
+	case strings.HasPrefix(value, SecretExec):
+		fields := strings.Fields(value[len(SecretExec):])
+		if len(fields) == 0 {
+			return "", errors.New("read secret from command failed, empty command")
+		}
+		var stdout bytes.Buffer
+		c := exec.CommandContext(ctx, fields[0], fields[1:]...)
+		c.Stdout = &stdout
+		c.Stderr = os.Stderr
+		if err := c.Run(); err != nil {
+			return "", fmt.Errorf("read secret from command %q failed, %w", fields[0], err)
+		}
+		return strings.TrimSpace(stdout.String()), nil
+	}
+	return value, nil
+}
+
+func readFile(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAll(f)
+}
+
+// readAll 读取全部内容，超过 maxInputSize 时返回错误
+func readAll(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, maxInputSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxInputSize {
+		return nil, fmt.Errorf("exceeds %d bytes", maxInputSize)
+	}
+	return b, nil
+}
+
+func trimNewline(b []byte) string {
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.TrimSuffix(s, "\r")
+}
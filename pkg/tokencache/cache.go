@@ -0,0 +1,58 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokencache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// RefreshMargin 到期前提前刷新的时间，避免使用即将过期的 access_token
+const RefreshMargin = 5 * time.Minute
+
+// Token 缓存的 access_token
+type Token struct {
+	Provider    string    `json:"provider"`     // 平台：weixin、workweixin
+	ID          string    `json:"id"`           // app_id 或 corp_id
+	AccessToken string    `json:"access_token"` // 接口调用凭证
+	ExpireAt    time.Time `json:"expire_at"`    // 到期时间
+}
+
+// Valid 在 margin 之后是否仍未过期
+func (t *Token) Valid(now time.Time, margin time.Duration) bool {
+	return t != nil && t.AccessToken != "" && now.Add(margin).Before(t.ExpireAt)
+}
+
+// FetchFunc 从平台获取 access_token
+type FetchFunc func(ctx context.Context) (*Token, error)
+
+// Cache access_token 缓存
+type Cache interface {
+	fmt.Stringer
+	// Get 返回未过期的 access_token，没有时调用 fetch 获取并保存
+	Get(ctx context.Context, key string, fetch FetchFunc) (*Token, error)
+	// Delete 删除缓存的 access_token，下次 Get 时重新获取
+	Delete(ctx context.Context, key string) error
+}
+
+// Key 缓存键：平台、app_id/corp_id，以及获取方式和 secret 的摘要，不保存 secret；
+// 不同获取方式（如微信 legacy 和 stable）的 access_token 互不覆盖
+func Key(provider, id, mode, secret string) string {
+	sum := sha256.Sum256([]byte(mode + "\x00" + secret))
+	return provider + ":" + id + ":" + hex.EncodeToString(sum[:8])
+}
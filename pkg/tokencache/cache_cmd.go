@@ -0,0 +1,90 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokencache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/config"
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/output"
+)
+
+// 缓存操作
+const (
+	ActionShow  = "show"
+	ActionClear = "clear"
+)
+
+// ValidateAction 验证缓存操作
+func ValidateAction(v string) error {
+	switch v {
+	case ActionShow, ActionClear:
+	default:
+		return fmt.Errorf("%s not in [%q %q]", v, ActionShow, ActionClear)
+	}
+	return nil
+}
+
+type CmdCacheParams struct {
+	Printer  *output.Printer
+	Cache    *FileCache
+	Provider string
+	ID       string // app_id 或 corp_id，为空时为平台的全部缓存
+	Action   string
+}
+
+func (t *CmdCacheParams) Validate() error {
+	if err := ValidateAction(t.Action); err != nil {
+		return fmt.Errorf("invalid flags %s: %v", flags.Cache, err)
+	}
+	return nil
+}
+
+// CmdCache 查看或清除 access_token 缓存，查看时隐藏 access_token
+func CmdCache(arg *CmdCacheParams) error {
+	if err := arg.Validate(); err != nil {
+		return err
+	}
+
+	if arg.Action == ActionClear {
+		n, err := arg.Cache.Clear(arg.Provider, arg.ID)
+		if err != nil {
+			return err
+		}
+		return arg.Printer.Print(fmt.Sprintf("ok; cleared: %v", n), struct {
+			Cleared int `json:"cleared"`
+		}{n})
+	}
+
+	list, err := arg.Cache.List(arg.Provider)
+	if err != nil {
+		return err
+	}
+	shown := make([]Token, 0, len(list))
+	lines := make([]string, 0, len(list))
+	for _, v := range list {
+		if arg.ID != "" && v.ID != arg.ID {
+			continue
+		}
+		tk := *v
+		tk.AccessToken = config.Mask(tk.AccessToken)
+		shown = append(shown, tk)
+		lines = append(lines, fmt.Sprintf("id: %q, access_token: %q, expire_at: %q", tk.ID, tk.AccessToken, tk.ExpireAt.Local().Format(time.RFC3339)))
+	}
+	return arg.Printer.Print(strings.Join(lines, "\n"), shown)
+}
@@ -0,0 +1,133 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokencache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lenye/pmsg/pkg/file"
+	"github.com/lenye/pmsg/pkg/version"
+)
+
+// FileCache access_token 保存在本地文件，多个进程共享
+// 读写时加文件锁，获取 access_token 期间其他进程等待，避免同时获取导致彼此的 access_token 失效
+type FileCache struct {
+	mu     sync.Mutex
+	path   string
+	margin time.Duration
+}
+
+// NewFile 新建基于本地文件的缓存，margin 为到期前提前刷新的时间
+func NewFile(path string, margin time.Duration) *FileCache {
+	return &FileCache{path: path, margin: margin}
+}
+
+// String 缓存来源，用于错误信息
+func (t *FileCache) String() string {
+	return "token cache " + t.path
+}
+
+// DefaultFile 默认的缓存文件
+func DefaultFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, version.AppName, "token.json")
+}
+
+func (t *FileCache) Get(ctx context.Context, key string, fetch FetchFunc) (*Token, error) {
+	var got *Token
+	err := t.update(func(tokens map[string]*Token) (bool, error) {
+		now := time.Now()
+		if tk := tokens[key]; tk.Valid(now, t.margin) {
+			got = tk
+			return false, nil
+		}
+		tk, err := fetch(ctx)
+		if err != nil {
+			return false, err
+		}
+		tokens[key] = tk
+		got = tk
+		// 顺便清理已过期的缓存
+		for k, v := range tokens {
+			if !v.Valid(now, 0) {
+				delete(tokens, k)
+			}
+		}
+		return true, nil
+	})
+	return got, err
+}
+
+func (t *FileCache) Delete(ctx context.Context, key string) error {
+	return t.update(func(tokens map[string]*Token) (bool, error) {
+		_, ok := tokens[key]
+		delete(tokens, key)
+		return ok, nil
+	})
+}
+
+// Clear 删除平台的全部缓存，id 不为空时只删除该 app_id/corp_id 的缓存，返回删除的数量
+func (t *FileCache) Clear(provider, id string) (int, error) {
+	var n int
+	err := t.update(func(tokens map[string]*Token) (bool, error) {
+		for k, v := range tokens {
+			if v.Provider == provider && (id == "" || v.ID == id) {
+				delete(tokens, k)
+				n++
+			}
+		}
+		return n > 0, nil
+	})
+	return n, err
+}
+
+// List 平台的全部缓存，按 app_id/corp_id 排序
+func (t *FileCache) List(provider string) ([]*Token, error) {
+	var list []*Token
+	err := t.update(func(tokens map[string]*Token) (bool, error) {
+		for _, v := range tokens {
+			if v.Provider == provider {
+				list = append(list, v)
+			}
+		}
+		return false, nil
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, err
+}
+
+// update 加文件锁读取缓存，fn 返回 true 时写回；缓存文件无效时返回错误，不覆盖
+func (t *FileCache) update(fn func(tokens map[string]*Token) (bool, error)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tokens := make(map[string]*Token)
+	return file.UpdateJSON(t.path, &tokens, func() (bool, error) {
+		for k, v := range tokens {
+			if v == nil {
+				delete(tokens, k)
+			}
+		}
+		return fn(tokens)
+	})
+}
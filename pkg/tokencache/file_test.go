@@ -0,0 +1,67 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokencache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileCacheGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	var fetched int
+	fetch := func(ctx context.Context) (*Token, error) {
+		fetched++
+		return &Token{AccessToken: "tk", ExpireAt: time.Now().Add(time.Hour)}, nil
+	}
+
+	// 第二个进程读取第一个进程保存的 access_token，不重新获取
+	for _, c := range []*FileCache{NewFile(path, time.Minute), NewFile(path, time.Minute)} {
+		tk, err := c.Get(context.Background(), "key", fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tk.AccessToken != "tk" {
+			t.Fatalf("token %+v", tk)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("fetched %d times, want 1", fetched)
+	}
+}
+
+func TestFileCacheCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 缓存文件无效时返回错误，不覆盖
+	c := NewFile(path, time.Minute)
+	_, err := c.Get(context.Background(), "key", func(ctx context.Context) (*Token, error) {
+		t.Fatal("fetch with a corrupt cache file")
+		return nil, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("err %v, want invalid file", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{not json" {
+		t.Errorf("corrupt file overwritten: %q", data)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokencache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache access_token 保存在内存中，同一进程中并发的请求共享一个 access_token；
+// next 不为 nil 时，内存中没有未过期的 access_token 再从 next 获取
+type MemoryCache struct {
+	mu     sync.Mutex
+	next   Cache
+	margin time.Duration
+	tokens map[string]*Token
+}
+
+// NewMemory 新建内存缓存，margin 为到期前提前刷新的时间
+func NewMemory(next Cache, margin time.Duration) *MemoryCache {
+	return &MemoryCache{next: next, margin: margin, tokens: make(map[string]*Token)}
+}
+
+// String 缓存来源，用于错误信息
+func (t *MemoryCache) String() string {
+	if t.next != nil {
+		return t.next.String()
+	}
+	return "memory token cache"
+}
+
+// Get 获取 access_token 期间其他请求等待，只获取一次
+func (t *MemoryCache) Get(ctx context.Context, key string, fetch FetchFunc) (*Token, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tk := t.tokens[key]; tk.Valid(time.Now(), t.margin) {
+		return tk, nil
+	}
+	var tk *Token
+	var err error
+	if t.next != nil {
+		tk, err = t.next.Get(ctx, key, fetch)
+	} else {
+		tk, err = fetch(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.tokens[key] = tk
+	return tk, nil
+}
+
+func (t *MemoryCache) Delete(ctx context.Context, key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.tokens, key)
+	if t.next != nil {
+		return t.next.Delete(ctx, key)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokencache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+)
+
+// ErrServerUnauthorized access_token 服务拒绝了认证凭证
+var ErrServerUnauthorized = errors.New("token server: unauthorized")
+
+// Remote 从 access_token 服务获取 access_token，不直接请求平台
+type Remote struct {
+	client *httpClient.Client
+	url    string
+	auth   string
+}
+
+// NewRemote 新建 access_token 服务的客户端，serverURL 如 http://127.0.0.1:8960
+func NewRemote(c *httpClient.Client, serverURL, auth string) *Remote {
+	return &Remote{
+		client: c,
+		url:    strings.TrimSuffix(serverURL, "/") + ServerPath,
+		auth:   auth,
+	}
+}
+
+// String 缓存来源，用于错误信息
+func (t *Remote) String() string {
+	return "token server " + strings.TrimSuffix(t.url, ServerPath)
+}
+
+// SplitKey 从缓存键中取出平台和 app_id/corp_id
+func SplitKey(key string) (provider, id string) {
+	provider, rest, _ := strings.Cut(key, ":")
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		rest = rest[:i]
+	}
+	return provider, rest
+}
+
+// do 请求 access_token 服务，respBody 为 nil 时不解析响应
+func (t *Remote) do(ctx context.Context, method, key string, respBody any) error {
+	provider, id := SplitKey(key)
+	u := t.url + "?" + url.Values{"provider": {provider}, "id": {id}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u, http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.auth)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w; token server %s %s, %s", httpClient.ErrRequest, method, t.url, httpClient.RedactError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var e serverError
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("%w; %s %s", ErrServerUnauthorized, method, t.url)
+		}
+		return fmt.Errorf("%w; token server http response status code: %v, %s %s, %s", httpClient.ErrRequest, resp.StatusCode, method, t.url, e.Error)
+	}
+	if respBody == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+		return fmt.Errorf("%w; token server invalid response json, %s %s, %v", httpClient.ErrRequest, method, t.url, err)
+	}
+	return nil
+}
+
+// Get 从 access_token 服务获取 access_token，不调用 fetch
+func (t *Remote) Get(ctx context.Context, key string, _ FetchFunc) (*Token, error) {
+	var tk Token
+	if err := t.do(ctx, http.MethodGet, key, &tk); err != nil {
+		return nil, err
+	}
+	if tk.AccessToken == "" {
+		return nil, fmt.Errorf("%w; token server returned an empty access token, %s", httpClient.ErrRequest, t.url)
+	}
+	return &tk, nil
+}
+
+// Delete 通知 access_token 服务作废 access_token
+func (t *Remote) Delete(ctx context.Context, key string) error {
+	return t.do(ctx, http.MethodDelete, key, nil)
+}
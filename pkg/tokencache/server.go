@@ -0,0 +1,235 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokencache
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerPath access_token 服务的请求路径
+const ServerPath = "/token"
+
+// 刷新时间
+const (
+	// RetryInterval 提前刷新失败后的重试间隔
+	RetryInterval = 30 * time.Second
+	// InvalidateInterval 两次作废之间的最短间隔，避免多个客户端轮流作废刚获取的 access_token
+	InvalidateInterval = 10 * time.Second
+)
+
+// serverApp 服务中的一个应用
+type serverApp struct {
+	mu        sync.Mutex
+	fetch     FetchFunc
+	token     *Token
+	fetchedAt time.Time
+}
+
+// get 返回未过期的 access_token，force 为 true 或已过期时重新获取
+func (t *serverApp) get(ctx context.Context, margin time.Duration, force bool) (*Token, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !force && t.token.Valid(time.Now(), margin) {
+		return t.token, nil
+	}
+	tk, err := t.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.token = tk
+	t.fetchedAt = time.Now()
+	return tk, nil
+}
+
+// invalidate 作废 access_token，刚获取的不作废
+func (t *serverApp) invalidate() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if time.Since(t.fetchedAt) < InvalidateInterval {
+		return false
+	}
+	t.token = nil
+	return true
+}
+
+// Server access_token 服务，集中获取并在到期前刷新 access_token，供多个 pmsg 共用
+type Server struct {
+	provider string
+	auth     string
+	margin   time.Duration
+	logger   *log.Logger
+	apps     map[string]*serverApp
+}
+
+// NewServer 新建 access_token 服务，auth 为客户端的认证凭证
+func NewServer(provider, auth string, margin time.Duration, logger *log.Logger) *Server {
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	return &Server{
+		provider: provider,
+		auth:     auth,
+		margin:   margin,
+		logger:   logger,
+		apps:     make(map[string]*serverApp),
+	}
+}
+
+// Add 增加应用，id 为 app_id 或 corp_id
+func (s *Server) Add(id string, fetch FetchFunc) {
+	s.apps[id] = &serverApp{fetch: fetch}
+}
+
+// app 按 id 查找应用，只有一个应用时 id 可为空
+func (s *Server) app(id string) (*serverApp, bool) {
+	if id == "" && len(s.apps) == 1 {
+		for _, v := range s.apps {
+			return v, true
+		}
+	}
+	app, ok := s.apps[id]
+	return app, ok
+}
+
+// refresh 获取 access_token，并在到期前 margin 刷新，直到 ctx 结束
+func (s *Server) refresh(ctx context.Context, id string, app *serverApp) {
+	for {
+		wait := RetryInterval
+		tk, err := app.get(ctx, s.margin, false)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Printf("%s %s: fetch access token failed, retry in %v: %v", s.provider, id, wait, err)
+		} else {
+			s.logger.Printf("%s %s: access token expire at %v", s.provider, id, tk.ExpireAt.Local().Format(time.RFC3339))
+			if d := time.Until(tk.ExpireAt.Add(-s.margin)); d > 0 {
+				wait = d
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// ListenAndServe 启动服务，ctx 结束时关闭
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for id, app := range s.apps {
+		go s.refresh(ctx, id, app)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	s.logger.Printf("%s access token server listening on %s", s.provider, addr)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// serverError 错误响应
+type serverError struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// authorized 检查 Authorization: Bearer 认证凭证
+func (s *Server) authorized(r *http.Request) bool {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(auth), []byte(s.auth)) == 1
+}
+
+// ServeHTTP GET /token?provider=&id= 返回 access_token，DELETE 作废 access_token
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != ServerPath {
+		writeJSON(w, http.StatusNotFound, serverError{Error: "not found"})
+		return
+	}
+	if !s.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, serverError{Error: "unauthorized"})
+		return
+	}
+
+	query := r.URL.Query()
+	id := query.Get("id")
+	app, ok := s.app(id)
+	if provider := query.Get("provider"); !ok || (provider != "" && provider != s.provider) {
+		writeJSON(w, http.StatusNotFound, serverError{Error: "unknown " + s.provider + " app: " + id})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tk, err := app.get(r.Context(), 0, false)
+		if err != nil {
+			s.logger.Printf("%s %s: fetch access token failed: %v", s.provider, id, err)
+			writeJSON(w, http.StatusBadGateway, serverError{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, tk)
+	case http.MethodDelete:
+		if app.invalidate() {
+			s.logger.Printf("%s %s: access token invalidated by %s", s.provider, id, r.RemoteAddr)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, serverError{Error: "method not allowed"})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lenye/pmsg/pkg/apiserver"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// GitHub 请求头
+const (
+	GitHubEventHeader     = "X-GitHub-Event"
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+)
+
+// GitHubEvents 通知的 GitHub 事件及 action，action 为空时不限
+var GitHubEvents = map[string][]string{
+	"push":         nil,
+	"pull_request": {"opened", "reopened", "closed", "ready_for_review"},
+	"release":      {"published"},
+	"workflow_run": {"completed"},
+}
+
+// workflowSeverities workflow_run 结论对应的消息级别，其他为 info
+var workflowSeverities = map[string]string{
+	"failure":         notify.SeverityCritical,
+	"timed_out":       notify.SeverityCritical,
+	"startup_failure": notify.SeverityCritical,
+	"cancelled":       notify.SeverityWarning,
+	"action_required": notify.SeverityWarning,
+}
+
+// GitHub GitHub webhook，使用 secret 验证 HMAC-SHA256 签名
+//
+// https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+type GitHub struct {
+	Secret string
+}
+
+func (t *GitHub) Name() string {
+	return SourceGitHub
+}
+
+// verify 验证 X-Hub-Signature-256: sha256=hex(hmac_sha256(secret, body))
+func (t *GitHub) verify(header http.Header, body []byte) error {
+	sig, ok := strings.CutPrefix(header.Get(GitHubSignatureHeader), "sha256=")
+	if !ok {
+		return fmt.Errorf("github: missing %s, %w", GitHubSignatureHeader, apiserver.ErrUnauthorized)
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("github: invalid %s, %w", GitHubSignatureHeader, apiserver.ErrUnauthorized)
+	}
+	mac := hmac.New(sha256.New, []byte(t.Secret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return fmt.Errorf("github: signature mismatch, %w", apiserver.ErrUnauthorized)
+	}
+	return nil
+}
+
+// Parse 验证签名并解析事件：push、pull_request、release、workflow_run，其他事件忽略
+func (t *GitHub) Parse(header http.Header, body []byte) (*Event, error) {
+	if err := t.verify(header, body); err != nil {
+		return nil, err
+	}
+	payload, err := decodePayload(body)
+	if err != nil {
+		return nil, err
+	}
+
+	ev := Event{Source: SourceGitHub, Name: header.Get(GitHubEventHeader), Payload: payload, Severity: notify.SeverityInfo}
+	actions, ok := GitHubEvents[ev.Name]
+	if !ok {
+		ev.Ignored = "event not supported"
+		return &ev, nil
+	}
+	if action := str(payload, "action"); actions != nil && !contains(actions, action) {
+		ev.Ignored = fmt.Sprintf("action %q not notified", action)
+		return &ev, nil
+	}
+	if ev.Name == "workflow_run" {
+		if v, ok := workflowSeverities[str(payload, "workflow_run", "conclusion")]; ok {
+			ev.Severity = v
+		}
+	}
+	return &ev, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/lenye/pmsg/pkg/apiserver"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// GitLab 请求头
+const (
+	GitLabEventHeader = "X-Gitlab-Event"
+	GitLabTokenHeader = "X-Gitlab-Token"
+)
+
+// gitLabEvents 通知的 GitLab 事件：X-Gitlab-Event 对应的事件名称
+var gitLabEvents = map[string]string{
+	"Pipeline Hook":      "pipeline",
+	"Merge Request Hook": "merge_request",
+}
+
+// GitLabPipelineStatus 通知的流水线状态，运行中等状态忽略
+var GitLabPipelineStatus = []string{"success", "failed", "canceled"}
+
+// GitLabMergeRequestActions 通知的合并请求 action
+var GitLabMergeRequestActions = []string{"open", "reopen", "close", "merge"}
+
+// GitLab GitLab webhook，使用 secret token 验证请求
+//
+// https://docs.gitlab.com/ee/user/project/integrations/webhooks.html
+type GitLab struct {
+	Token string
+}
+
+func (t *GitLab) Name() string {
+	return SourceGitLab
+}
+
+// Parse 验证 X-Gitlab-Token 并解析事件：pipeline、merge_request，其他事件忽略
+func (t *GitLab) Parse(header http.Header, body []byte) (*Event, error) {
+	if subtle.ConstantTimeCompare([]byte(header.Get(GitLabTokenHeader)), []byte(t.Token)) != 1 {
+		return nil, fmt.Errorf("gitlab: invalid %s, %w", GitLabTokenHeader, apiserver.ErrUnauthorized)
+	}
+	payload, err := decodePayload(body)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := header.Get(GitLabEventHeader)
+	ev := Event{Source: SourceGitLab, Name: gitLabEvents[kind], Payload: payload, Severity: notify.SeverityInfo}
+	switch ev.Name {
+	case "pipeline":
+		status := str(payload, "object_attributes", "status")
+		if !contains(GitLabPipelineStatus, status) {
+			ev.Ignored = fmt.Sprintf("pipeline status %q not notified", status)
+		}
+		switch status {
+		case "failed":
+			ev.Severity = notify.SeverityCritical
+		case "canceled":
+			ev.Severity = notify.SeverityWarning
+		}
+	case "merge_request":
+		if action := str(payload, "object_attributes", "action"); !contains(GitLabMergeRequestActions, action) {
+			ev.Ignored = fmt.Sprintf("action %q not notified", action)
+		}
+	default:
+		ev.Name = kind
+		ev.Ignored = "event not supported"
+	}
+	return &ev, nil
+}
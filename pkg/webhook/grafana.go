@@ -0,0 +1,61 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lenye/pmsg/pkg/alertmanager"
+)
+
+// Grafana Grafana 告警 webhook 通知，使用服务的 Authorization: Bearer 认证
+//
+// 请求同 Alertmanager webhook，另有 title、message、state 以及每个告警的 valueString、dashboardURL、panelURL、silenceURL
+//
+// https://grafana.com/docs/grafana/latest/alerting/configure-notifications/manage-contact-points/integrations/webhook-notifier/
+type Grafana struct {
+	UserLabel   string // @ 提醒的用户 id 标签
+	MobileLabel string // @ 提醒的手机号标签
+}
+
+func (t *Grafana) Name() string {
+	return SourceGrafana
+}
+
+// Parse 解析告警，消息级别和 @ 提醒同 alertmanager
+func (t *Grafana) Parse(header http.Header, body []byte) (*Event, error) {
+	payload, err := decodePayload(body)
+	if err != nil {
+		return nil, err
+	}
+	var msg alertmanager.Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("invalid grafana webhook json, %v", err)
+	}
+	if len(msg.Alerts) == 0 {
+		return nil, fmt.Errorf("grafana webhook has no alerts")
+	}
+
+	firing := msg.Firing()
+	return &Event{
+		Source:   SourceGrafana,
+		Name:     "alert",
+		Payload:  payload,
+		Severity: alertmanager.Severity(firing),
+		Mentions: alertmanager.Mentions(firing, t.UserLabel, t.MobileLabel),
+	}, nil
+}
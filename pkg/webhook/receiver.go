@@ -0,0 +1,52 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+
+	"github.com/lenye/pmsg/pkg/apiserver"
+	"github.com/lenye/pmsg/pkg/fanout"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// Receiver 接收 webhook，按模板渲染后发送到全部目标
+type Receiver struct {
+	Client     *client.Client
+	Dests      []fanout.Destination
+	Template   *Template
+	MinSuccess int // 最少成功的目标数，0 时为 1
+}
+
+// Handler 处理来源的 webhook 请求，忽略的事件不发送，输出 Ignored
+func (t *Receiver) Handler(src Source) apiserver.HandlerFunc {
+	return func(ctx context.Context, req *apiserver.Request) error {
+		ev, err := src.Parse(req.Header, []byte(req.Data))
+		if err != nil {
+			return err
+		}
+		if ev.Ignored != "" {
+			ignored := Ignored{Source: ev.Source, Event: ev.Name, Ignored: ev.Ignored}
+			return req.Printer.Print(ignored.String(), ignored)
+		}
+		g, err := t.Template.Render(ev)
+		if err != nil {
+			return err
+		}
+		msg := notify.Message{Type: notify.TypeGeneric, Generic: g, Mentions: ev.Mentions}
+		return fanout.Deliver(ctx, t.Client, req.Printer, t.Dests, msg, t.MinSuccess)
+	}
+}
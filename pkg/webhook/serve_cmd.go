@@ -0,0 +1,95 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/lenye/pmsg/pkg/apiserver"
+	"github.com/lenye/pmsg/pkg/fanout"
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
+)
+
+type CmdServeParams struct {
+	Client        *client.Client
+	Logger        *log.Logger
+	Classify      apiserver.ClassifyFunc
+	Listen        string
+	Auth          string
+	To            []string
+	GitHubSecret  string
+	GitLabToken   string
+	TemplateFile  string
+	AtUserLabel   string
+	AtMobileLabel string
+	AllOrNothing  bool
+	MinSuccess    int // 最少成功的目标数，0 时为 1
+}
+
+func (t *CmdServeParams) Validate() error {
+	if len(t.To) == 0 {
+		return fmt.Errorf("invalid flags %s: at least one destination is required", flags.To)
+	}
+	if t.MinSuccess < 0 || t.MinSuccess > len(t.To) {
+		return fmt.Errorf("invalid flags %s: must be between 1 and the number of destinations %d", flags.MinSuccess, len(t.To))
+	}
+	if t.AllOrNothing && t.MinSuccess > 0 {
+		return fmt.Errorf("invalid flags: %s and %s cannot be used together", flags.AllOrNothing, flags.MinSuccess)
+	}
+	return apiserver.ValidateListen(t.Listen, t.Auth)
+}
+
+// CmdServe 启动 webhook 接收服务：grafana 使用服务的认证凭证，设置 secret 后才接收 github、gitlab
+func CmdServe(ctx context.Context, arg *CmdServeParams) error {
+
+	if err := arg.Validate(); err != nil {
+		return err
+	}
+
+	dests, err := fanout.ParseDestinations(ctx, arg.To)
+	if err != nil {
+		return err
+	}
+	tpl, err := LoadTemplate(arg.TemplateFile)
+	if err != nil {
+		return fmt.Errorf("invalid flags %s: %v", flags.TemplateFile, err)
+	}
+
+	r := Receiver{
+		Client:     arg.Client,
+		Dests:      dests,
+		Template:   tpl,
+		MinSuccess: arg.MinSuccess,
+	}
+	if arg.AllOrNothing {
+		r.MinSuccess = len(dests)
+	}
+
+	srv := apiserver.NewServer(arg.Auth, arg.Classify, arg.Logger)
+	srv.HandlePath(apiserver.WebhookPath+SourceGrafana, r.Handler(&Grafana{UserLabel: arg.AtUserLabel, MobileLabel: arg.AtMobileLabel}))
+	if arg.GitHubSecret != "" {
+		srv.HandleSigned(apiserver.WebhookPath+SourceGitHub, r.Handler(&GitHub{Secret: arg.GitHubSecret}))
+	}
+	if arg.GitLabToken != "" {
+		srv.HandleSigned(apiserver.WebhookPath+SourceGitLab, r.Handler(&GitLab{Token: arg.GitLabToken}))
+	}
+	if arg.Logger != nil {
+		arg.Logger.Printf("webhook routes: %v", srv.Routes())
+	}
+	return srv.ListenAndServe(ctx, arg.Listen)
+}
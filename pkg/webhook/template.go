@@ -0,0 +1,248 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/render"
+)
+
+// MaxItems 消息中最多列出的提交或告警数，其余只计数
+const MaxItems = 10
+
+// DefaultTemplate 默认模板，每个事件有 "{source}.{name}.title"（通用消息标题）和 "{source}.{name}.body"（CommonMark 正文），
+// 模板数据为 webhook 请求的 json
+//
+// 自定义模板可只重新定义其中的部分模板，如 "github.push.body"
+const DefaultTemplate = `
+{{- define "github.push.title" -}}
+[{{.repository.full_name}}] {{if .deleted}}{{refType .ref}} {{refName .ref}} deleted{{else if .created}}{{refType .ref}} {{refName .ref}} created{{else if .commits}}{{len .commits}} new commit(s) to {{refName .ref}}{{else}}{{refType .ref}} {{refName .ref}} updated{{end}}{{if .forced}} (force-pushed){{end}}
+{{- end}}
+
+{{- define "github.push.body" -}}
+{{range first .commits}}- [{{shortSHA .id}}]({{.url}}) {{firstLine .message}} - {{.author.name}}
+{{end}}
+{{- with more .commits}}- and {{.}} more
+{{end}}
+{{- with .pusher}}pushed by {{.name}}{{end}}{{with .compare}} · [compare]({{.}}){{end}}
+{{- end}}
+
+{{- define "github.pull_request.title" -}}
+[{{.repository.full_name}}] Pull request #{{.number}} {{if and (eq .action "closed") .pull_request.merged}}merged{{else}}{{replace .action "_" " "}}{{end}}: {{.pull_request.title}}
+{{- end}}
+
+{{- define "github.pull_request.body" -}}
+{{with .pull_request}}**{{.title}}**
+{{.user.login}} wants to merge ` + "`{{.head.ref}}`" + ` into ` + "`{{.base.ref}}`" + `
+[View pull request]({{.html_url}}){{end}}
+{{- end}}
+
+{{- define "github.release.title" -}}
+[{{.repository.full_name}}] Release {{.release.tag_name}} published{{if .release.prerelease}} (pre-release){{end}}
+{{- end}}
+
+{{- define "github.release.body" -}}
+{{with .release}}**{{with .name}}{{.}}{{else}}{{.tag_name}}{{end}}**{{with .author}} by {{.login}}{{end}}
+{{with .body}}
+{{truncate 500 .}}
+{{end}}
+[View release]({{.html_url}}){{end}}
+{{- end}}
+
+{{- define "github.workflow_run.title" -}}
+[{{.repository.full_name}}] Workflow {{.workflow_run.name}} {{.workflow_run.conclusion}}
+{{- end}}
+
+{{- define "github.workflow_run.body" -}}
+{{with .workflow_run}}**{{.display_title}}**
+Run #{{.run_number}} on ` + "`{{.head_branch}}`" + ` ({{shortSHA .head_sha}}) triggered by {{.event}}{{with .actor}} · {{.login}}{{end}}
+[View run]({{.html_url}}){{end}}
+{{- end}}
+
+{{- define "gitlab.pipeline.title" -}}
+[{{.project.path_with_namespace}}] Pipeline #{{.object_attributes.id}} {{.object_attributes.status}}
+{{- end}}
+
+{{- define "gitlab.pipeline.body" -}}
+{{with .commit}}**{{firstLine .message}}**
+{{end}}
+{{- with .object_attributes}}Pipeline on ` + "`{{.ref}}`" + ` ({{shortSHA .sha}}){{with .duration}} in {{.}}s{{end}}{{end}}{{with .user}} by {{.name}}{{end}}
+[View pipeline]({{.project.web_url}}/-/pipelines/{{.object_attributes.id}})
+{{- end}}
+
+{{- define "gitlab.merge_request.title" -}}
+{{- $action := .object_attributes.action -}}
+[{{.project.path_with_namespace}}] Merge request !{{.object_attributes.iid}} {{if eq $action "open"}}opened{{else if eq $action "merge"}}merged{{else if eq $action "close"}}closed{{else if eq $action "reopen"}}reopened{{else}}{{$action}}{{end}}: {{.object_attributes.title}}
+{{- end}}
+
+{{- define "gitlab.merge_request.body" -}}
+**{{.object_attributes.title}}**
+{{with .user}}{{.name}} · {{end}}` + "`{{.object_attributes.source_branch}}`" + ` → ` + "`{{.object_attributes.target_branch}}`" + `
+[View merge request]({{.object_attributes.url}})
+{{- end}}
+
+{{- define "grafana.alert.title" -}}
+{{with .title}}{{.}}{{else}}Grafana alert {{.status}}{{end}}
+{{- end}}
+
+{{- define "grafana.alert" -}}
+- **{{.labels.alertname}}**{{with .annotations}}{{with .summary}} {{.}}{{end}}{{end}}{{with .valueString}}
+  {{.}}{{end}}
+  {{- if or .dashboardURL .panelURL .silenceURL}}
+  {{with .dashboardURL}}[dashboard]({{.}}) {{end}}{{with .panelURL}}[panel]({{.}}) {{end}}{{with .silenceURL}}[silence]({{.}}){{end}}{{end}}
+{{- end}}
+
+{{- define "grafana.alert.body" -}}
+{{- $firing := alerts "firing" .alerts}}{{$resolved := alerts "resolved" .alerts -}}
+{{- if $firing}}**Firing**
+{{range first $firing}}
+{{template "grafana.alert" .}}{{end}}
+{{- with more $firing}}
+- and {{.}} more{{end}}
+{{end}}
+{{- if and $firing $resolved}}
+{{end}}
+{{- if $resolved}}**Resolved**
+{{range first $resolved}}
+{{template "grafana.alert" .}}{{end}}
+{{- with more $resolved}}
+- and {{.}} more{{end}}
+{{end}}
+{{- with .externalURL}}
+[Grafana]({{.}}){{end}}
+{{- end}}
+`
+
+// funcs 模板函数，另有 --template 的模板函数
+//
+//	refName   分支或标签名，去掉 refs/heads/、refs/tags/
+//	refType   refs/tags/ 为 tag，其他为 branch
+//	shortSHA  7位提交 id
+//	firstLine 第一行，如提交说明的标题
+//	first     前 MaxItems 个
+//	more      超过 MaxItems 的个数
+//	alerts    按状态筛选告警，如 {{alerts "firing" .alerts}}
+//	replace   替换，如 {{replace .action "_" " "}}
+func funcs() template.FuncMap {
+	m := render.Funcs("")
+	m["refName"] = func(ref string) string {
+		ref = strings.TrimPrefix(ref, "refs/heads/")
+		return strings.TrimPrefix(ref, "refs/tags/")
+	}
+	m["refType"] = func(ref string) string {
+		if strings.HasPrefix(ref, "refs/tags/") {
+			return "tag"
+		}
+		return "branch"
+	}
+	m["shortSHA"] = func(sha string) string {
+		if len(sha) > 7 {
+			return sha[:7]
+		}
+		return sha
+	}
+	m["firstLine"] = func(s string) string {
+		line, _, _ := strings.Cut(s, "\n")
+		return strings.TrimSpace(line)
+	}
+	m["first"] = func(list []any) []any {
+		if len(list) > MaxItems {
+			return list[:MaxItems]
+		}
+		return list
+	}
+	m["more"] = func(list []any) int {
+		if len(list) > MaxItems {
+			return len(list) - MaxItems
+		}
+		return 0
+	}
+	m["alerts"] = func(status string, list []any) []any {
+		var alerts []any
+		for _, v := range list {
+			if a, ok := v.(map[string]any); ok && a["status"] == status {
+				alerts = append(alerts, v)
+			}
+		}
+		return alerts
+	}
+	m["replace"] = func(s, old, new string) string {
+		return strings.ReplaceAll(s, old, new)
+	}
+	return m
+}
+
+// Template 事件消息模板
+type Template struct {
+	tpl *template.Template
+}
+
+// NewTemplate 解析模板，text 中的定义覆盖 DefaultTemplate 中的同名模板，text 为空时使用默认模板
+func NewTemplate(text string) (*Template, error) {
+	tpl, err := template.New("webhook").Funcs(funcs()).Parse(DefaultTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		if tpl, err = tpl.Parse(text); err != nil {
+			return nil, fmt.Errorf("invalid template, %w", err)
+		}
+	}
+	return &Template{tpl: tpl}, nil
+}
+
+// LoadTemplate 从文件读取模板，path 为空时使用默认模板
+func LoadTemplate(path string) (*Template, error) {
+	if path == "" {
+		return NewTemplate("")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read template file failed, %w", err)
+	}
+	return NewTemplate(string(b))
+}
+
+// Render 按事件的模板渲染为通用消息
+func (t *Template) Render(ev *Event) (*notify.Generic, error) {
+	prefix := ev.Source + "." + ev.Name
+	title, err := t.execute(prefix+".title", ev.Payload)
+	if err != nil {
+		return nil, err
+	}
+	body, err := t.execute(prefix+".body", ev.Payload)
+	if err != nil {
+		return nil, err
+	}
+	g := notify.Generic{Title: title, Body: body, Severity: ev.Severity}
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rendered message, %v", err)
+	}
+	return &g, nil
+}
+
+func (t *Template) execute(name string, data map[string]any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("render template %q failed, %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// 来源
+const (
+	SourceGitHub  = "github"
+	SourceGitLab  = "gitlab"
+	SourceGrafana = "grafana"
+)
+
+// Event 转换为消息的 webhook 事件
+type Event struct {
+	Source   string         // 来源，如 github
+	Name     string         // 事件，如 push，模板名称为 {source}.{name}.title 和 {source}.{name}.body
+	Payload  map[string]any // webhook 请求的 json，即模板数据
+	Severity string         // 消息级别
+	Mentions notify.Mentions
+	Ignored  string // 不发送消息的原因，不为空时忽略事件
+}
+
+// Ignored 忽略的事件，如 GitHub ping 或不通知的 action
+type Ignored struct {
+	Source  string `json:"source"`
+	Event   string `json:"event"`
+	Ignored string `json:"ignored"` // 原因
+}
+
+func (t Ignored) String() string {
+	return fmt.Sprintf("ignored %s %s: %s", t.Source, t.Event, t.Ignored)
+}
+
+// Source webhook 来源：验证请求签名并解析事件
+type Source interface {
+	Name() string
+	Parse(header http.Header, body []byte) (*Event, error)
+}
+
+// decodePayload 解析 json，数字保持原文，避免大的 id 显示为科学计数法
+func decodePayload(body []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var payload map[string]any
+	if err := dec.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid webhook json, %v", err)
+	}
+	if payload == nil {
+		return nil, fmt.Errorf("invalid webhook json, not an object")
+	}
+	return payload, nil
+}
+
+// str 读取 json 中的字符串，如 str(payload, "workflow_run", "conclusion")
+func str(payload map[string]any, keys ...string) string {
+	var v any = payload
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = m[k]
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// contains 是否在列表中
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lenye/pmsg/pkg/apiserver"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+const testAuth = "server-auth"
+
+// newTestServer 注册 github、gitlab、grafana，处理函数只输出解析的事件，不发送消息
+func newTestServer(events *[]*Event) *apiserver.Server {
+	handler := func(src Source) apiserver.HandlerFunc {
+		return func(ctx context.Context, req *apiserver.Request) error {
+			ev, err := src.Parse(req.Header, []byte(req.Data))
+			if err != nil {
+				return err
+			}
+			*events = append(*events, ev)
+			return req.Printer.Print(ev.Name, ev.Name)
+		}
+	}
+	classify := func(ctx context.Context, err error) (string, int) {
+		return "invalid", 1
+	}
+	srv := apiserver.NewServer(testAuth, classify, nil)
+	srv.HandlePath(apiserver.WebhookPath+SourceGrafana, handler(&Grafana{UserLabel: "at_user", MobileLabel: "at_mobile"}))
+	srv.HandleSigned(apiserver.WebhookPath+SourceGitHub, handler(&GitHub{Secret: "gh-secret"}))
+	srv.HandleSigned(apiserver.WebhookPath+SourceGitLab, handler(&GitLab{Token: "gl-token"}))
+	return srv
+}
+
+func post(srv http.Handler, source string, header http.Header, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, apiserver.WebhookPath+source, strings.NewReader(body))
+	for k, v := range header {
+		for _, x := range v {
+			req.Header.Add(k, x)
+		}
+	}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func githubSignature(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHubSignature(t *testing.T) {
+	body := `{"action":"opened","number":1}`
+	tests := []struct {
+		name      string
+		signature string
+		code      int
+	}{
+		{"valid", githubSignature("gh-secret", body), http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong secret", githubSignature("other", body), http.StatusUnauthorized},
+		{"not hex", "sha256=zz", http.StatusUnauthorized},
+		{"no prefix", strings.TrimPrefix(githubSignature("gh-secret", body), "sha256="), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*Event
+			srv := newTestServer(&events)
+			header := http.Header{GitHubEventHeader: {"pull_request"}}
+			if tt.signature != "" {
+				header.Set(GitHubSignatureHeader, tt.signature)
+			}
+			// 签名验证不使用服务的 Authorization: Bearer 认证
+			w := post(srv, SourceGitHub, header, body)
+			if w.Code != tt.code {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+			if tt.code == http.StatusOK {
+				if len(events) != 1 || events[0].Name != "pull_request" || events[0].Ignored != "" {
+					t.Errorf("events %+v", events)
+				}
+				return
+			}
+			if len(events) != 0 {
+				t.Errorf("unauthorized request parsed: %+v", events)
+			}
+			if !strings.Contains(w.Body.String(), `"type":"unauthorized"`) {
+				t.Errorf("body %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestGitLabToken(t *testing.T) {
+	body := `{"object_attributes":{"status":"failed"}}`
+	tests := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"valid", "gl-token", http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "gl-token2", http.StatusUnauthorized},
+		{"bearer auth", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*Event
+			srv := newTestServer(&events)
+			header := http.Header{GitLabEventHeader: {"Pipeline Hook"}}
+			if tt.token != "" {
+				header.Set(GitLabTokenHeader, tt.token)
+			}
+			if tt.name == "bearer auth" {
+				// 服务的认证凭证不能代替 X-Gitlab-Token
+				header.Set("Authorization", "Bearer "+testAuth)
+			}
+			w := post(srv, SourceGitLab, header, body)
+			if w.Code != tt.code {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+			if tt.code != http.StatusOK {
+				if len(events) != 0 {
+					t.Errorf("unauthorized request parsed: %+v", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Name != "pipeline" || events[0].Severity != notify.SeverityCritical {
+				t.Errorf("events %+v", events)
+			}
+		})
+	}
+}
+
+func TestGrafana(t *testing.T) {
+	body := `{"status":"firing","alerts":[
+		{"status":"firing","labels":{"alertname":"cpu","severity":"critical","at_user":"u1|u2","at_mobile":"13800000000"}},
+		{"status":"firing","labels":{"alertname":"disk","at_user":"u2"}},
+		{"status":"resolved","labels":{"alertname":"mem","at_user":"u3"}}
+	]}`
+
+	var events []*Event
+	srv := newTestServer(&events)
+
+	// grafana 使用服务的 Authorization: Bearer 认证
+	if w := post(srv, SourceGrafana, nil, body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("no auth: status %d, want 401", w.Code)
+	}
+	if w := post(srv, SourceGrafana, http.Header{"Authorization": {"Bearer wrong"}}, body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong auth: status %d, want 401", w.Code)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unauthorized request parsed: %+v", events)
+	}
+
+	w := post(srv, SourceGrafana, http.Header{"Authorization": {"Bearer " + testAuth}}, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if len(events) != 1 {
+		t.Fatalf("events %+v", events)
+	}
+	ev := events[0]
+	if ev.Name != "alert" || ev.Severity != notify.SeverityCritical {
+		t.Errorf("event %s severity %s", ev.Name, ev.Severity)
+	}
+	// 只 @ 触发中告警的用户
+	want := notify.Mentions{Users: []string{"u1", "u2"}, Mobiles: []string{"13800000000"}}
+	if !reflect.DeepEqual(ev.Mentions, want) {
+		t.Errorf("mentions %+v, want %+v", ev.Mentions, want)
+	}
+	for _, body := range []string{`{"alerts":[]}`, `not json`} {
+		w := post(srv, SourceGrafana, http.Header{"Authorization": {"Bearer " + testAuth}}, body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, w.Code)
+		}
+	}
+}
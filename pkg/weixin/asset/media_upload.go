@@ -15,11 +15,14 @@
 package asset
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/lenye/pmsg/pkg/file"
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 )
@@ -44,6 +47,25 @@ func ValidateMediaType(v string) error {
 	return nil
 }
 
+// MediaLimits 临时素材大小及格式限制
+var MediaLimits = map[string]file.Limit{
+	TypeImage: {MaxSize: 10 * file.MB, Exts: []string{".png", ".jpeg", ".jpg", ".gif"}},
+	TypeVoice: {MaxSize: 2 * file.MB, Exts: []string{".amr", ".mp3"}},
+	TypeVideo: {MaxSize: 10 * file.MB, Exts: []string{".mp4"}},
+	TypeThumb: {MaxSize: 64 * file.KB, Exts: []string{".jpg", ".jpeg"}},
+}
+
+// ValidateMediaFile 检查临时素材文件大小及格式
+func ValidateMediaFile(mediaType, filename string) error {
+	if err := ValidateMediaType(mediaType); err != nil {
+		return err
+	}
+	if err := MediaLimits[mediaType].Check(filename); err != nil {
+		return fmt.Errorf("invalid %s media file %q: %w", mediaType, filename, err)
+	}
+	return nil
+}
+
 type MediaResponse struct {
 	weixin.ResponseMeta
 	MediaMeta
@@ -69,18 +91,21 @@ func (t MediaMeta) String() string {
 	return strings.Join(sb, ", ")
 }
 
-const reqURL = weixin.Host + "/cgi-bin/media/upload?access_token="
+const reqPath = "/cgi-bin/media/upload?access_token="
 
 // MediaUpload 微信公众号/小程序 新增临时素材 媒体文件在微信后台保存时间为3天，即3天后media_id失效。
-func MediaUpload(accessToken, mediaType, filename string) (*MediaMeta, error) {
-	u := reqURL + url.QueryEscape(accessToken) + "&type=" + url.QueryEscape(mediaType)
+func MediaUpload(ctx context.Context, c *httpClient.Client, accessToken, mediaType, filename string) (*MediaMeta, error) {
+	if err := ValidateMediaFile(mediaType, filename); err != nil {
+		return nil, err
+	}
+	u := weixin.URL(c, reqPath) + url.QueryEscape(accessToken) + "&type=" + url.QueryEscape(mediaType)
 	var resp MediaResponse
-	_, err := client.PostFileJSON(u, FieldName, filename, &resp)
+	_, err := client.PostFileJSON(ctx, c, u, FieldName, filename, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return &resp.MediaMeta, nil
 }
@@ -15,20 +15,26 @@
 package asset
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/file"
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/token"
 )
 
 type CmdMediaUploadParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	AppID       string
 	AppSecret   string
+	TokenMode   string
 	MediaType   string
 	File        string
 }
@@ -45,31 +51,34 @@ func (t *CmdMediaUploadParams) Validate() error {
 		return fmt.Errorf("file is not exist, %v", t.File)
 	}
 
+	if err := ValidateMediaFile(t.MediaType, t.File); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // CmdMediaUpload 新增临时素材 微信公众号/小程序
-func CmdMediaUpload(arg *CmdMediaUploadParams) error {
+func CmdMediaUpload(ctx context.Context, arg *CmdMediaUploadParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.AppID, arg.AppSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
+	cred := token.Credential{
+		Client:      arg.Client,
+		Cache:       arg.TokenCache,
+		Mode:        arg.TokenMode,
+		AccessToken: arg.AccessToken,
+		AppID:       arg.AppID,
+		AppSecret:   arg.AppSecret,
 	}
-
-	if meta, err := MediaUpload(arg.AccessToken, arg.MediaType, arg.File); err != nil {
+	var meta *MediaMeta
+	if err := token.Do(ctx, &cred, func(accessToken string) (err error) {
+		meta, err = MediaUpload(ctx, arg.Client, accessToken, arg.MediaType, arg.File)
+		return err
+	}); err != nil {
 		return err
-	} else {
-		fmt.Println(fmt.Sprintf("%v; %v", weixin.MessageOK, meta))
 	}
-
-	return nil
+	return arg.Printer.Print(fmt.Sprintf("%v; %v", weixin.MessageOK, meta), meta)
 }
@@ -16,41 +16,76 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/weixin"
 )
 
 // CheckHttpResponseStatusCode 检查HTTP响应状态码
 func CheckHttpResponseStatusCode(method, url string, statusCode int) error {
 	if statusCode/100 != 2 {
-		return fmt.Errorf("%w; http response status code: %v, %s %s", httpClient.ErrRequest, statusCode, method, url)
+		return fmt.Errorf("%w; http response status code: %v, %s %s", httpClient.ErrRequest, statusCode, method, httpClient.RedactURL(url))
 	}
 	return nil
 }
 
-func GetJSON(url string, respBody any) (http.Header, error) {
-	resp, err := httpClient.Get(url)
+// decodeJSON 检查http响应并解析json，可重试时返回 *httpClient.RetryableError；
+// idempotent 为 false 时请求失败只在请求确定未发出时重试，响应状态码只在限流和服务不可用时重试
+func decodeJSON(method, url string, idempotent bool, resp *http.Response, err error, respBody any) (http.Header, error) {
+	if errors.Is(err, httpClient.ErrDryRun) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%w; %s %s, %v", httpClient.ErrRequest, http.MethodGet, url, err)
+		return nil, httpClient.RequestError(method, url, err, idempotent)
 	}
 	defer resp.Body.Close()
 
-	if err := CheckHttpResponseStatusCode(http.MethodGet, url, resp.StatusCode); err != nil {
-		return nil, err
+	if err := CheckHttpResponseStatusCode(method, url, resp.StatusCode); err != nil {
+		return nil, httpClient.StatusError(err, resp.StatusCode, resp.Header, idempotent)
 	}
 
 	if respBody == nil {
 		return resp.Header, nil
 	}
 
-	return resp.Header, json.NewDecoder(resp.Body).Decode(respBody)
+	if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+		return resp.Header, fmt.Errorf("%w; invalid response json, %s %s, %v", weixin.ErrRequest, method, httpClient.RedactURL(url), err)
+	}
+	if r, ok := respBody.(httpClient.Retryer); ok && r.Retryable() {
+		return resp.Header, httpClient.Retryable(nil, 0)
+	}
+	return resp.Header, nil
+}
+
+// GetJSON http get json
+func GetJSON(ctx context.Context, c *httpClient.Client, url string, respBody any) (http.Header, error) {
+	var header http.Header
+	err := c.Retry(ctx, func() error {
+		httpClient.ResetBody(respBody)
+		resp, err := c.Get(ctx, url)
+		header, err = decodeJSON(http.MethodGet, url, true, resp, err, respBody)
+		return err
+	})
+	return header, err
 }
 
 // PostJSON http post json
-func PostJSON(url string, reqBody, respBody any) (http.Header, error) {
+func PostJSON(ctx context.Context, c *httpClient.Client, url string, reqBody, respBody any) (http.Header, error) {
+	return postJSON(ctx, c.Post, false, c, url, reqBody, respBody)
+}
+
+// PostJSONNoDryRun http post json，dry run 模式下同样发送，用于获取 access_token 等幂等的请求
+func PostJSONNoDryRun(ctx context.Context, c *httpClient.Client, url string, reqBody, respBody any) (http.Header, error) {
+	return postJSON(ctx, c.PostNoDryRun, true, c, url, reqBody, respBody)
+}
+
+func postJSON(ctx context.Context, post func(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error), idempotent bool, c *httpClient.Client, url string, reqBody, respBody any) (http.Header, error) {
 	buf := new(bytes.Buffer)
 	enc := json.NewEncoder(buf)
 	enc.SetEscapeHTML(false)
@@ -59,37 +94,24 @@ func PostJSON(url string, reqBody, respBody any) (http.Header, error) {
 		return nil, err
 	}
 
-	resp, err := httpClient.Post(url, httpClient.HdrValContentTypeJson, bytes.NewReader(buf.Bytes()))
-	if err != nil {
-		return nil, fmt.Errorf("%w; %s %s, %v", httpClient.ErrRequest, http.MethodPost, url, err)
-	}
-	defer resp.Body.Close()
-
-	if err := CheckHttpResponseStatusCode(http.MethodPost, url, resp.StatusCode); err != nil {
-		return nil, err
-	}
-
-	if respBody == nil {
-		return resp.Header, nil
-	}
-
-	return resp.Header, json.NewDecoder(resp.Body).Decode(respBody)
+	var header http.Header
+	err = c.Retry(ctx, func() error {
+		httpClient.ResetBody(respBody)
+		resp, err := post(ctx, url, httpClient.HdrValContentTypeJson, bytes.NewReader(buf.Bytes()))
+		header, err = decodeJSON(http.MethodPost, url, idempotent, resp, err, respBody)
+		return err
+	})
+	return header, err
 }
 
-func PostFileJSON(url, fieldName, fileName string, respBody any) (http.Header, error) {
-	resp, err := httpClient.PostFile(url, fieldName, fileName)
-	if err != nil {
-		return nil, fmt.Errorf("%w; %s %s, %v", httpClient.ErrRequest, http.MethodPost, url, err)
-	}
-	defer resp.Body.Close()
-
-	if err := CheckHttpResponseStatusCode(http.MethodPost, url, resp.StatusCode); err != nil {
-		return nil, err
-	}
-
-	if respBody == nil {
-		return resp.Header, nil
-	}
-
-	return resp.Header, json.NewDecoder(resp.Body).Decode(respBody)
+// PostFileJSON 上传文件
+func PostFileJSON(ctx context.Context, c *httpClient.Client, url, fieldName, fileName string, respBody any) (http.Header, error) {
+	var header http.Header
+	err := c.Retry(ctx, func() error {
+		httpClient.ResetBody(respBody)
+		resp, err := c.PostFile(ctx, url, fieldName, fileName)
+		header, err = decodeJSON(http.MethodPost, url, false, resp, err, respBody)
+		return err
+	})
+	return header, err
 }
@@ -0,0 +1,67 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+)
+
+func TestRetryStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		get    bool
+		hits   int32
+	}{
+		{name: "send 502", status: http.StatusBadGateway, hits: 1},
+		{name: "send 500", status: http.StatusInternalServerError, hits: 1},
+		{name: "send 504", status: http.StatusGatewayTimeout, hits: 1},
+		{name: "send 503", status: http.StatusServiceUnavailable, hits: 3},
+		{name: "send 429", status: http.StatusTooManyRequests, hits: 3},
+		{name: "get 502", status: http.StatusBadGateway, get: true, hits: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := httpClient.New(httpClient.WithRetry(httpClient.RetryPolicy{MaxAttempts: 3, BaseWait: time.Millisecond, MaxWait: time.Millisecond}))
+			var resp map[string]any
+			var err error
+			if tt.get {
+				_, err = GetJSON(context.Background(), c, srv.URL, &resp)
+			} else {
+				_, err = PostJSON(context.Background(), c, srv.URL, map[string]string{"touser": "oA"}, &resp)
+			}
+			if err == nil {
+				t.Fatal("want error")
+			}
+			if got := hits.Load(); got != tt.hits {
+				t.Errorf("server hits %d, want %d", got, tt.hits)
+			}
+		})
+	}
+}
@@ -15,9 +15,11 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 )
@@ -41,18 +43,18 @@ type CustomerMessage struct {
 	Link            *LinkMeta            `json:"link,omitempty"`
 }
 
-const reqURL = weixin.Host + "/cgi-bin/message/custom/send?access_token="
+const reqPath = "/cgi-bin/message/custom/send?access_token="
 
 // SendCustomer 发送微信客服消息
-func SendCustomer(accessToken string, msg *CustomerMessage) error {
-	u := reqURL + url.QueryEscape(accessToken)
+func SendCustomer(ctx context.Context, c *httpClient.Client, accessToken string, msg *CustomerMessage) error {
+	u := weixin.URL(c, reqPath) + url.QueryEscape(accessToken)
 	var resp weixin.ResponseMeta
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
@@ -15,22 +15,26 @@
 package message
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/token"
 )
 
 type CmdMiniSendCustomerParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	AppID       string
 	AppSecret   string
+	TokenMode   string
 	ToUser      string
 	MsgType     string
 	Data        string
@@ -48,80 +52,25 @@ func (t *CmdMiniSendCustomerParams) Validate() error {
 }
 
 // CmdMiniSendCustomer 发送微信小程序客服消息
-func CmdMiniSendCustomer(arg *CmdMiniSendCustomerParams) error {
+func CmdMiniSendCustomer(ctx context.Context, arg *CmdMiniSendCustomerParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	msg := CustomerMessage{
-		ToUser:  arg.ToUser,
-		MsgType: arg.MsgType,
+	n := MiniCustomerNotifier{
+		Credential: token.Credential{
+			Client:      arg.Client,
+			Cache:       arg.TokenCache,
+			Mode:        arg.TokenMode,
+			AccessToken: arg.AccessToken,
+			AppID:       arg.AppID,
+			AppSecret:   arg.AppSecret,
+		},
+		ToUser: arg.ToUser,
 	}
-
-	buf := bytes.NewBufferString("")
-	buf.WriteString(arg.Data)
-	switch arg.MsgType {
-	case MiniProgramMsgTypeText:
-		var msgMeta TextMeta
-		msgMeta.Content = buf.String()
-		msg.Text = &msgMeta
-	case MiniProgramMsgTypeImage:
-		var msgMeta ImageMeta
-		msgMeta.MediaID = buf.String()
-		msg.Image = &msgMeta
-	case MiniProgramMsgTypeLink:
-		var msgMeta LinkMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		if msgMeta.Title == "" {
-			return errors.New("title is empty")
-		}
-		if msgMeta.Description == "" {
-			return errors.New("description is empty")
-		}
-		if msgMeta.Url == "" {
-			return errors.New("url is empty")
-		}
-		if msgMeta.ThumbUrl == "" {
-			return errors.New("thumb_url is empty")
-		}
-		msg.Link = &msgMeta
-	case MiniProgramMsgTypeMiniProgramPage:
-		var msgMeta MiniProgramPageMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		if msgMeta.Title == "" {
-			return errors.New("title is empty")
-		}
-		if msgMeta.AppID != "" {
-			return errors.New("no appid required")
-		}
-		if msgMeta.PagePath == "" {
-			return errors.New("pagepath is empty")
-		}
-		if msgMeta.ThumbMediaID == "" {
-			return errors.New("thumb_media_id is empty")
-		}
-		msg.MiniProgramPage = &msgMeta
-	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.AppID, arg.AppSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
-	}
-
-	if err := SendCustomer(arg.AccessToken, &msg); err != nil {
+	if _, err := n.Send(ctx, notify.Message{Type: arg.MsgType, Content: arg.Data}); err != nil {
 		return err
 	}
-	fmt.Println(weixin.MessageOK)
-
-	return nil
+	return arg.Printer.Print(weixin.MessageOK, nil)
 }
@@ -0,0 +1,289 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/weixin/token"
+)
+
+// SetMpContent 按公众号客服消息类型设置消息内容：text 为文本，image、voice、mpnews 为 media_id，
+// mpnewsarticle 为 article_id，wxcard 为 card_id，其他为该类型的 json
+func (t *CustomerMessage) SetMpContent(content string) error {
+	if err := ValidateMpMsgType(t.MsgType); err != nil {
+		return err
+	}
+
+	switch t.MsgType {
+	case MpMsgTypeText:
+		var msgMeta TextMeta
+		msgMeta.Content = content
+		t.Text = &msgMeta
+	case MpMsgTypeImage:
+		var msgMeta ImageMeta
+		msgMeta.MediaID = content
+		t.Image = &msgMeta
+	case MpMsgTypeVoice:
+		var msgMeta VoiceMeta
+		msgMeta.MediaID = content
+		t.Voice = &msgMeta
+	case MpMsgTypeVideo:
+		var msgMeta VideoMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.MediaID == "" {
+			return errors.New("media_id is empty")
+		}
+		if msgMeta.ThumbMediaID == "" {
+			return errors.New("thumb_media_id is empty")
+		}
+		if msgMeta.Title == "" {
+			return errors.New("title is empty")
+		}
+		if msgMeta.Description == "" {
+			return errors.New("description is empty")
+		}
+		t.Video = &msgMeta
+	case MpMsgTypeMusic:
+		var msgMeta MusicMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.MusicUrl == "" {
+			return errors.New("musicurl is empty")
+		}
+		if msgMeta.HqmusicUrl == "" {
+			return errors.New("hqmusicurl is empty")
+		}
+		if msgMeta.ThumbMediaID == "" {
+			return errors.New("thumb_media_id is empty")
+		}
+		if msgMeta.Title == "" {
+			return errors.New("title is empty")
+		}
+		if msgMeta.Description == "" {
+			return errors.New("description is empty")
+		}
+		t.Music = &msgMeta
+	case MpMsgTypeNews:
+		var msgMeta NewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if len(msgMeta.Articles) != 1 {
+			return errors.New("articles length != 1")
+		}
+		article := msgMeta.Articles[0]
+		if article.Url == "" {
+			return errors.New("url is empty")
+		}
+		if article.PicUrl == "" {
+			return errors.New("picurl is empty")
+		}
+		if article.Title == "" {
+			return errors.New("title is empty")
+		}
+		if article.Description == "" {
+			return errors.New("description is empty")
+		}
+		t.News = &msgMeta
+	case MpMsgTypeMpNews:
+		var msgMeta MpNewsMeta
+		msgMeta.MediaID = content
+		t.MpNews = &msgMeta
+	case MpMsgTypeMpNewsArticle:
+		var msgMeta MpNewsArticleMeta
+		msgMeta.ArticleID = content
+		t.MpNewsArticle = &msgMeta
+	case MpMsgTypeMsgMenu:
+		var msgMeta MsgMenuMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.HeadContent == "" {
+			return errors.New("head_content is empty")
+		}
+		if msgMeta.TailContent == "" {
+			return errors.New("tail_content is empty")
+		}
+		lenList := len(msgMeta.List)
+		if lenList == 0 {
+			return errors.New("list is empty")
+		}
+		for i := 0; i < lenList; i++ {
+			if msgMeta.List[i].ID == "" {
+				return fmt.Errorf("list[%v].id is empty", i)
+			}
+			if msgMeta.List[i].Content == "" {
+				return fmt.Errorf("list[%v].content is empty", i)
+			}
+		}
+		t.MsgMenu = &msgMeta
+	case MpMsgTypeWxCard:
+		var msgMeta WxCardMeta
+		msgMeta.CardID = content
+		t.WxCard = &msgMeta
+	case MiniProgramMsgTypeMiniProgramPage:
+		var msgMeta MiniProgramPageMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.Title == "" {
+			return errors.New("title is empty")
+		}
+		if msgMeta.AppID == "" {
+			return errors.New("appid is empty")
+		}
+		if msgMeta.PagePath == "" {
+			return errors.New("pagepath is empty")
+		}
+		if msgMeta.ThumbMediaID == "" {
+			return errors.New("thumb_media_id is empty")
+		}
+		t.MiniProgramPage = &msgMeta
+	}
+	return nil
+}
+
+// SetMiniProgramContent 按小程序客服消息类型设置消息内容：text 为文本，image 为 media_id，其他为该类型的 json
+func (t *CustomerMessage) SetMiniProgramContent(content string) error {
+	if err := ValidateMiniProgramMsgType(t.MsgType); err != nil {
+		return err
+	}
+
+	switch t.MsgType {
+	case MiniProgramMsgTypeText:
+		var msgMeta TextMeta
+		msgMeta.Content = content
+		t.Text = &msgMeta
+	case MiniProgramMsgTypeImage:
+		var msgMeta ImageMeta
+		msgMeta.MediaID = content
+		t.Image = &msgMeta
+	case MiniProgramMsgTypeLink:
+		var msgMeta LinkMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.Title == "" {
+			return errors.New("title is empty")
+		}
+		if msgMeta.Description == "" {
+			return errors.New("description is empty")
+		}
+		if msgMeta.Url == "" {
+			return errors.New("url is empty")
+		}
+		if msgMeta.ThumbUrl == "" {
+			return errors.New("thumb_url is empty")
+		}
+		t.Link = &msgMeta
+	case MiniProgramMsgTypeMiniProgramPage:
+		var msgMeta MiniProgramPageMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.Title == "" {
+			return errors.New("title is empty")
+		}
+		if msgMeta.AppID != "" {
+			return errors.New("no appid required")
+		}
+		if msgMeta.PagePath == "" {
+			return errors.New("pagepath is empty")
+		}
+		if msgMeta.ThumbMediaID == "" {
+			return errors.New("thumb_media_id is empty")
+		}
+		t.MiniProgramPage = &msgMeta
+	}
+	return nil
+}
+
+// MpCustomerNotifier 微信公众号客服消息
+type MpCustomerNotifier struct {
+	Credential token.Credential
+	ToUser     string // 接收者 openid
+	KfAccount  string // 客服帐号，为空时不指定
+}
+
+// Send 发送微信公众号客服消息，通用消息转换为 text，不支持 @ 提醒，Result.Response 为 nil
+func (t *MpCustomerNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWeiXin}
+
+	m := CustomerMessage{
+		ToUser:  t.ToUser,
+		MsgType: msg.Type,
+	}
+	if t.KfAccount != "" {
+		m.CustomService = &ServiceMeta{KfAccount: t.KfAccount}
+	}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	if g != nil {
+		m.MsgType = MpMsgTypeText
+		m.Text = &TextMeta{Content: g.Text()}
+	} else if err := m.SetMpContent(msg.Content); err != nil {
+		return result, err
+	}
+
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) error {
+		return SendCustomer(ctx, t.Credential.Client, accessToken, &m)
+	}); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// MiniCustomerNotifier 微信小程序客服消息
+type MiniCustomerNotifier struct {
+	Credential token.Credential
+	ToUser     string // 接收者 openid
+}
+
+// Send 发送微信小程序客服消息，通用消息转换为 text，不支持 @ 提醒，Result.Response 为 nil
+func (t *MiniCustomerNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWeiXin}
+
+	m := CustomerMessage{
+		ToUser:  t.ToUser,
+		MsgType: msg.Type,
+	}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	if g != nil {
+		m.MsgType = MiniProgramMsgTypeText
+		m.Text = &TextMeta{Content: g.Text()}
+	} else if err := m.SetMiniProgramContent(msg.Content); err != nil {
+		return result, err
+	}
+
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) error {
+		return SendCustomer(ctx, t.Credential.Client, accessToken, &m)
+	}); err != nil {
+		return result, err
+	}
+	return result, nil
+}
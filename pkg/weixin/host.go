@@ -14,4 +14,22 @@
 
 package weixin
 
+import (
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+)
+
+// Host 微信接口默认地址
 const Host = "https://api.weixin.qq.com"
+
+// HostEnv 替换微信接口地址的环境变量
+const HostEnv = "PMSG_WEIXIN_API_BASE"
+
+// WithHost 替换微信接口地址
+func WithHost(base string) httpClient.Option {
+	return httpClient.WithBaseURL(Host, base)
+}
+
+// URL 拼接微信接口地址
+func URL(c *httpClient.Client, path string) string {
+	return c.BaseURL(Host) + path
+}
@@ -15,9 +15,12 @@
 package message
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 )
@@ -68,6 +71,23 @@ type SubscribeDataItem struct {
 	Value string `json:"value"`
 }
 
+// ParseSubscribeData 解析模板数据 json，如 {"thing1":{"value":"test"}}，内容为空时返回 nil
+func ParseSubscribeData(content string) (map[string]SubscribeDataItem, error) {
+	if content == "" {
+		return nil, nil
+	}
+	var data map[string]SubscribeDataItem
+	if err := json.Unmarshal([]byte(content), &data); err != nil {
+		return nil, fmt.Errorf("invalid json format, %v", err)
+	}
+	for k, v := range data {
+		if v.Value == "" {
+			return nil, fmt.Errorf("data %v.value not set", k)
+		}
+	}
+	return data, nil
+}
+
 const (
 	MiniProgramStateDeveloper = "developer" // developer为开发版
 	MiniProgramStateTrial     = "trial"     // trial为体验版
@@ -101,18 +121,18 @@ func ValidateLanguage(v string) error {
 	return nil
 }
 
-const reqURL = weixin.Host + "/cgi-bin/message/subscribe/send?access_token="
+const reqPath = "/cgi-bin/message/subscribe/send?access_token="
 
 // SendSubscribe 发送微信小程序订阅消息
-func SendSubscribe(accessToken string, msg *SubscribeMessage) error {
-	u := reqURL + url.QueryEscape(accessToken)
+func SendSubscribe(ctx context.Context, c *httpClient.Client, accessToken string, msg *SubscribeMessage) error {
+	u := weixin.URL(c, reqPath) + url.QueryEscape(accessToken)
 	var resp weixin.ResponseMeta
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
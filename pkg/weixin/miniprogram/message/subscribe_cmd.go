@@ -15,21 +15,26 @@
 package message
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/token"
 )
 
 type CmdMiniSendSubscribeParams struct {
-	UserAgent        string
+	Client           *client.Client
+	Printer          *output.Printer
+	TokenCache       tokencache.Cache
 	AccessToken      string
 	AppID            string
 	AppSecret        string
+	TokenMode        string
 	ToUser           string
 	TemplateID       string
 	MiniProgramState string
@@ -42,6 +47,9 @@ func (t *CmdMiniSendSubscribeParams) Validate() error {
 	if t.AccessToken == "" && t.AppID == "" {
 		return flags.ErrWeixinAccessToken
 	}
+	if t.ToUser == "" {
+		return fmt.Errorf("required flag(s) %q not set", flags.ToUser)
+	}
 	if t.Language != "" {
 		if err := ValidateLanguage(t.Language); err != nil {
 			return fmt.Errorf("invalid flags %s: %v", flags.Language, err)
@@ -58,57 +66,29 @@ func (t *CmdMiniSendSubscribeParams) Validate() error {
 }
 
 // CmdMiniProgramSendSubscribe 发送微信小程序订阅消息
-func CmdMiniProgramSendSubscribe(arg *CmdMiniSendSubscribeParams) error {
+func CmdMiniProgramSendSubscribe(ctx context.Context, arg *CmdMiniSendSubscribeParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	var dataItem map[string]SubscribeDataItem
-	buf := bytes.NewBufferString("")
-	buf.WriteString(arg.Data)
-	if buf.String() != "" {
-		if err := json.Unmarshal(buf.Bytes(), &dataItem); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		for k, v := range dataItem {
-			if v.Value == "" {
-				return fmt.Errorf("data %v.value not set", k)
-			}
-		}
-	}
-
-	msg := SubscribeMessage{
+	n := SubscribeNotifier{
+		Credential: token.Credential{
+			Client:      arg.Client,
+			Cache:       arg.TokenCache,
+			Mode:        arg.TokenMode,
+			AccessToken: arg.AccessToken,
+			AppID:       arg.AppID,
+			AppSecret:   arg.AppSecret,
+		},
 		ToUser:           arg.ToUser,
 		TemplateID:       arg.TemplateID,
-		Data:             dataItem,
 		Page:             arg.Page,
-		MiniProgramState: MiniProgramStateFormal,
-		Language:         LanguageZhCN,
-	}
-
-	if arg.Language != "" {
-		msg.Language = arg.Language
-	}
-
-	if arg.MiniProgramState != "" {
-		msg.MiniProgramState = arg.MiniProgramState
-	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.AppID, arg.AppSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
+		MiniProgramState: arg.MiniProgramState,
+		Language:         arg.Language,
 	}
-
-	if err := SendSubscribe(arg.AccessToken, &msg); err != nil {
+	if _, err := n.Send(ctx, notify.Message{Type: MsgTypeSubscribe, Content: arg.Data}); err != nil {
 		return err
 	}
-	fmt.Println(weixin.MessageOK)
-
-	return nil
+	return arg.Printer.Print(weixin.MessageOK, nil)
 }
@@ -0,0 +1,74 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/weixin/token"
+)
+
+// MsgTypeSubscribe 订阅消息
+const MsgTypeSubscribe = "subscribe"
+
+// SubscribeNotifier 微信小程序订阅消息
+type SubscribeNotifier struct {
+	Credential       token.Credential
+	ToUser           string // 接收者 openid
+	TemplateID       string
+	Page             string
+	MiniProgramState string // 为空时为 formal
+	Language         string // 为空时为 zh_CN
+}
+
+// Send 发送微信小程序订阅消息，消息内容为模板数据 json，不支持通用消息，Result.Response 为 nil
+func (t *SubscribeNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWeiXin}
+	if msg.Generic != nil {
+		return result, errors.New("generic message not supported, content must be template data")
+	}
+	if msg.Type != "" && msg.Type != MsgTypeSubscribe {
+		return result, fmt.Errorf("%s not in [%q]", msg.Type, MsgTypeSubscribe)
+	}
+
+	data, err := ParseSubscribeData(msg.Content)
+	if err != nil {
+		return result, err
+	}
+	m := SubscribeMessage{
+		ToUser:           t.ToUser,
+		TemplateID:       t.TemplateID,
+		Data:             data,
+		Page:             t.Page,
+		MiniProgramState: MiniProgramStateFormal,
+		Language:         LanguageZhCN,
+	}
+	if t.MiniProgramState != "" {
+		m.MiniProgramState = t.MiniProgramState
+	}
+	if t.Language != "" {
+		m.Language = t.Language
+	}
+
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) error {
+		return SendSubscribe(ctx, t.Credential.Client, accessToken, &m)
+	}); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -15,9 +15,11 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 )
@@ -67,18 +69,18 @@ type SubscribeDataItem struct {
 	Value string `json:"value"`
 }
 
-const subscribeURL = weixin.Host + "/cgi-bin/message/subscribe/bizsend?access_token="
+const subscribePath = "/cgi-bin/message/subscribe/bizsend?access_token="
 
 // BizSendSubscribe 发送微信公众号订阅通知消息
-func BizSendSubscribe(accessToken string, msg *SubscribeMessage) error {
-	u := subscribeURL + url.QueryEscape(accessToken)
+func BizSendSubscribe(ctx context.Context, c *httpClient.Client, accessToken string, msg *SubscribeMessage) error {
+	u := weixin.URL(c, subscribePath) + url.QueryEscape(accessToken)
 	var resp weixin.ResponseMeta
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
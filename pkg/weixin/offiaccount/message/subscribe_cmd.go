@@ -16,20 +16,26 @@ package message
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/token"
 )
 
 type CmdMpBizSendSubscribeParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	AppID       string
 	AppSecret   string
+	TokenMode   string
 	ToUser      string
 	TemplateID  string
 	Page        string
@@ -65,7 +71,7 @@ func (t *CmdMpBizSendSubscribeParams) Validate() error {
 }
 
 // CmdMpBizSendSubscribe 发送微信公众号订阅通知消息
-func CmdMpBizSendSubscribe(arg *CmdMpBizSendSubscribeParams) error {
+func CmdMpBizSendSubscribe(ctx context.Context, arg *CmdMpBizSendSubscribeParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
@@ -102,20 +108,18 @@ func CmdMpBizSendSubscribe(arg *CmdMpBizSendSubscribeParams) error {
 		}
 	}
 
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.AppID, arg.AppSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
+	cred := token.Credential{
+		Client:      arg.Client,
+		Cache:       arg.TokenCache,
+		Mode:        arg.TokenMode,
+		AccessToken: arg.AccessToken,
+		AppID:       arg.AppID,
+		AppSecret:   arg.AppSecret,
 	}
-
-	if err := BizSendSubscribe(arg.AccessToken, &msg); err != nil {
+	if err := token.Do(ctx, &cred, func(accessToken string) error {
+		return BizSendSubscribe(ctx, arg.Client, accessToken, &msg)
+	}); err != nil {
 		return err
 	}
-	fmt.Println(weixin.MessageOK)
-
-	return nil
+	return arg.Printer.Print(weixin.MessageOK, nil)
 }
@@ -15,9 +15,11 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 )
@@ -105,18 +107,18 @@ func (t TemplateMessageResponse) String() string {
 	return fmt.Sprintf("errcode: %v, errmsg: %q, msgid: %v", t.ErrorCode, t.ErrorMessage, t.MsgID)
 }
 
-const templateURL = weixin.Host + "/cgi-bin/message/template/send?access_token="
+const templatePath = "/cgi-bin/message/template/send?access_token="
 
 // SendTemplate 发送微信公众号模板消息
-func SendTemplate(accessToken string, msg *TemplateMessage) (int64, error) {
-	u := templateURL + url.QueryEscape(accessToken)
+func SendTemplate(ctx context.Context, c *httpClient.Client, accessToken string, msg *TemplateMessage) (int64, error) {
+	u := weixin.URL(c, templatePath) + url.QueryEscape(accessToken)
 	var resp TemplateMessageResponse
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return 0, err
 	}
 	if !resp.Succeed() {
-		return 0, fmt.Errorf("%w; %v", weixin.ErrRequest, resp.ResponseMeta)
+		return 0, fmt.Errorf("%w; %v", resp.Err(), resp.ResponseMeta)
 	}
 	return resp.MsgID, nil
 }
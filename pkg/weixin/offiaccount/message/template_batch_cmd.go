@@ -0,0 +1,151 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/batch"
+	"github.com/lenye/pmsg/pkg/flags"
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
+	"github.com/lenye/pmsg/pkg/weixin/token"
+)
+
+type CmdMpSendTemplateBatchParams struct {
+	Client       *client.Client
+	Printer      *output.Printer
+	TokenCache   tokencache.Cache
+	AccessToken  string
+	AppID        string
+	AppSecret    string
+	TokenMode    string
+	TemplateID   string
+	Url          string
+	Mini         map[string]string
+	Color        string
+	Data         string // 公共的模板数据 json，可选，每行的列覆盖同名字段的值
+	Batch        string // 批量发送的文件，每行一个接收者
+	ResultFile   string // 结果文件，为空时为 Batch 加 .result.jsonl
+	Concurrency  int
+	RetryFailed  bool
+	RetryUnknown bool // 重新发送结果未知的行，这些行可能已发送
+	Progress     batch.ProgressFunc
+}
+
+func (t *CmdMpSendTemplateBatchParams) Validate() error {
+	if t.AccessToken == "" && t.AppID == "" {
+		return flags.ErrWeixinAccessToken
+	}
+	if t.Concurrency < 1 {
+		return fmt.Errorf("invalid flags %s: must be greater than 0", flags.Concurrency)
+	}
+	return validateMini(t.Mini)
+}
+
+// CmdMpSendTemplateBatch 批量发送微信公众号模板消息，每行一个接收者和模板数据，共享 access_token；
+// 每行的 client_msg_id 由模板id、行号和接收者生成
+func CmdMpSendTemplateBatch(ctx context.Context, arg *CmdMpSendTemplateBatchParams) error {
+
+	if err := arg.Validate(); err != nil {
+		return err
+	}
+
+	base, err := ParseTemplateData(arg.Data)
+	if err != nil {
+		return err
+	}
+	rows, err := batch.ReadRows(arg.Batch)
+	if err != nil {
+		return err
+	}
+
+	cred := token.Credential{
+		Client:      arg.Client,
+		Cache:       tokencache.NewMemory(arg.TokenCache, tokencache.RefreshMargin),
+		Mode:        arg.TokenMode,
+		AccessToken: arg.AccessToken,
+		AppID:       arg.AppID,
+		AppSecret:   arg.AppSecret,
+	}
+	var miniProgram *MiniProgramMeta
+	if arg.Mini != nil {
+		miniProgram = &MiniProgramMeta{
+			AppID:    arg.Mini[flags.MiniAppID],
+			PagePath: arg.Mini[flags.MiniPagePath],
+		}
+	}
+
+	send := func(ctx context.Context, row *batch.Row) (string, error) {
+		data := make(map[string]TemplateDataItem, len(base)+len(row.Data))
+		for k, v := range base {
+			data[k] = v
+		}
+		for k, v := range row.Data {
+			if v == "" {
+				return "", fmt.Errorf("data %v.value not set", k)
+			}
+			item := data[k]
+			item.Value = v
+			data[k] = item
+		}
+		content, err := json.Marshal(data)
+		if err != nil {
+			return "", err
+		}
+		// 中断后再次执行或 --retry_failed 重新发送时，client_msg_id 不变，避免重复发送
+		notifier := TemplateNotifier{
+			Credential:  cred,
+			ToUser:      row.ToUser,
+			TemplateID:  arg.TemplateID,
+			URL:         arg.Url,
+			MiniProgram: miniProgram,
+			Color:       arg.Color,
+			ClientMsgID: row.ClientMsgID(arg.TemplateID),
+		}
+		result, err := notifier.Send(ctx, notify.Message{Type: MsgTypeTemplate, Content: string(content)})
+		if errors.Is(err, client.ErrDryRun) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return result.MessageID, nil
+	}
+
+	resultFile := arg.ResultFile
+	if resultFile == "" {
+		resultFile = batch.ResultFile(arg.Batch)
+	}
+	summary, err := batch.Run(ctx, &batch.Params{
+		Rows:         rows,
+		Send:         send,
+		Concurrency:  arg.Concurrency,
+		ResultFile:   resultFile,
+		RetryFailed:  arg.RetryFailed,
+		RetryUnknown: arg.RetryUnknown,
+		DryRun:       arg.Client.DryRun(),
+		Progress:     arg.Progress,
+	})
+	if perr := arg.Printer.Print(summary.String(), summary); perr != nil && err == nil {
+		err = perr
+	}
+	return err
+}
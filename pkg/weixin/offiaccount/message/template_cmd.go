@@ -15,21 +15,26 @@
 package message
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/token"
 )
 
 type CmdMpSendTemplateParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	AppID       string
 	AppSecret   string
+	TokenMode   string
 	ToUser      string
 	TemplateID  string
 	Url         string
@@ -43,84 +48,69 @@ func (t *CmdMpSendTemplateParams) Validate() error {
 	if t.AccessToken == "" && t.AppID == "" {
 		return flags.ErrWeixinAccessToken
 	}
+	if t.ToUser == "" {
+		return fmt.Errorf("required flag(s) %q not set", flags.ToUser)
+	}
+	return validateMini(t.Mini)
+}
 
-	// 跳小程序
-	if t.Mini != nil {
-		if miniAppID, ok := t.Mini[flags.MiniAppID]; !ok {
+// validateMini 跳小程序参数
+func validateMini(mini map[string]string) error {
+	if mini == nil {
+		return nil
+	}
+	if miniAppID, ok := mini[flags.MiniAppID]; !ok {
+		return fmt.Errorf("mini flag %q not set", flags.MiniAppID)
+	} else {
+		if miniAppID == "" {
 			return fmt.Errorf("mini flag %q not set", flags.MiniAppID)
-		} else {
-			if miniAppID == "" {
-				return fmt.Errorf("mini flag %q not set", flags.MiniAppID)
-			}
 		}
+	}
 
-		if miniPagePath, ok := t.Mini[flags.MiniPagePath]; !ok {
+	if miniPagePath, ok := mini[flags.MiniPagePath]; !ok {
+		return fmt.Errorf("mini flag %q not set", flags.MiniPagePath)
+	} else {
+		if miniPagePath == "" {
 			return fmt.Errorf("mini flag %q not set", flags.MiniPagePath)
-		} else {
-			if miniPagePath == "" {
-				return fmt.Errorf("mini flag %q not set", flags.MiniPagePath)
-			}
 		}
 	}
-
 	return nil
 }
 
 // CmdMpSendTemplate 发送微信公众号模板消息
-func CmdMpSendTemplate(arg *CmdMpSendTemplateParams) error {
+func CmdMpSendTemplate(ctx context.Context, arg *CmdMpSendTemplateParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	var dataItem map[string]TemplateDataItem
-	buf := bytes.NewBufferString("")
-	buf.WriteString(arg.Data)
-	if buf.String() != "" {
-		if err := json.Unmarshal(buf.Bytes(), &dataItem); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		for k, v := range dataItem {
-			if v.Value == "" {
-				return fmt.Errorf("data %v.value not set", k)
-			}
-		}
-	}
-
-	msg := TemplateMessage{
+	n := TemplateNotifier{
+		Credential: token.Credential{
+			Client:      arg.Client,
+			Cache:       arg.TokenCache,
+			Mode:        arg.TokenMode,
+			AccessToken: arg.AccessToken,
+			AppID:       arg.AppID,
+			AppSecret:   arg.AppSecret,
+		},
 		ToUser:      arg.ToUser,
 		TemplateID:  arg.TemplateID,
-		Data:        dataItem,
 		URL:         arg.Url,
-		ClientMsgID: arg.ClientMsgID,
 		Color:       arg.Color,
+		ClientMsgID: arg.ClientMsgID,
 	}
 
 	// 跳小程序
 	if arg.Mini != nil {
-		miniAppID, _ := arg.Mini[flags.MiniAppID]
-		miniPagePath, _ := arg.Mini[flags.MiniPagePath]
-		msg.MiniProgram = &MiniProgramMeta{
-			AppID:    miniAppID,
-			PagePath: miniPagePath,
-		}
-	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.AppID, arg.AppSecret)
-		if err != nil {
-			return err
+		n.MiniProgram = &MiniProgramMeta{
+			AppID:    arg.Mini[flags.MiniAppID],
+			PagePath: arg.Mini[flags.MiniPagePath],
 		}
-		arg.AccessToken = accessTokenResp.AccessToken
 	}
 
-	if gotMsgID, err := SendTemplate(arg.AccessToken, &msg); err != nil {
+	result, err := n.Send(ctx, notify.Message{Type: MsgTypeTemplate, Content: arg.Data})
+	if err != nil {
 		return err
-	} else {
-		fmt.Println(fmt.Sprintf("%v; msgid: %v", weixin.MessageOK, gotMsgID))
 	}
-
-	return nil
+	return arg.Printer.Print(fmt.Sprintf("%v; msgid: %v", weixin.MessageOK, result.MessageID), result.Response)
 }
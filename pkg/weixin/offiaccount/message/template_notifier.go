@@ -0,0 +1,93 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/weixin/token"
+)
+
+// MsgTypeTemplate 模板消息
+const MsgTypeTemplate = "template"
+
+// ParseTemplateData 解析模板数据 json，如 {"first":{"value":"test"}}，内容为空时返回 nil
+func ParseTemplateData(content string) (map[string]TemplateDataItem, error) {
+	if content == "" {
+		return nil, nil
+	}
+	var data map[string]TemplateDataItem
+	if err := json.Unmarshal([]byte(content), &data); err != nil {
+		return nil, fmt.Errorf("invalid json format, %v", err)
+	}
+	for k, v := range data {
+		if v.Value == "" {
+			return nil, fmt.Errorf("data %v.value not set", k)
+		}
+	}
+	return data, nil
+}
+
+// TemplateNotifier 微信公众号模板消息
+type TemplateNotifier struct {
+	Credential  token.Credential
+	ToUser      string // 接收者 openid
+	TemplateID  string
+	URL         string
+	MiniProgram *MiniProgramMeta // 跳小程序，没有时为 nil
+	Color       string
+	ClientMsgID string
+}
+
+// Send 发送微信公众号模板消息，消息内容为模板数据 json，不支持通用消息，Result.Response 为 TemplateMessageResponse
+func (t *TemplateNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWeiXin}
+	if msg.Generic != nil {
+		return result, errors.New("generic message not supported, content must be template data")
+	}
+	if msg.Type != "" && msg.Type != MsgTypeTemplate {
+		return result, fmt.Errorf("%s not in [%q]", msg.Type, MsgTypeTemplate)
+	}
+
+	data, err := ParseTemplateData(msg.Content)
+	if err != nil {
+		return result, err
+	}
+	m := TemplateMessage{
+		ToUser:      t.ToUser,
+		TemplateID:  t.TemplateID,
+		Data:        data,
+		URL:         t.URL,
+		MiniProgram: t.MiniProgram,
+		ClientMsgID: t.ClientMsgID,
+		Color:       t.Color,
+	}
+
+	var msgID int64
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) (err error) {
+		msgID, err = SendTemplate(ctx, t.Credential.Client, accessToken, &m)
+		return err
+	}); err != nil {
+		return result, err
+	}
+	result.MessageID = strconv.FormatInt(msgID, 10)
+	result.Response = TemplateMessageResponse{MsgID: msgID}
+	return result, nil
+}
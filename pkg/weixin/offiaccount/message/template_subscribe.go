@@ -15,9 +15,11 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 )
@@ -60,18 +62,18 @@ type TemplateSubscribeMessage struct {
 	Data        map[string]TemplateDataItem `json:"data"`                  // 必须, 模板数据, JSON 格式的 []byte, 满足特定的模板需求
 }
 
-const subscribeTplURL = weixin.Host + "/cgi-bin/message/template/subscribe?access_token="
+const subscribeTplPath = "/cgi-bin/message/template/subscribe?access_token="
 
 // SendTemplateSubscribe 发送微信公众号一次性订阅消息
-func SendTemplateSubscribe(accessToken string, msg *TemplateSubscribeMessage) error {
-	u := subscribeTplURL + url.QueryEscape(accessToken)
+func SendTemplateSubscribe(ctx context.Context, c *httpClient.Client, accessToken string, msg *TemplateSubscribeMessage) error {
+	u := weixin.URL(c, subscribeTplPath) + url.QueryEscape(accessToken)
 	var resp weixin.ResponseMeta
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
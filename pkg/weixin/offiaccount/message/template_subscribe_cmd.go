@@ -16,20 +16,26 @@ package message
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/token"
 )
 
 type CmdMpSendTemplateSubscribeParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	AppID       string
 	AppSecret   string
+	TokenMode   string
 	ToUser      string
 	TemplateID  string
 	Url         string
@@ -71,7 +77,7 @@ func (t *CmdMpSendTemplateSubscribeParams) Validate() error {
 }
 
 // CmdMpSendTemplateSubscribe 发送微信公众号一次性订阅消息
-func CmdMpSendTemplateSubscribe(arg *CmdMpSendTemplateSubscribeParams) error {
+func CmdMpSendTemplateSubscribe(ctx context.Context, arg *CmdMpSendTemplateSubscribeParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
@@ -113,20 +119,18 @@ func CmdMpSendTemplateSubscribe(arg *CmdMpSendTemplateSubscribeParams) error {
 		}
 	}
 
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.AppID, arg.AppSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
+	cred := token.Credential{
+		Client:      arg.Client,
+		Cache:       arg.TokenCache,
+		Mode:        arg.TokenMode,
+		AccessToken: arg.AccessToken,
+		AppID:       arg.AppID,
+		AppSecret:   arg.AppSecret,
 	}
-
-	if err := SendTemplateSubscribe(arg.AccessToken, &msg); err != nil {
+	if err := token.Do(ctx, &cred, func(accessToken string) error {
+		return SendTemplateSubscribe(ctx, arg.Client, accessToken, &msg)
+	}); err != nil {
 		return err
 	}
-	fmt.Println(weixin.MessageOK)
-
-	return nil
+	return arg.Printer.Print(weixin.MessageOK, nil)
 }
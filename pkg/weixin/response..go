@@ -24,8 +24,35 @@ const (
 	MessageOK = "ok"
 )
 
+const (
+	CodeSystemBusy       = -1    // 系统繁忙
+	CodeMinuteQuotaLimit = 45011 // 接口调用频率超过每分钟限制
+	CodeConcurrencyLimit = 45033 // 接口并发调用超过限制
+)
+
+const (
+	CodeInvalidCredential  = 40001 // 获取 access_token 时 AppSecret 错误，或者 access_token 无效
+	CodeInvalidAppID       = 40013 // 不合法的 AppID 或 CorpID
+	CodeInvalidAccessToken = 40014 // 不合法的 access_token
+	CodeInvalidSecret      = 40091 // secret 不合法
+	CodeInvalidAppSecret   = 40125 // 不合法的 AppSecret
+	CodeInvalidIP          = 40164 // 调用接口的IP地址不在白名单中
+	CodeMissingAccessToken = 41001 // 缺少 access_token 参数
+	CodeAccessTokenExpired = 42001 // access_token 超时
+	CodeWorkInvalidIP      = 60020 // 企业微信：不安全的访问IP
+)
+
 var ErrRequest = errors.New("weixin request error")
 
+// ErrUnauthorized 接口调用凭证无效、过期或获取失败
+var ErrUnauthorized = fmt.Errorf("%w: unauthorized", ErrRequest)
+
+// ErrBusy 系统繁忙或接口调用超过频率限制，稍后可重试
+var ErrBusy = fmt.Errorf("%w: busy or rate limited", ErrRequest)
+
+// ErrAccessTokenInvalid access_token 无效或过期，重新获取 access_token 后可重试
+var ErrAccessTokenInvalid = fmt.Errorf("%w: invalid or expired access token", ErrUnauthorized)
+
 // ResponseMeta 响应操作信息
 type ResponseMeta struct {
 	ErrorCode    int64  `json:"errcode"`          // 出错返回码，为0表示成功，非0表示调用失败
@@ -40,3 +67,45 @@ func (t ResponseMeta) String() string {
 func (t ResponseMeta) Succeed() bool {
 	return t.ErrorCode == CodeOK
 }
+
+// Unauthorized 是否为接口调用凭证错误
+func (t ResponseMeta) Unauthorized() bool {
+	switch t.ErrorCode {
+	case CodeInvalidCredential, CodeInvalidAppID, CodeInvalidAccessToken, CodeInvalidSecret,
+		CodeInvalidAppSecret, CodeInvalidIP, CodeMissingAccessToken, CodeAccessTokenExpired, CodeWorkInvalidIP:
+		return true
+	}
+	return false
+}
+
+// AccessTokenInvalid 是否为 access_token 无效或过期
+func (t ResponseMeta) AccessTokenInvalid() bool {
+	switch t.ErrorCode {
+	case CodeInvalidCredential, CodeInvalidAccessToken, CodeAccessTokenExpired:
+		return true
+	}
+	return false
+}
+
+// Err 返回码对应的错误
+func (t ResponseMeta) Err() error {
+	if t.AccessTokenInvalid() {
+		return ErrAccessTokenInvalid
+	}
+	if t.Unauthorized() {
+		return ErrUnauthorized
+	}
+	if t.Retryable() {
+		return ErrBusy
+	}
+	return ErrRequest
+}
+
+// Retryable 是否可重试
+func (t ResponseMeta) Retryable() bool {
+	switch t.ErrorCode {
+	case CodeSystemBusy, CodeMinuteQuotaLimit, CodeConcurrencyLimit:
+		return true
+	}
+	return false
+}
@@ -15,10 +15,12 @@
 package token
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"time"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 )
@@ -42,22 +44,25 @@ type AccessTokenResponse struct {
 	AccessTokenMeta
 }
 
-const reqURL = weixin.Host + "/cgi-bin/token?grant_type=client_credential&appid="
+const reqPath = "/cgi-bin/token?grant_type=client_credential&appid="
 
 // FetchAccessToken 获取微信接口调用凭证
 // 正常情况下，微信会返回下述 JSON
 // {"access_token":"ACCESS_TOKEN","expires_in":7200}
 // 错误时微信会返回错误码等信息，JSON数据包示例如下:
 // {"errcode":40013,"errmsg":"invalid appid"}
-func FetchAccessToken(appID, appSecret string) (*AccessTokenMeta, error) {
-	u := reqURL + url.QueryEscape(appID) + "&secret=" + url.QueryEscape(appSecret)
+func FetchAccessToken(ctx context.Context, c *httpClient.Client, appID, appSecret string) (*AccessTokenMeta, error) {
+	if !c.FetchToken() {
+		return &AccessTokenMeta{AccessToken: httpClient.DryRunAccessToken}, nil
+	}
+	u := weixin.URL(c, reqPath) + url.QueryEscape(appID) + "&secret=" + url.QueryEscape(appSecret)
 	var resp AccessTokenResponse
-	_, err := client.GetJSON(u, &resp)
+	_, err := client.GetJSON(ctx, c, u, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp.ResponseMeta)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp.ResponseMeta)
 	}
 
 	resp.AccessTokenMeta.ExpireAt = time.Now().Add(time.Second * time.Duration(resp.AccessTokenMeta.ExpireIn))
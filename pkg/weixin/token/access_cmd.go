@@ -15,27 +15,34 @@
 package token
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 )
 
 type CmdTokenParams struct {
-	UserAgent string
-	AppID     string
-	AppSecret string
+	Client       *client.Client
+	Printer      *output.Printer
+	TokenCache   tokencache.Cache
+	AppID        string
+	AppSecret    string
+	TokenMode    string
+	ForceRefresh bool
 }
 
 // CmdGetAccessToken 获取微信接口调用凭证
-func CmdGetAccessToken(arg *CmdTokenParams) error {
-	client.SetUserAgent(arg.UserAgent)
-
-	accessTokenResp, err := FetchAccessToken(arg.AppID, arg.AppSecret)
+func CmdGetAccessToken(ctx context.Context, arg *CmdTokenParams) error {
+	get := AccessToken
+	if arg.ForceRefresh {
+		get = RefreshAccessToken
+	}
+	accessTokenResp, err := get(ctx, arg.Client, arg.TokenCache, arg.TokenMode, arg.AppID, arg.AppSecret)
 	if err != nil {
 		return err
 	}
-	fmt.Println(fmt.Sprintf("%v; %v", weixin.MessageOK, accessTokenResp))
-
-	return nil
+	return arg.Printer.Print(fmt.Sprintf("%v; %v", weixin.MessageOK, accessTokenResp), accessTokenResp)
 }
@@ -0,0 +1,92 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"context"
+	"time"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/tokencache"
+)
+
+// Provider 缓存中的平台名称
+const Provider = "weixin"
+
+// fetchFunc 从平台获取 access_token
+func fetchFunc(c *httpClient.Client, mode, appID, appSecret string, forceRefresh bool) tokencache.FetchFunc {
+	return func(ctx context.Context) (*tokencache.Token, error) {
+		meta, err := Fetch(ctx, c, mode, appID, appSecret, forceRefresh)
+		if err != nil {
+			return nil, err
+		}
+		return &tokencache.Token{Provider: Provider, ID: appID, AccessToken: meta.AccessToken, ExpireAt: meta.ExpireAt}, nil
+	}
+}
+
+// cacheKey 缓存键，未指定 mode 时同 legacy
+func cacheKey(mode, appID, appSecret string) string {
+	if mode == "" {
+		mode = ModeLegacy
+	}
+	return tokencache.Key(Provider, appID, mode, appSecret)
+}
+
+// AccessToken 按 mode 获取接口调用凭证，cache 不为 nil 时优先使用未过期的缓存
+func AccessToken(ctx context.Context, c *httpClient.Client, cache tokencache.Cache, mode, appID, appSecret string) (*AccessTokenMeta, error) {
+	return accessToken(ctx, c, cache, mode, appID, appSecret, false)
+}
+
+// RefreshAccessToken 不使用缓存，重新获取接口调用凭证并更新缓存；稳定版强制刷新
+func RefreshAccessToken(ctx context.Context, c *httpClient.Client, cache tokencache.Cache, mode, appID, appSecret string) (*AccessTokenMeta, error) {
+	return accessToken(ctx, c, cache, mode, appID, appSecret, true)
+}
+
+// RenewAccessToken access_token stale 无效时重新获取并更新缓存，稳定版不强制刷新；
+// 缓存中已是其他 access_token（如并发的请求已重新获取）时直接使用，不重复获取
+func RenewAccessToken(ctx context.Context, c *httpClient.Client, cache tokencache.Cache, mode, appID, appSecret, stale string) (*AccessTokenMeta, error) {
+	if cache == nil || !c.FetchToken() {
+		return Fetch(ctx, c, mode, appID, appSecret, false)
+	}
+	meta, err := accessToken(ctx, c, cache, mode, appID, appSecret, false)
+	if err != nil || meta.AccessToken != stale {
+		return meta, err
+	}
+	if err := cache.Delete(ctx, cacheKey(mode, appID, appSecret)); err != nil {
+		return nil, err
+	}
+	return accessToken(ctx, c, cache, mode, appID, appSecret, false)
+}
+
+func accessToken(ctx context.Context, c *httpClient.Client, cache tokencache.Cache, mode, appID, appSecret string, refresh bool) (*AccessTokenMeta, error) {
+	if cache == nil || !c.FetchToken() {
+		return Fetch(ctx, c, mode, appID, appSecret, refresh)
+	}
+	key := cacheKey(mode, appID, appSecret)
+	if refresh {
+		if err := cache.Delete(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	tk, err := cache.Get(ctx, key, fetchFunc(c, mode, appID, appSecret, refresh))
+	if err != nil {
+		return nil, err
+	}
+	return &AccessTokenMeta{
+		AccessToken: tk.AccessToken,
+		ExpireIn:    int64(time.Until(tk.ExpireAt).Seconds()),
+		ExpireAt:    tk.ExpireAt,
+	}, nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"context"
+	"log"
+
+	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/tokencache"
+)
+
+type CmdServeParams struct {
+	Client    *client.Client
+	Logger    *log.Logger
+	Listen    string
+	Auth      string
+	AppID     string
+	AppSecret string
+	TokenMode string
+}
+
+// CmdServe 启动微信 access_token 服务，集中获取并在到期前刷新 access_token
+func CmdServe(ctx context.Context, arg *CmdServeParams) error {
+	srv := tokencache.NewServer(Provider, arg.Auth, tokencache.RefreshMargin, arg.Logger)
+	srv.Add(arg.AppID, fetchFunc(arg.Client, arg.TokenMode, arg.AppID, arg.AppSecret, false))
+	return srv.ListenAndServe(ctx, arg.Listen)
+}
@@ -0,0 +1,87 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/weixin"
+	"github.com/lenye/pmsg/pkg/weixin/client"
+)
+
+// 获取 access_token 的方式
+const (
+	ModeLegacy = "legacy" // 获取接口调用凭证，重新获取后之前的 access_token 在5分钟后失效
+	ModeStable = "stable" // 获取稳定版接口调用凭证，不影响其他持有者的 access_token
+)
+
+// ValidateMode 验证获取 access_token 的方式
+func ValidateMode(v string) error {
+	switch v {
+	case ModeLegacy, ModeStable:
+	default:
+		return fmt.Errorf("%s not in [%q %q]", v, ModeLegacy, ModeStable)
+	}
+	return nil
+}
+
+// StableAccessTokenRequest 获取稳定版接口调用凭证的请求
+type StableAccessTokenRequest struct {
+	GrantType    string `json:"grant_type"`
+	AppID        string `json:"appid"`
+	Secret       string `json:"secret"`
+	ForceRefresh bool   `json:"force_refresh,omitempty"`
+}
+
+const stableReqPath = "/cgi-bin/stable_token"
+
+// FetchStableAccessToken 获取稳定版接口调用凭证
+// 未过期时返回同一个 access_token，forceRefresh 为 true 时强制刷新，之前的 access_token 在5分钟后失效
+// 正常情况下，微信会返回下述 JSON
+// {"access_token":"ACCESS_TOKEN","expires_in":7200}
+func FetchStableAccessToken(ctx context.Context, c *httpClient.Client, appID, appSecret string, forceRefresh bool) (*AccessTokenMeta, error) {
+	if !c.FetchToken() {
+		return &AccessTokenMeta{AccessToken: httpClient.DryRunAccessToken}, nil
+	}
+	req := StableAccessTokenRequest{
+		GrantType:    "client_credential",
+		AppID:        appID,
+		Secret:       appSecret,
+		ForceRefresh: forceRefresh,
+	}
+	var resp AccessTokenResponse
+	_, err := client.PostJSONNoDryRun(ctx, c, weixin.URL(c, stableReqPath), &req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Succeed() {
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp.ResponseMeta)
+	}
+
+	resp.AccessTokenMeta.ExpireAt = time.Now().Add(time.Second * time.Duration(resp.AccessTokenMeta.ExpireIn))
+
+	return &resp.AccessTokenMeta, nil
+}
+
+// Fetch 按 mode 获取接口调用凭证，forceRefresh 只用于稳定版
+func Fetch(ctx context.Context, c *httpClient.Client, mode, appID, appSecret string, forceRefresh bool) (*AccessTokenMeta, error) {
+	if mode == ModeStable {
+		return FetchStableAccessToken(ctx, c, appID, appSecret, forceRefresh)
+	}
+	return FetchAccessToken(ctx, c, appID, appSecret)
+}
@@ -15,11 +15,14 @@
 package asset
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/lenye/pmsg/pkg/file"
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -45,6 +48,25 @@ func ValidateMediaType(v string) error {
 	return nil
 }
 
+// MediaLimits 临时素材大小及格式限制，所有文件都必须大于5个字节
+var MediaLimits = map[string]file.Limit{
+	TypeImage: {MinSize: 5, MaxSize: 10 * file.MB, Exts: []string{".jpg", ".jpeg", ".png"}},
+	TypeVoice: {MinSize: 5, MaxSize: 2 * file.MB, Exts: []string{".amr"}},
+	TypeVideo: {MinSize: 5, MaxSize: 10 * file.MB, Exts: []string{".mp4"}},
+	TypeFile:  {MinSize: 5, MaxSize: 20 * file.MB},
+}
+
+// ValidateMediaFile 检查临时素材文件大小及格式
+func ValidateMediaFile(mediaType, filename string) error {
+	if err := ValidateMediaType(mediaType); err != nil {
+		return err
+	}
+	if err := MediaLimits[mediaType].Check(filename); err != nil {
+		return fmt.Errorf("invalid %s media file %q: %w", mediaType, filename, err)
+	}
+	return nil
+}
+
 type MediaResponse struct {
 	weixin.ResponseMeta
 	MediaMeta
@@ -70,18 +92,21 @@ func (t MediaMeta) String() string {
 	return strings.Join(sb, ", ")
 }
 
-const reqURL = work.Host + "/cgi-bin/media/upload?access_token="
+const reqPath = "/cgi-bin/media/upload?access_token="
 
 // MediaUpload 上传临时素材 媒体文件在微信后台保存时间为3天，即3天后media_id失效。
-func MediaUpload(accessToken, mediaType, filename string) (*MediaMeta, error) {
-	u := reqURL + url.QueryEscape(accessToken) + "&type=" + url.QueryEscape(mediaType)
+func MediaUpload(ctx context.Context, c *httpClient.Client, accessToken, mediaType, filename string) (*MediaMeta, error) {
+	if err := ValidateMediaFile(mediaType, filename); err != nil {
+		return nil, err
+	}
+	u := work.URL(c, reqPath) + url.QueryEscape(accessToken) + "&type=" + url.QueryEscape(mediaType)
 	var resp MediaResponse
-	_, err := client.PostFileJSON(u, FieldName, filename, &resp)
+	_, err := client.PostFileJSON(ctx, c, u, FieldName, filename, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return &resp.MediaMeta, nil
 }
@@ -15,17 +15,22 @@
 package asset
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/file"
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/work/token"
 )
 
 type CmdWorkMediaUploadParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	CorpID      string
 	CorpSecret  string
@@ -46,31 +51,33 @@ func (t *CmdWorkMediaUploadParams) Validate() error {
 		return fmt.Errorf("file is not exist, %v", t.File)
 	}
 
+	if err := ValidateMediaFile(t.MediaType, t.File); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // CmdWorkMediaUpload 上传临时素材
-func CmdWorkMediaUpload(arg *CmdWorkMediaUploadParams) error {
+func CmdWorkMediaUpload(ctx context.Context, arg *CmdWorkMediaUploadParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.CorpID, arg.CorpSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
+	cred := token.Credential{
+		Client:      arg.Client,
+		Cache:       arg.TokenCache,
+		AccessToken: arg.AccessToken,
+		CorpID:      arg.CorpID,
+		CorpSecret:  arg.CorpSecret,
 	}
-
-	if meta, err := MediaUpload(arg.AccessToken, arg.MediaType, arg.File); err != nil {
+	var meta *MediaMeta
+	if err := token.Do(ctx, &cred, func(accessToken string) (err error) {
+		meta, err = MediaUpload(ctx, arg.Client, accessToken, arg.MediaType, arg.File)
+		return err
+	}); err != nil {
 		return err
-	} else {
-		fmt.Println(fmt.Sprintf("%v; %v", weixin.MessageOK, meta))
 	}
-
-	return nil
+	return arg.Printer.Print(fmt.Sprintf("%v; %v", weixin.MessageOK, meta), meta)
 }
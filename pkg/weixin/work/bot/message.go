@@ -15,9 +15,14 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"time"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/ratelimit"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -33,15 +38,17 @@ const (
 
 	TplCardTypeText = "text_notice" // 文本通知模版卡片
 	TplCardTypeNews = "news_notice" // 图文展示模版卡片
+
+	MsgTypeGeneric = notify.TypeGeneric // 通用消息，转换为 markdown 或模版卡片
 )
 
 // ValidateMsgType 验证
 func ValidateMsgType(v string) error {
 	switch v {
-	case MsgTypeText, MsgTypeMarkdown, MsgTypeImage, MsgTypeNews, MsgTypeFile, TplCardTypeText, TplCardTypeNews:
+	case MsgTypeText, MsgTypeMarkdown, MsgTypeImage, MsgTypeNews, MsgTypeFile, TplCardTypeText, TplCardTypeNews, MsgTypeGeneric:
 	default:
-		return fmt.Errorf("%s not in [%q %q %q %q %q %q %q]", v,
-			MsgTypeText, MsgTypeMarkdown, MsgTypeImage, MsgTypeNews, MsgTypeFile, TplCardTypeText, TplCardTypeNews)
+		return fmt.Errorf("%s not in [%q %q %q %q %q %q %q %q]", v,
+			MsgTypeText, MsgTypeMarkdown, MsgTypeImage, MsgTypeNews, MsgTypeFile, TplCardTypeText, TplCardTypeNews, MsgTypeGeneric)
 	}
 	return nil
 }
@@ -57,21 +64,25 @@ type Message struct {
 	TemplateCard any           `json:"template_card,omitempty"` // 模版卡片
 }
 
-const sendURL = work.Host + "/cgi-bin/webhook/send?key="
+const sendPath = "/cgi-bin/webhook/send?key="
+
+// SendLimit 每个机器人发送的消息不能超过20条/分钟
+var SendLimit = ratelimit.Limit{Burst: 20, Interval: time.Minute}
 
 // Send 发送企业微信群机器人消息
 //
 // 消息发送频率限制
 // 每个机器人发送的消息不能超过20条/分钟。
-func Send(key string, msg *Message) error {
-	u := sendURL + url.QueryEscape(key)
+func Send(ctx context.Context, c *httpClient.Client, key string, msg *Message) error {
+	u := work.URL(c, sendPath) + url.QueryEscape(key)
+	ctx = httpClient.WithRateLimit(ctx, "workweixin:bot:"+key, SendLimit)
 	var resp weixin.ResponseMeta
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
@@ -15,25 +15,25 @@
 package bot
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"strings"
 
 	"github.com/lenye/pmsg/pkg/file"
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
 	"github.com/lenye/pmsg/pkg/weixin"
 )
 
 type CmdSendParams struct {
-	UserAgent string
-	Key       string
-	MsgType   string
-	AtUser    string
-	AtMobile  string
-	Data      string
+	Client   *client.Client
+	Printer  *output.Printer
+	Key      string
+	MsgType  string
+	AtUser   string
+	AtMobile string
+	Data     string
 }
 
 func (t *CmdSendParams) Validate() error {
@@ -51,81 +51,26 @@ func (t *CmdSendParams) Validate() error {
 }
 
 // CmdSend 发送企业微信群机器人消息
-func CmdSend(arg *CmdSendParams) error {
+func CmdSend(ctx context.Context, arg *CmdSendParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	msg := Message{
-		MsgType: arg.MsgType,
+	n := Notifier{
+		Client: arg.Client,
+		Key:    arg.Key,
 	}
-
-	buf := new(bytes.Buffer)
-	buf.WriteString(arg.Data)
-	switch arg.MsgType {
-	case MsgTypeText:
-		var msgMeta TextMeta
-		msgMeta.Content = buf.String()
-		if arg.AtUser != "" {
-			msgMeta.MentionedList = strings.Split(arg.AtUser, "|")
-		}
-		if arg.AtMobile != "" {
-			msgMeta.MentionedMobileList = strings.Split(arg.AtMobile, "|")
-		}
-		msg.Text = &msgMeta
-	case MsgTypeMarkdown:
-		var msgMeta MarkdownMeta
-		msgMeta.Content = buf.String()
-		msg.Markdown = &msgMeta
-	case MsgTypeImage:
-		msgMeta, err := ImageFile2Meta(buf.String())
-		if err != nil {
-			return err
-		}
-		msg.Image = msgMeta
-	case MsgTypeNews:
-		var msgMeta NewsMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.Articles)
-		if lenArticles == 0 || lenArticles > 8 {
-			return errors.New("length of articles is 1-8")
-		}
-		msg.News = &msgMeta
-	case MsgTypeFile:
-		var msgMeta FileMeta
-		msgMeta.MediaID = buf.String()
-		msg.File = &msgMeta
-	case TplCardTypeText:
-		var msgMeta TemplateCardText
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		if msgMeta.CardType != TplCardTypeText {
-			return errors.New("invalid card_type")
-		}
-		msg.MsgType = MsgTypeTplCard
-		msg.TemplateCard = &msgMeta
-	case TplCardTypeNews:
-		var msgMeta TemplateCardNews
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		if msgMeta.CardType != TplCardTypeNews {
-			return errors.New("invalid card_type")
-		}
-		msg.MsgType = MsgTypeTplCard
-		msg.TemplateCard = &msgMeta
+	msg := notify.Message{
+		Type:    arg.MsgType,
+		Content: arg.Data,
+		Mentions: notify.Mentions{
+			Users:   notify.Split(arg.AtUser),
+			Mobiles: notify.Split(arg.AtMobile),
+		},
 	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if err := Send(arg.Key, &msg); err != nil {
+	if _, err := n.Send(ctx, msg); err != nil {
 		return err
 	}
-	fmt.Println(weixin.MessageOK)
-
-	return nil
+	return arg.Printer.Print(weixin.MessageOK, nil)
 }
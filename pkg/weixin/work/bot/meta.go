@@ -108,16 +108,16 @@ type TemplateCardText struct {
 
 // TemplateCardNews 图文展示模版卡片
 type TemplateCardNews struct {
-	CardType              string             `json:"card_type"`               // 模版卡片的模版类型，文本通知模版卡片的类型为text_notice
-	Source                *CardSource        `json:"source,omitempty"`        // 卡片来源样式信息，不需要来源样式可不填写
-	MainTitle             CardTitle          `json:"main_title"`              // 模版卡片的主要内容，包括一级标题和标题辅助信息
-	CardImage             CardImage          `json:"card_image"`              // 图片样式
-	ImageTextArea         *CardImageTextArea `json:"image_text_area"`         // 左图右文样式
-	QuoteArea             *CardQuoteArea     `json:"quote_area,omitempty"`    // 引用文献样式，建议不与关键数据共用
-	VerticalContentList   []CardTitle        `json:"vertical_content_list"`   // 卡片二级垂直内容，该字段可为空数组，但有数据的话需确认对应字段是否必填，列表长度不超过4
-	HorizontalContentList []CardContent      `json:"horizontal_content_list"` // 二级标题+文本列表，该字段可为空数组，但有数据的话需确认对应字段是否必填，列表长度不超过6
-	JumpList              []JumpContent      `json:"jump_list"`               // 跳转指引样式的列表，该字段可为空数组，但有数据的话需确认对应字段是否必填，列表长度不超过3
-	CardAction            CardAction         `json:"card_action"`             // 整体卡片的点击跳转事件，text_notice模版卡片中该字段为必填项
+	CardType              string             `json:"card_type"`                 // 模版卡片的模版类型，文本通知模版卡片的类型为text_notice
+	Source                *CardSource        `json:"source,omitempty"`          // 卡片来源样式信息，不需要来源样式可不填写
+	MainTitle             CardTitle          `json:"main_title"`                // 模版卡片的主要内容，包括一级标题和标题辅助信息
+	CardImage             CardImage          `json:"card_image"`                // 图片样式
+	ImageTextArea         *CardImageTextArea `json:"image_text_area,omitempty"` // 左图右文样式
+	QuoteArea             *CardQuoteArea     `json:"quote_area,omitempty"`      // 引用文献样式，建议不与关键数据共用
+	VerticalContentList   []CardTitle        `json:"vertical_content_list"`     // 卡片二级垂直内容，该字段可为空数组，但有数据的话需确认对应字段是否必填，列表长度不超过4
+	HorizontalContentList []CardContent      `json:"horizontal_content_list"`   // 二级标题+文本列表，该字段可为空数组，但有数据的话需确认对应字段是否必填，列表长度不超过6
+	JumpList              []JumpContent      `json:"jump_list"`                 // 跳转指引样式的列表，该字段可为空数组，但有数据的话需确认对应字段是否必填，列表长度不超过3
+	CardAction            CardAction         `json:"card_action"`               // 整体卡片的点击跳转事件，text_notice模版卡片中该字段为必填项
 }
 
 // CardSource 卡片来源样式信息，不需要来源样式可不填写
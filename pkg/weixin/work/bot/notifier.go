@@ -0,0 +1,219 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+// NewMessage 按消息类型新建企业微信群机器人消息，text、generic 支持 @ 提醒，markdown 只支持 @ 用户 id，
+// 其他消息类型有 @ 提醒时返回错误；image 的内容为图片文件名
+func NewMessage(msgType, content string, mentions notify.Mentions) (*Message, error) {
+	if err := ValidateMsgType(msgType); err != nil {
+		return nil, err
+	}
+	switch msgType {
+	case MsgTypeGeneric, MsgTypeText:
+	case MsgTypeMarkdown:
+		if textMentions(mentions) {
+			return nil, fmt.Errorf("%s message does not support @ mobile or @all, use %s", msgType, MsgTypeText)
+		}
+	default:
+		if !mentions.Empty() {
+			return nil, fmt.Errorf("%s message does not support @ mentions, use %s or %s", msgType, MsgTypeText, MsgTypeMarkdown)
+		}
+	}
+
+	msg := Message{
+		MsgType: msgType,
+	}
+
+	switch msgType {
+	case MsgTypeGeneric:
+		g, err := notify.ParseGeneric(content)
+		if err != nil {
+			return nil, err
+		}
+		return NewGenericMessage(g, mentions), nil
+	case MsgTypeText:
+		msg.Text = newText(content, mentions)
+	case MsgTypeMarkdown:
+		msg.Markdown = &MarkdownMeta{Content: content + atUsers(mentions.Users)}
+	case MsgTypeImage:
+		msgMeta, err := ImageFile2Meta(content)
+		if err != nil {
+			return nil, err
+		}
+		msg.Image = msgMeta
+	case MsgTypeNews:
+		var msgMeta NewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return nil, errors.New("length of articles is 1-8")
+		}
+		msg.News = &msgMeta
+	case MsgTypeFile:
+		msg.File = &FileMeta{MediaID: content}
+	case TplCardTypeText:
+		var msgMeta TemplateCardText
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.CardType != TplCardTypeText {
+			return nil, errors.New("invalid card_type")
+		}
+		msg.MsgType = MsgTypeTplCard
+		msg.TemplateCard = &msgMeta
+	case TplCardTypeNews:
+		var msgMeta TemplateCardNews
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return nil, fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.CardType != TplCardTypeNews {
+			return nil, errors.New("invalid card_type")
+		}
+		msg.MsgType = MsgTypeTplCard
+		msg.TemplateCard = &msgMeta
+	}
+	return &msg, nil
+}
+
+// newText 文本消息，@所有人 为 @all
+func newText(content string, mentions notify.Mentions) *TextMeta {
+	msgMeta := TextMeta{
+		Content:             content,
+		MentionedList:       mentions.Users,
+		MentionedMobileList: mentions.Mobiles,
+	}
+	if mentions.All {
+		msgMeta.MentionedList = append(msgMeta.MentionedList, "@all")
+	}
+	return &msgMeta
+}
+
+// textMentions 是否有只有文本消息支持的 @ 提醒：手机号、@所有人
+func textMentions(mentions notify.Mentions) bool {
+	if len(mentions.Mobiles) > 0 || mentions.All {
+		return true
+	}
+	for _, v := range mentions.Users {
+		if v == "@all" {
+			return true
+		}
+	}
+	return false
+}
+
+// atUsers markdown 消息以 <@userid> 附在正文之后 @ 用户
+func atUsers(users []string) string {
+	var s string
+	for _, v := range users {
+		s += "\n<@" + v + ">"
+	}
+	return s
+}
+
+// severitySources 消息级别对应模版卡片的来源样式
+var severitySources = map[string]*CardSource{
+	notify.SeverityInfo:     {Desc: "INFO", DescColor: 3},
+	notify.SeverityWarning:  {Desc: "WARNING", DescColor: 1},
+	notify.SeverityCritical: {Desc: "CRITICAL", DescColor: 2},
+}
+
+// NewGenericMessage 通用消息转换为企业微信群机器人消息：
+// 有按钮或链接时为模版卡片，有图片时为图文展示，否则为文本通知；没有时为 markdown，@ 提醒以 <@userid> 附在正文之后。
+// 模版卡片不支持 @ 提醒，@ 用户时为 markdown；markdown 不支持 @ 手机号和 @所有人，这时为文本消息
+func NewGenericMessage(g *notify.Generic, mentions notify.Mentions) *Message {
+	if textMentions(mentions) {
+		return &Message{MsgType: MsgTypeText, Text: newText(g.Text(), mentions)}
+	}
+	if url := g.URL(); url != "" && len(mentions.Users) == 0 {
+		var jumps []JumpContent
+		for _, v := range append(append([]notify.Link(nil), g.Buttons...), g.Links...) {
+			if len(jumps) == 3 {
+				break
+			}
+			jumps = append(jumps, JumpContent{Type: 1, URL: v.URL, Title: notify.Truncate(v.Title, 13)})
+		}
+		title := CardTitle{Title: notify.Truncate(g.Title, 26)}
+		body := notify.PlainText(g.Body)
+		action := CardAction{Type: 1, URL: url}
+
+		msg := Message{MsgType: MsgTypeTplCard}
+		if g.Image != "" {
+			title.Desc = notify.Truncate(body, 30)
+			msg.TemplateCard = &TemplateCardNews{
+				CardType:              TplCardTypeNews,
+				Source:                severitySources[g.Severity],
+				MainTitle:             title,
+				CardImage:             CardImage{Url: g.Image},
+				VerticalContentList:   []CardTitle{},
+				HorizontalContentList: []CardContent{},
+				JumpList:              jumps,
+				CardAction:            action,
+			}
+		} else {
+			msg.TemplateCard = &TemplateCardText{
+				CardType:              TplCardTypeText,
+				Source:                severitySources[g.Severity],
+				MainTitle:             title,
+				SubTitleText:          notify.Truncate(body, 112),
+				HorizontalContentList: []CardContent{},
+				JumpList:              jumps,
+				CardAction:            action,
+			}
+		}
+		return &msg
+	}
+
+	// markdown 不支持图片，以链接显示
+	text := g.Markdown(true, false, true)
+	if g.Image != "" {
+		text += "\n\n[image](" + g.Image + ")"
+	}
+	text += atUsers(mentions.Users)
+	return &Message{MsgType: MsgTypeMarkdown, Markdown: &MarkdownMeta{Content: text}}
+}
+
+// Notifier 企业微信群机器人
+type Notifier struct {
+	Client *httpClient.Client
+	Key    string // 机器人 webhook key
+}
+
+// Send 发送企业微信群机器人消息
+func (t *Notifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWorkWeiXin}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	var m *Message
+	if g != nil {
+		m = NewGenericMessage(g, msg.Mentions)
+	} else if m, err = NewMessage(msg.Type, msg.Content, msg.Mentions); err != nil {
+		return result, err
+	}
+	return result, Send(ctx, t.Client, t.Key, m)
+}
@@ -0,0 +1,109 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lenye/pmsg/pkg/notify"
+)
+
+func TestNewGenericMessageMentions(t *testing.T) {
+	card := &notify.Generic{
+		Title:   "部署失败",
+		Body:    "**api** 部署失败",
+		Buttons: []notify.Link{{Title: "查看", URL: "https://example.com/run/1"}},
+	}
+	plain := &notify.Generic{Title: "磁盘告警", Body: "使用率 95%"}
+
+	tests := []struct {
+		name     string
+		g        *notify.Generic
+		mentions notify.Mentions
+		msgType  string
+		contains string
+		text     *TextMeta
+	}{
+		{"card", card, notify.Mentions{}, MsgTypeTplCard, "", nil},
+		// 模版卡片不支持 @ 提醒
+		{"card users", card, notify.Mentions{Users: []string{"u1"}}, MsgTypeMarkdown, "<@u1>", nil},
+		{"markdown users", plain, notify.Mentions{Users: []string{"u1", "u2"}}, MsgTypeMarkdown, "<@u1>\n<@u2>", nil},
+		// markdown 不支持 @ 手机号和 @所有人
+		{"card mobiles", card, notify.Mentions{Mobiles: []string{"13800000000"}}, MsgTypeText, "https://example.com/run/1",
+			&TextMeta{MentionedMobileList: []string{"13800000000"}}},
+		{"markdown all", plain, notify.Mentions{Users: []string{"u1"}, All: true}, MsgTypeText, "使用率 95%",
+			&TextMeta{MentionedList: []string{"u1", "@all"}}},
+		{"markdown user @all", plain, notify.Mentions{Users: []string{"@all"}}, MsgTypeText, "使用率 95%",
+			&TextMeta{MentionedList: []string{"@all"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := NewGenericMessage(tt.g, tt.mentions)
+			if msg.MsgType != tt.msgType {
+				t.Fatalf("msgtype %s, want %s", msg.MsgType, tt.msgType)
+			}
+			switch msg.MsgType {
+			case MsgTypeMarkdown:
+				if !strings.Contains(msg.Markdown.Content, tt.contains) {
+					t.Errorf("markdown %q, want %q", msg.Markdown.Content, tt.contains)
+				}
+				// 按钮以链接显示
+				if tt.g == card && !strings.Contains(msg.Markdown.Content, "(https://example.com/run/1)") {
+					t.Errorf("markdown %q, want the button link", msg.Markdown.Content)
+				}
+			case MsgTypeText:
+				if !strings.Contains(msg.Text.Content, tt.contains) {
+					t.Errorf("text %q, want %q", msg.Text.Content, tt.contains)
+				}
+				if !reflect.DeepEqual(msg.Text.MentionedList, tt.text.MentionedList) ||
+					!reflect.DeepEqual(msg.Text.MentionedMobileList, tt.text.MentionedMobileList) {
+					t.Errorf("mentions %v %v, want %v %v", msg.Text.MentionedList, msg.Text.MentionedMobileList,
+						tt.text.MentionedList, tt.text.MentionedMobileList)
+				}
+			}
+		})
+	}
+}
+
+func TestNewMessageMentions(t *testing.T) {
+	users := notify.Mentions{Users: []string{"u1"}}
+	tests := []struct {
+		name     string
+		msgType  string
+		content  string
+		mentions notify.Mentions
+		wantErr  bool
+	}{
+		{"text all", MsgTypeText, "hi", notify.Mentions{Mobiles: []string{"13800000000"}, All: true}, false},
+		{"markdown users", MsgTypeMarkdown, "hi", users, false},
+		{"markdown mobiles", MsgTypeMarkdown, "hi", notify.Mentions{Mobiles: []string{"13800000000"}}, true},
+		{"markdown all", MsgTypeMarkdown, "hi", notify.Mentions{All: true}, true},
+		{"file users", MsgTypeFile, "media_id", users, true},
+		{"file", MsgTypeFile, "media_id", notify.Mentions{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := NewMessage(tt.msgType, tt.content, tt.mentions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && tt.msgType == MsgTypeMarkdown && msg.Markdown.Content != "hi\n<@u1>" {
+				t.Errorf("markdown %q", msg.Markdown.Content)
+			}
+		})
+	}
+}
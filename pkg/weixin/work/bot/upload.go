@@ -15,11 +15,14 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/lenye/pmsg/pkg/file"
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -27,6 +30,9 @@ import (
 
 const FieldName = "media"
 
+// UploadLimit 文件大小在5B~20M之间
+var UploadLimit = file.Limit{MinSize: 5, MaxSize: 20 * file.MB}
+
 type MediaResponse struct {
 	weixin.ResponseMeta
 	MediaMeta
@@ -52,18 +58,21 @@ func (t MediaMeta) String() string {
 	return strings.Join(sb, ", ")
 }
 
-const uploadURL = work.Host + "/cgi-bin/webhook/upload_media?key="
+const uploadPath = "/cgi-bin/webhook/upload_media?key="
 
 // Upload 上传临时素材 媒体文件在微信后台保存时间为3天，即3天后media_id失效。
-func Upload(key, filename string) (*MediaMeta, error) {
-	u := uploadURL + url.QueryEscape(key) + "&type=file"
+func Upload(ctx context.Context, c *httpClient.Client, key, filename string) (*MediaMeta, error) {
+	if err := UploadLimit.Check(filename); err != nil {
+		return nil, fmt.Errorf("invalid upload file %q: %w", filename, err)
+	}
+	u := work.URL(c, uploadPath) + url.QueryEscape(key) + "&type=file"
 	var resp MediaResponse
-	_, err := client.PostFileJSON(u, FieldName, filename, &resp)
+	_, err := client.PostFileJSON(ctx, c, u, FieldName, filename, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return &resp.MediaMeta, nil
 }
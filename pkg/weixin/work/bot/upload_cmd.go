@@ -15,17 +15,20 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/file"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
 	"github.com/lenye/pmsg/pkg/weixin"
 )
 
 type CmdUploadParams struct {
-	UserAgent string
-	Key       string
-	File      string
+	Client  *client.Client
+	Printer *output.Printer
+	Key     string
+	File    string
 }
 
 func (t *CmdUploadParams) Validate() error {
@@ -33,23 +36,23 @@ func (t *CmdUploadParams) Validate() error {
 		return fmt.Errorf("file is not exist, %v", t.File)
 	}
 
+	if err := UploadLimit.Check(t.File); err != nil {
+		return fmt.Errorf("invalid upload file %q: %w", t.File, err)
+	}
+
 	return nil
 }
 
 // CmdUpload 企业微信群机器人上传文件
-func CmdUpload(arg *CmdUploadParams) error {
+func CmdUpload(ctx context.Context, arg *CmdUploadParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	client.SetUserAgent(arg.UserAgent)
-
-	if meta, err := Upload(arg.Key, arg.File); err != nil {
+	meta, err := Upload(ctx, arg.Client, arg.Key, arg.File)
+	if err != nil {
 		return err
-	} else {
-		fmt.Println(fmt.Sprintf("%v; %v", weixin.MessageOK, meta))
 	}
-
-	return nil
+	return arg.Printer.Print(fmt.Sprintf("%v; %v", weixin.MessageOK, meta), meta)
 }
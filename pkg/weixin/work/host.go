@@ -14,4 +14,22 @@
 
 package work
 
+import (
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+)
+
+// Host 企业微信接口默认地址
 const Host = "https://qyapi.weixin.qq.com"
+
+// HostEnv 替换企业微信接口地址的环境变量
+const HostEnv = "PMSG_WORKWEIXIN_API_BASE"
+
+// WithHost 替换企业微信接口地址
+func WithHost(base string) httpClient.Option {
+	return httpClient.WithBaseURL(Host, base)
+}
+
+// URL 拼接企业微信接口地址
+func URL(c *httpClient.Client, path string) string {
+	return c.BaseURL(Host) + path
+}
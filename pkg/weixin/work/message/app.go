@@ -15,10 +15,13 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -37,6 +40,7 @@ const (
 	AppMsgTypeMarkdown          = "markdown"           // markdown消息
 	AppMsgTypeMiniProgramNotice = "miniprogram_notice" // 小程序通知消息
 	AppMsgTypeTemplateCard      = "template_card"      // 模板卡片消息
+	AppMsgTypeGeneric           = notify.TypeGeneric   // 通用消息，转换为 textcard 或 markdown
 )
 
 // ValidateAppMsgType 验证
@@ -44,12 +48,12 @@ func ValidateAppMsgType(v string) error {
 	switch v {
 	case AppMsgTypeText, AppMsgTypeImage, AppMsgTypeVoice, AppMsgTypeVideo,
 		AppMsgTypeFile, AppMsgTypeTextCard, AppMsgTypeNews, AppMsgTypeMpNews,
-		AppMsgTypeMarkdown, AppMsgTypeMiniProgramNotice, AppMsgTypeTemplateCard:
+		AppMsgTypeMarkdown, AppMsgTypeMiniProgramNotice, AppMsgTypeTemplateCard, AppMsgTypeGeneric:
 	default:
-		return fmt.Errorf("%s not in [%q %q %q %q %q %q %q %q %q %q %q]", v,
+		return fmt.Errorf("%s not in [%q %q %q %q %q %q %q %q %q %q %q %q]", v,
 			AppMsgTypeText, AppMsgTypeImage, AppMsgTypeVoice, AppMsgTypeVideo,
 			AppMsgTypeFile, AppMsgTypeTextCard, AppMsgTypeNews, AppMsgTypeMpNews,
-			AppMsgTypeMarkdown, AppMsgTypeMiniProgramNotice, AppMsgTypeTemplateCard)
+			AppMsgTypeMarkdown, AppMsgTypeMiniProgramNotice, AppMsgTypeTemplateCard, AppMsgTypeGeneric)
 	}
 	return nil
 }
@@ -136,18 +140,18 @@ func (t AppMessageResponse) String() string {
 	return strings.Join(sb, ", ")
 }
 
-const appSendURL = work.Host + "/cgi-bin/message/send?access_token="
+const appSendPath = "/cgi-bin/message/send?access_token="
 
 // SendApp 发送企业微信应用消息
-func SendApp(accessToken string, msg *AppMessage) (*AppMessageResponse, error) {
-	u := appSendURL + url.QueryEscape(accessToken)
+func SendApp(ctx context.Context, c *httpClient.Client, accessToken string, msg *AppMessage) (*AppMessageResponse, error) {
+	u := work.URL(c, appSendPath) + url.QueryEscape(accessToken)
 	var resp AppMessageResponse
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return &resp, nil
 }
@@ -15,20 +15,23 @@
 package message
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/work/token"
 )
 
 type CmdWorkSendAppParams struct {
-	UserAgent              string
+	Client                 *client.Client
+	Printer                *output.Printer
+	TokenCache             tokencache.Cache
 	AccessToken            string
 	CorpID                 string
 	CorpSecret             string
@@ -93,118 +96,32 @@ func (t *CmdWorkSendAppParams) Validate() error {
 }
 
 // CmdWorkSendApp 发送企业微信应用消息
-func CmdWorkSendApp(arg *CmdWorkSendAppParams) error {
+func CmdWorkSendApp(ctx context.Context, arg *CmdWorkSendAppParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	msg := AppMessage{
+	n := AppNotifier{
+		Credential: token.Credential{
+			Client:      arg.Client,
+			Cache:       arg.TokenCache,
+			AccessToken: arg.AccessToken,
+			CorpID:      arg.CorpID,
+			CorpSecret:  arg.CorpSecret,
+		},
 		ToUser:                 arg.ToUser,
 		ToParty:                arg.ToParty,
 		ToTag:                  arg.ToTag,
 		AgentID:                arg.AgentID,
-		MsgType:                arg.MsgType,
 		Safe:                   arg.Safe,
 		EnableIDTrans:          arg.EnableIDTrans,
 		EnableDuplicateCheck:   arg.EnableDuplicateCheck,
 		DuplicateCheckInterval: arg.DuplicateCheckInterval,
 	}
-
-	buf := bytes.NewBufferString("")
-	buf.WriteString(arg.Data)
-	switch arg.MsgType {
-	case AppMsgTypeText:
-		var msgMeta TextMeta
-		msgMeta.Content = buf.String()
-		msg.Text = &msgMeta
-	case AppMsgTypeImage:
-		var msgMeta ImageMeta
-		msgMeta.MediaID = buf.String()
-		msg.Image = &msgMeta
-	case AppMsgTypeVoice:
-		var msgMeta VoiceMeta
-		msgMeta.MediaID = buf.String()
-		msg.Voice = &msgMeta
-	case AppMsgTypeVideo:
-		var msgMeta VideoMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		if msgMeta.MediaID == "" {
-			return errors.New("media_id is empty")
-		}
-		msg.Video = &msgMeta
-	case AppMsgTypeFile:
-		var msgMeta FileMeta
-		msgMeta.MediaID = buf.String()
-		msg.File = &msgMeta
-	case AppMsgTypeTextCard:
-		var msgMeta TextCardMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.TextCard = &msgMeta
-	case AppMsgTypeNews:
-		var msgMeta NewsMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.Articles)
-		if lenArticles == 0 || lenArticles > 8 {
-			return errors.New("length of articles is 1-8")
-		}
-		msg.News = &msgMeta
-	case AppMsgTypeMpNews:
-		var msgMeta MpNewsMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.Articles)
-		if lenArticles == 0 || lenArticles > 8 {
-			return errors.New("length of articles is 1-8")
-		}
-		msg.MpNews = &msgMeta
-	case AppMsgTypeMarkdown:
-		var msgMeta MarkdownMeta
-		msgMeta.Content = buf.String()
-		msg.Markdown = &msgMeta
-	case AppMsgTypeMiniProgramNotice:
-		var msgMeta MiniProgramNoticeMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.ContentItem)
-		if lenArticles > 10 {
-			return errors.New("content_item up to 10")
-		}
-		msg.MiniProgramNotice = &msgMeta
-	case AppMsgTypeTemplateCard:
-		var msgMeta TemplateCardMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		if err := ValidateAppTemplateCardType(msgMeta.CardType); err != nil {
-			return fmt.Errorf("invalid card_type: %v", err)
-		}
-		msg.TemplateCard = &msgMeta
-	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.CorpID, arg.CorpSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
-	}
-
-	if resp, err := SendApp(arg.AccessToken, &msg); err != nil {
+	result, err := n.Send(ctx, notify.Message{Type: arg.MsgType, Content: arg.Data})
+	if err != nil {
 		return err
-	} else {
-		fmt.Println(fmt.Sprintf("%v; %v", weixin.MessageOK, resp))
 	}
-
-	return nil
+	return arg.Printer.Print(fmt.Sprintf("%v; %v", weixin.MessageOK, result.Response), result.Response)
 }
@@ -0,0 +1,204 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/weixin/work/token"
+)
+
+// SetContent 按消息类型设置消息内容：text、markdown 为文本，image、voice、file 为 media_id，其他为该类型的 json，generic 为通用消息 json
+func (t *AppMessage) SetContent(content string) error {
+	if err := ValidateAppMsgType(t.MsgType); err != nil {
+		return err
+	}
+
+	switch t.MsgType {
+	case AppMsgTypeGeneric:
+		g, err := notify.ParseGeneric(content)
+		if err != nil {
+			return err
+		}
+		t.SetGeneric(g)
+	case AppMsgTypeText:
+		var msgMeta TextMeta
+		msgMeta.Content = content
+		t.Text = &msgMeta
+	case AppMsgTypeImage:
+		var msgMeta ImageMeta
+		msgMeta.MediaID = content
+		t.Image = &msgMeta
+	case AppMsgTypeVoice:
+		var msgMeta VoiceMeta
+		msgMeta.MediaID = content
+		t.Voice = &msgMeta
+	case AppMsgTypeVideo:
+		var msgMeta VideoMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.MediaID == "" {
+			return errors.New("media_id is empty")
+		}
+		t.Video = &msgMeta
+	case AppMsgTypeFile:
+		var msgMeta FileMeta
+		msgMeta.MediaID = content
+		t.File = &msgMeta
+	case AppMsgTypeTextCard:
+		var msgMeta TextCardMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		t.TextCard = &msgMeta
+	case AppMsgTypeNews:
+		var msgMeta NewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return errors.New("length of articles is 1-8")
+		}
+		t.News = &msgMeta
+	case AppMsgTypeMpNews:
+		var msgMeta MpNewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return errors.New("length of articles is 1-8")
+		}
+		t.MpNews = &msgMeta
+	case AppMsgTypeMarkdown:
+		var msgMeta MarkdownMeta
+		msgMeta.Content = content
+		t.Markdown = &msgMeta
+	case AppMsgTypeMiniProgramNotice:
+		var msgMeta MiniProgramNoticeMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.ContentItem)
+		if lenArticles > 10 {
+			return errors.New("content_item up to 10")
+		}
+		t.MiniProgramNotice = &msgMeta
+	case AppMsgTypeTemplateCard:
+		var msgMeta TemplateCardMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if err := ValidateAppTemplateCardType(msgMeta.CardType); err != nil {
+			return fmt.Errorf("invalid card_type: %v", err)
+		}
+		t.TemplateCard = &msgMeta
+	}
+	return nil
+}
+
+// SetGeneric 设置通用消息：有按钮或链接时为 textcard，否则为 markdown
+func (t *AppMessage) SetGeneric(g *notify.Generic) {
+	t.TextCard, t.Markdown = genericMeta(g)
+	if t.TextCard != nil {
+		t.MsgType = AppMsgTypeTextCard
+	} else {
+		t.MsgType = AppMsgTypeMarkdown
+	}
+}
+
+// genericMeta 通用消息转换为 textcard 或 markdown：有按钮或链接时为 textcard，否则为 markdown
+func genericMeta(g *notify.Generic) (*TextCardMeta, *MarkdownMeta) {
+	if url := g.URL(); url != "" {
+		card := TextCardMeta{
+			Title:       g.FullTitle(),
+			Description: notify.PlainText(g.Body),
+			Url:         url,
+		}
+		if card.Title == "" {
+			card.Title = notify.Truncate(card.Description, 32)
+		}
+		if len(g.Buttons) > 0 {
+			card.BtnTxt = notify.Truncate(g.Buttons[0].Title, 4)
+		}
+		return &card, nil
+	}
+
+	// markdown 不支持图片，以链接显示
+	text := g.Markdown(true, false, false)
+	if g.Image != "" {
+		text += "\n\n[image](" + g.Image + ")"
+	}
+	return nil, &MarkdownMeta{Content: text}
+}
+
+// AppNotifier 企业微信应用消息
+type AppNotifier struct {
+	Credential             token.Credential
+	ToUser                 string // 成员ID列表，多个接收者用‘|’分隔，"@all" 为全部成员
+	ToParty                string // 部门ID列表，多个接收者用‘|’分隔
+	ToTag                  string // 标签ID列表，多个接收者用‘|’分隔
+	AgentID                int64
+	Safe                   int
+	EnableIDTrans          int
+	EnableDuplicateCheck   int
+	DuplicateCheckInterval int
+}
+
+// Send 发送企业微信应用消息，不支持 @ 提醒，Result.Response 为 *AppMessageResponse
+func (t *AppNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWorkWeiXin}
+
+	m := AppMessage{
+		ToUser:                 t.ToUser,
+		ToParty:                t.ToParty,
+		ToTag:                  t.ToTag,
+		AgentID:                t.AgentID,
+		MsgType:                msg.Type,
+		Safe:                   t.Safe,
+		EnableIDTrans:          t.EnableIDTrans,
+		EnableDuplicateCheck:   t.EnableDuplicateCheck,
+		DuplicateCheckInterval: t.DuplicateCheckInterval,
+	}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	if g != nil {
+		m.SetGeneric(g)
+	} else if err := m.SetContent(msg.Content); err != nil {
+		return result, err
+	}
+
+	var resp *AppMessageResponse
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) (err error) {
+		resp, err = SendApp(ctx, t.Credential.Client, accessToken, &m)
+		return err
+	}); err != nil {
+		return result, err
+	}
+	result.MessageID = resp.MsgID
+	result.InvalidUsers = notify.Split(resp.InvalidUser)
+	result.InvalidParties = notify.Split(resp.InvalidParty)
+	result.InvalidTags = notify.Split(resp.InvalidTag)
+	result.Response = resp
+	return result, nil
+}
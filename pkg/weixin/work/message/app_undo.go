@@ -15,9 +15,11 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -34,18 +36,18 @@ type UndoAppMessageResponse struct {
 	weixin.ResponseMeta
 }
 
-const undoAppSendURL = work.Host + "/cgi-bin/message/recall?access_token="
+const undoAppSendPath = "/cgi-bin/message/recall?access_token="
 
 // UndoApp 撤回企业微信应用消息
-func UndoApp(accessToken string, msg *UndoAppMessage) error {
-	u := appSendURL + url.QueryEscape(accessToken)
+func UndoApp(ctx context.Context, c *httpClient.Client, accessToken string, msg *UndoAppMessage) error {
+	u := work.URL(c, undoAppSendPath) + url.QueryEscape(accessToken)
 	var resp UndoAppMessageResponse
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
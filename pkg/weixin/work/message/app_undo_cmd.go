@@ -15,15 +15,19 @@
 package message
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/work/token"
 )
 
 type CmdWorkUndoAppParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	CorpID      string
 	CorpSecret  string
@@ -31,26 +35,23 @@ type CmdWorkUndoAppParams struct {
 }
 
 // CmdWorkUndoApp 撤回企业微信应用消息
-func CmdWorkUndoApp(arg *CmdWorkUndoAppParams) error {
+func CmdWorkUndoApp(ctx context.Context, arg *CmdWorkUndoAppParams) error {
 
 	msg := UndoAppMessage{
 		MsgID: arg.MsgID,
 	}
 
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.CorpID, arg.CorpSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
+	cred := token.Credential{
+		Client:      arg.Client,
+		Cache:       arg.TokenCache,
+		AccessToken: arg.AccessToken,
+		CorpID:      arg.CorpID,
+		CorpSecret:  arg.CorpSecret,
 	}
-
-	if err := UndoApp(arg.AccessToken, &msg); err != nil {
+	if err := token.Do(ctx, &cred, func(accessToken string) error {
+		return UndoApp(ctx, arg.Client, accessToken, &msg)
+	}); err != nil {
 		return err
 	}
-	fmt.Println(weixin.MessageOK)
-
-	return nil
+	return arg.Printer.Print(weixin.MessageOK, nil)
 }
@@ -15,9 +15,11 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -67,18 +69,18 @@ type AppChatMessage struct {
 	Markdown *MarkdownMeta `json:"markdown,omitempty"` // markdown消息
 }
 
-const appChatSendURL = work.Host + "/cgi-bin/appchat/send?access_token="
+const appChatSendPath = "/cgi-bin/appchat/send?access_token="
 
 // SendAppChat 发送企业微信群聊推送消息
-func SendAppChat(accessToken string, msg *AppChatMessage) error {
-	u := appChatSendURL + url.QueryEscape(accessToken)
+func SendAppChat(ctx context.Context, c *httpClient.Client, accessToken string, msg *AppChatMessage) error {
+	u := work.URL(c, appChatSendPath) + url.QueryEscape(accessToken)
 	var resp weixin.ResponseMeta
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return err
 	}
 	if !resp.Succeed() {
-		return fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return nil
 }
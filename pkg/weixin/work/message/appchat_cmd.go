@@ -15,19 +15,22 @@
 package message
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/work/token"
 )
 
 type CmdWorkSendAppChatParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	CorpID      string
 	CorpSecret  string
@@ -54,92 +57,25 @@ func (t *CmdWorkSendAppChatParams) Validate() error {
 }
 
 // CmdWorkSendAppChat 发送企业微信群聊推送消息
-func CmdWorkSendAppChat(arg *CmdWorkSendAppChatParams) error {
+func CmdWorkSendAppChat(ctx context.Context, arg *CmdWorkSendAppChatParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	msg := AppChatMessage{
-		ChatID:  arg.ChatID,
-		MsgType: arg.MsgType,
-		Safe:    arg.Safe,
+	n := AppChatNotifier{
+		Credential: token.Credential{
+			Client:      arg.Client,
+			Cache:       arg.TokenCache,
+			AccessToken: arg.AccessToken,
+			CorpID:      arg.CorpID,
+			CorpSecret:  arg.CorpSecret,
+		},
+		ChatID: arg.ChatID,
+		Safe:   arg.Safe,
 	}
-
-	buf := bytes.NewBufferString("")
-	buf.WriteString(arg.Data)
-	switch arg.MsgType {
-	case AppChatMsgTypeText:
-		var msgMeta TextMeta
-		msgMeta.Content = buf.String()
-		msg.Text = &msgMeta
-	case AppChatMsgTypeImage:
-		var msgMeta ImageMeta
-		msgMeta.MediaID = buf.String()
-		msg.Image = &msgMeta
-	case AppChatMsgTypeVoice:
-		var msgMeta VoiceMeta
-		msgMeta.MediaID = buf.String()
-		msg.Voice = &msgMeta
-	case AppChatMsgTypeVideo:
-		var msgMeta VideoMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		if msgMeta.MediaID == "" {
-			return errors.New("media_id is empty")
-		}
-		msg.Video = &msgMeta
-	case AppChatMsgTypeFile:
-		var msgMeta FileMeta
-		msgMeta.MediaID = buf.String()
-		msg.File = &msgMeta
-	case AppChatMsgTypeTextCard:
-		var msgMeta TextCardMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.TextCard = &msgMeta
-	case AppChatMsgTypeNews:
-		var msgMeta NewsMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.Articles)
-		if lenArticles == 0 || lenArticles > 8 {
-			return errors.New("length of articles is 1-8")
-		}
-		msg.News = &msgMeta
-	case AppChatMsgTypeMpNews:
-		var msgMeta MpNewsMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.Articles)
-		if lenArticles == 0 || lenArticles > 8 {
-			return errors.New("length of articles is 1-8")
-		}
-		msg.MpNews = &msgMeta
-	case AppChatMsgTypeMarkdown:
-		var msgMeta MarkdownMeta
-		msgMeta.Content = buf.String()
-		msg.Markdown = &msgMeta
-	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.CorpID, arg.CorpSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
-	}
-
-	if err := SendAppChat(arg.AccessToken, &msg); err != nil {
+	if _, err := n.Send(ctx, notify.Message{Type: arg.MsgType, Content: arg.Data}); err != nil {
 		return err
 	}
-	fmt.Println(weixin.MessageOK)
-
-	return nil
+	return arg.Printer.Print(weixin.MessageOK, nil)
 }
@@ -0,0 +1,135 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/weixin/work/token"
+)
+
+// SetContent 按消息类型设置消息内容：text、markdown 为文本，image、voice、file 为 media_id，其他为该类型的 json
+func (t *AppChatMessage) SetContent(content string) error {
+	if err := ValidateAppChatMsgType(t.MsgType); err != nil {
+		return err
+	}
+
+	switch t.MsgType {
+	case AppChatMsgTypeText:
+		var msgMeta TextMeta
+		msgMeta.Content = content
+		t.Text = &msgMeta
+	case AppChatMsgTypeImage:
+		var msgMeta ImageMeta
+		msgMeta.MediaID = content
+		t.Image = &msgMeta
+	case AppChatMsgTypeVoice:
+		var msgMeta VoiceMeta
+		msgMeta.MediaID = content
+		t.Voice = &msgMeta
+	case AppChatMsgTypeVideo:
+		var msgMeta VideoMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.MediaID == "" {
+			return errors.New("media_id is empty")
+		}
+		t.Video = &msgMeta
+	case AppChatMsgTypeFile:
+		var msgMeta FileMeta
+		msgMeta.MediaID = content
+		t.File = &msgMeta
+	case AppChatMsgTypeTextCard:
+		var msgMeta TextCardMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		t.TextCard = &msgMeta
+	case AppChatMsgTypeNews:
+		var msgMeta NewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return errors.New("length of articles is 1-8")
+		}
+		t.News = &msgMeta
+	case AppChatMsgTypeMpNews:
+		var msgMeta MpNewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return errors.New("length of articles is 1-8")
+		}
+		t.MpNews = &msgMeta
+	case AppChatMsgTypeMarkdown:
+		var msgMeta MarkdownMeta
+		msgMeta.Content = content
+		t.Markdown = &msgMeta
+	}
+	return nil
+}
+
+// SetGeneric 设置通用消息：有按钮或链接时为 textcard，否则为 markdown
+func (t *AppChatMessage) SetGeneric(g *notify.Generic) {
+	t.TextCard, t.Markdown = genericMeta(g)
+	if t.TextCard != nil {
+		t.MsgType = AppChatMsgTypeTextCard
+	} else {
+		t.MsgType = AppChatMsgTypeMarkdown
+	}
+}
+
+// AppChatNotifier 企业微信群聊推送消息
+type AppChatNotifier struct {
+	Credential token.Credential
+	ChatID     string // 群聊id
+	Safe       int
+}
+
+// Send 发送企业微信群聊推送消息，不支持 @ 提醒，Result.Response 为 nil
+func (t *AppChatNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWorkWeiXin}
+
+	m := AppChatMessage{
+		ChatID:  t.ChatID,
+		MsgType: msg.Type,
+		Safe:    t.Safe,
+	}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	if g != nil {
+		m.SetGeneric(g)
+	} else if err := m.SetContent(msg.Content); err != nil {
+		return result, err
+	}
+
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) error {
+		return SendAppChat(ctx, t.Credential.Client, accessToken, &m)
+	}); err != nil {
+		return result, err
+	}
+	return result, nil
+}
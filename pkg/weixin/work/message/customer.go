@@ -15,9 +15,11 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -84,18 +86,18 @@ func (t CustomerMessageResponse) String() string {
 	return fmt.Sprintf("errcode: %v, errmsg: %q, msgid: %q", t.ErrorCode, t.ErrorMessage, t.MsgID)
 }
 
-const customerSendURL = work.Host + "/cgi-bin/kf/send_msg?access_token="
+const customerSendPath = "/cgi-bin/kf/send_msg?access_token="
 
 // SendCustomer 发送微信客服消息
-func SendCustomer(accessToken string, msg *CustomerMessage) (*CustomerMessageResponse, error) {
-	u := customerSendURL + url.QueryEscape(accessToken)
+func SendCustomer(ctx context.Context, c *httpClient.Client, accessToken string, msg *CustomerMessage) (*CustomerMessageResponse, error) {
+	u := work.URL(c, customerSendPath) + url.QueryEscape(accessToken)
 	var resp CustomerMessageResponse
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return &resp, nil
 }
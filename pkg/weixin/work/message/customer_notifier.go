@@ -0,0 +1,120 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/weixin/work/token"
+)
+
+// SetContent 按消息类型设置消息内容：text 为文本，image、voice、video、file 为 media_id，其他为该类型的 json
+func (t *CustomerMessage) SetContent(content string) error {
+	if err := ValidateCustomerMsgType(t.MsgType); err != nil {
+		return err
+	}
+
+	switch t.MsgType {
+	case CustomerMsgTypeText:
+		var msgMeta TextMeta
+		msgMeta.Content = content
+		t.Text = &msgMeta
+	case CustomerMsgTypeImage:
+		var msgMeta ImageMeta
+		msgMeta.MediaID = content
+		t.Image = &msgMeta
+	case CustomerMsgTypeVoice:
+		var msgMeta VoiceMeta
+		msgMeta.MediaID = content
+		t.Voice = &msgMeta
+	case CustomerMsgTypeVideo:
+		var msgMeta VideoMeta
+		msgMeta.MediaID = content
+		t.Video = &msgMeta
+	case CustomerMsgTypeFile:
+		var msgMeta FileMeta
+		msgMeta.MediaID = content
+		t.File = &msgMeta
+	case CustomerMsgTypeLink:
+		var msgMeta LinkMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		t.Link = &msgMeta
+	case CustomerMsgTypeMiniProgram:
+		var msgMeta MiniProgramMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		t.MiniProgram = &msgMeta
+	case CustomerMsgTypeMsgMenu:
+		var msgMeta MsgMenuMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		t.MsgMenu = &msgMeta
+	case CustomerMsgTypeLocation:
+		var msgMeta LocationMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		t.Location = &msgMeta
+	}
+	return nil
+}
+
+// CustomerNotifier 微信客服消息
+type CustomerNotifier struct {
+	Credential token.Credential
+	ToUser     string // 客户的external_userid
+	OpenKfID   string // 客服账号ID
+	MsgID      string // 消息ID，为空时由系统生成
+}
+
+// Send 发送微信客服消息，通用消息转换为 text，不支持 @ 提醒，Result.Response 为 *CustomerMessageResponse
+func (t *CustomerNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWorkWeiXin}
+
+	m := CustomerMessage{
+		ToUser:   t.ToUser,
+		OpenKfID: t.OpenKfID,
+		MsgID:    t.MsgID,
+		MsgType:  msg.Type,
+	}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	if g != nil {
+		m.MsgType = CustomerMsgTypeText
+		m.Text = &TextMeta{Content: g.Text()}
+	} else if err := m.SetContent(msg.Content); err != nil {
+		return result, err
+	}
+
+	var resp *CustomerMessageResponse
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) (err error) {
+		resp, err = SendCustomer(ctx, t.Credential.Client, accessToken, &m)
+		return err
+	}); err != nil {
+		return result, err
+	}
+	result.MessageID = resp.MsgID
+	result.Response = resp
+	return result, nil
+}
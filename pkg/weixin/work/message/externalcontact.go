@@ -15,10 +15,12 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -99,18 +101,18 @@ func (t ExternalContactMessageResponse) String() string {
 	return strings.Join(sb, ", ")
 }
 
-const externalContactSendURL = work.Host + "/cgi-bin/externalcontact/message/send?access_token="
+const externalContactSendPath = "/cgi-bin/externalcontact/message/send?access_token="
 
 // SendExternalContact 发送企业微信家校消息
-func SendExternalContact(accessToken string, msg *ExternalContactMessage) (*ExternalContactMessageResponse, error) {
-	u := externalContactSendURL + url.QueryEscape(accessToken)
+func SendExternalContact(ctx context.Context, c *httpClient.Client, accessToken string, msg *ExternalContactMessage) (*ExternalContactMessageResponse, error) {
+	u := work.URL(c, externalContactSendPath) + url.QueryEscape(accessToken)
 	var resp ExternalContactMessageResponse
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return &resp, nil
 }
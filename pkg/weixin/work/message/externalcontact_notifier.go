@@ -0,0 +1,145 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/weixin/work/token"
+)
+
+// SetContent 按消息类型设置消息内容：text 为文本，image、voice、file 为 media_id，其他为该类型的 json
+func (t *ExternalContactMessage) SetContent(content string) error {
+	if err := ValidateExternalContactMsgType(t.MsgType); err != nil {
+		return err
+	}
+
+	switch t.MsgType {
+	case ExternalContactMsgTypeText:
+		var msgMeta TextMeta
+		msgMeta.Content = content
+		t.Text = &msgMeta
+	case ExternalContactMsgTypeImage:
+		var msgMeta ImageMeta
+		msgMeta.MediaID = content
+		t.Image = &msgMeta
+	case ExternalContactMsgTypeVoice:
+		var msgMeta VoiceMeta
+		msgMeta.MediaID = content
+		t.Voice = &msgMeta
+	case ExternalContactMsgTypeVideo:
+		var msgMeta VideoMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.MediaID == "" {
+			return errors.New("media_id is empty")
+		}
+		t.Video = &msgMeta
+	case ExternalContactMsgTypeFile:
+		var msgMeta FileMeta
+		msgMeta.MediaID = content
+		t.File = &msgMeta
+	case ExternalContactMsgTypeNews:
+		var msgMeta NewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return errors.New("length of articles is 1-8")
+		}
+		t.News = &msgMeta
+	case ExternalContactMsgTypeMpNews:
+		var msgMeta MpNewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return errors.New("length of articles is 1-8")
+		}
+		t.MpNews = &msgMeta
+	case ExternalContactMsgTypeMiniProgramNotice:
+		var msgMeta MiniProgramNoticeMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.ContentItem)
+		if lenArticles > 10 {
+			return errors.New("content_item up to 10")
+		}
+		t.MiniProgramNotice = &msgMeta
+	}
+	return nil
+}
+
+// ExternalContactNotifier 企业微信家校消息
+type ExternalContactNotifier struct {
+	Credential             token.Credential
+	RecvScope              int      // 0 发送给家长，1 发送给学生，2 发送给家长和学生
+	ToParentUserID         []string // 家长的userid
+	ToStudentUserID        []string // 学生的userid
+	ToParty                []string // 班级id
+	ToAll                  int      // 1 为发送给学校的所有家长或学生
+	AgentID                int64
+	EnableIDTrans          int
+	EnableDuplicateCheck   int
+	DuplicateCheckInterval int
+}
+
+// Send 发送企业微信家校消息，通用消息转换为 text，不支持 @ 提醒，Result.Response 为 *ExternalContactMessageResponse
+func (t *ExternalContactNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWorkWeiXin}
+
+	m := ExternalContactMessage{
+		RecvScope:              t.RecvScope,
+		ToParentUserID:         t.ToParentUserID,
+		ToStudentUserID:        t.ToStudentUserID,
+		ToParty:                t.ToParty,
+		ToAll:                  t.ToAll,
+		MsgType:                msg.Type,
+		AgentID:                t.AgentID,
+		EnableIDTrans:          t.EnableIDTrans,
+		EnableDuplicateCheck:   t.EnableDuplicateCheck,
+		DuplicateCheckInterval: t.DuplicateCheckInterval,
+	}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	if g != nil {
+		m.MsgType = ExternalContactMsgTypeText
+		m.Text = &TextMeta{Content: g.Text()}
+	} else if err := m.SetContent(msg.Content); err != nil {
+		return result, err
+	}
+
+	var resp *ExternalContactMessageResponse
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) (err error) {
+		resp, err = SendExternalContact(ctx, t.Credential.Client, accessToken, &m)
+		return err
+	}); err != nil {
+		return result, err
+	}
+	result.InvalidUsers = append(append([]string(nil), resp.InvalidParentUserID...), resp.InvalidStudentUserID...)
+	result.InvalidParties = resp.InvalidParty
+	result.Response = resp
+	return result, nil
+}
@@ -15,10 +15,12 @@
 package message
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -100,18 +102,18 @@ func (t LinkedCorpMessageResponse) String() string {
 	return strings.Join(sb, ", ")
 }
 
-const linkedCorpSendURL = work.Host + "/cgi-bin/linkedcorp/message/send?access_token="
+const linkedCorpSendPath = "/cgi-bin/linkedcorp/message/send?access_token="
 
 // SendLinkedCorp 发送企业微信互联企业消息
-func SendLinkedCorp(accessToken string, msg *LinkedCorpMessage) (*LinkedCorpMessageResponse, error) {
-	u := linkedCorpSendURL + url.QueryEscape(accessToken)
+func SendLinkedCorp(ctx context.Context, c *httpClient.Client, accessToken string, msg *LinkedCorpMessage) (*LinkedCorpMessageResponse, error) {
+	u := work.URL(c, linkedCorpSendPath) + url.QueryEscape(accessToken)
 	var resp LinkedCorpMessageResponse
-	_, err := client.PostJSON(u, msg, &resp)
+	_, err := client.PostJSON(ctx, c, u, msg, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp)
 	}
 	return &resp, nil
 }
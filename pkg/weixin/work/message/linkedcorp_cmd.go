@@ -15,19 +15,22 @@
 package message
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/flags"
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/work/token"
 )
 
 type CmdWorkSendLinkedCorpParams struct {
-	UserAgent   string
+	Client      *client.Client
+	Printer     *output.Printer
+	TokenCache  tokencache.Cache
 	AccessToken string
 	CorpID      string
 	CorpSecret  string
@@ -71,107 +74,30 @@ func (t *CmdWorkSendLinkedCorpParams) Validate() error {
 }
 
 // CmdWorkSendLinkedCorp 发送企业微信互联企业消息
-func CmdWorkSendLinkedCorp(arg *CmdWorkSendLinkedCorpParams) error {
+func CmdWorkSendLinkedCorp(ctx context.Context, arg *CmdWorkSendLinkedCorpParams) error {
 
 	if err := arg.Validate(); err != nil {
 		return err
 	}
 
-	msg := LinkedCorpMessage{
+	n := LinkedCorpNotifier{
+		Credential: token.Credential{
+			Client:      arg.Client,
+			Cache:       arg.TokenCache,
+			AccessToken: arg.AccessToken,
+			CorpID:      arg.CorpID,
+			CorpSecret:  arg.CorpSecret,
+		},
 		ToUser:  arg.ToUser,
 		ToParty: arg.ToParty,
 		ToTag:   arg.ToTag,
 		ToAll:   arg.ToAll,
 		AgentID: arg.AgentID,
-		MsgType: arg.MsgType,
 		Safe:    arg.Safe,
 	}
-
-	buf := bytes.NewBufferString("")
-	buf.WriteString(arg.Data)
-	switch arg.MsgType {
-	case LinkedCorpMsgTypeText:
-		var msgMeta TextMeta
-		msgMeta.Content = buf.String()
-		msg.Text = &msgMeta
-	case LinkedCorpMsgTypeImage:
-		var msgMeta ImageMeta
-		msgMeta.MediaID = buf.String()
-		msg.Image = &msgMeta
-	case LinkedCorpMsgTypeVoice:
-		var msgMeta VoiceMeta
-		msgMeta.MediaID = buf.String()
-		msg.Voice = &msgMeta
-	case LinkedCorpMsgTypeVideo:
-		var msgMeta VideoMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		if msgMeta.MediaID == "" {
-			return errors.New("media_id is empty")
-		}
-		msg.Video = &msgMeta
-	case LinkedCorpMsgTypeFile:
-		var msgMeta FileMeta
-		msgMeta.MediaID = buf.String()
-		msg.File = &msgMeta
-	case LinkedCorpMsgTypeTextCard:
-		var msgMeta TextCardMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		msg.TextCard = &msgMeta
-	case LinkedCorpMsgTypeNews:
-		var msgMeta NewsMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.Articles)
-		if lenArticles == 0 || lenArticles > 8 {
-			return errors.New("length of articles is 1-8")
-		}
-		msg.News = &msgMeta
-	case LinkedCorpMsgTypeMpNews:
-		var msgMeta MpNewsMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.Articles)
-		if lenArticles == 0 || lenArticles > 8 {
-			return errors.New("length of articles is 1-8")
-		}
-		msg.MpNews = &msgMeta
-	case LinkedCorpMsgTypeMarkdown:
-		var msgMeta MarkdownMeta
-		msgMeta.Content = buf.String()
-		msg.Markdown = &msgMeta
-	case LinkedCorpMsgTypeMiniProgramNotice:
-		var msgMeta MiniProgramNoticeMeta
-		if err := json.Unmarshal(buf.Bytes(), &msgMeta); err != nil {
-			return fmt.Errorf("invalid json format, %v", err)
-		}
-		lenArticles := len(msgMeta.ContentItem)
-		if lenArticles > 10 {
-			return errors.New("content_item up to 10")
-		}
-		msg.MiniProgramNotice = &msgMeta
-	}
-
-	client.SetUserAgent(arg.UserAgent)
-
-	if arg.AccessToken == "" {
-		accessTokenResp, err := token.FetchAccessToken(arg.CorpID, arg.CorpSecret)
-		if err != nil {
-			return err
-		}
-		arg.AccessToken = accessTokenResp.AccessToken
-	}
-
-	if resp, err := SendLinkedCorp(arg.AccessToken, &msg); err != nil {
+	result, err := n.Send(ctx, notify.Message{Type: arg.MsgType, Content: arg.Data})
+	if err != nil {
 		return err
-	} else {
-		fmt.Println(fmt.Sprintf("%v; %v", weixin.MessageOK, resp))
 	}
-
-	return nil
+	return arg.Printer.Print(fmt.Sprintf("%v; %v", weixin.MessageOK, result.Response), result.Response)
 }
@@ -0,0 +1,159 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lenye/pmsg/pkg/notify"
+	"github.com/lenye/pmsg/pkg/weixin/work/token"
+)
+
+// SetContent 按消息类型设置消息内容：text、markdown 为文本，image、voice、file 为 media_id，其他为该类型的 json
+func (t *LinkedCorpMessage) SetContent(content string) error {
+	if err := ValidateLinkedCorpMsgType(t.MsgType); err != nil {
+		return err
+	}
+
+	switch t.MsgType {
+	case LinkedCorpMsgTypeText:
+		var msgMeta TextMeta
+		msgMeta.Content = content
+		t.Text = &msgMeta
+	case LinkedCorpMsgTypeImage:
+		var msgMeta ImageMeta
+		msgMeta.MediaID = content
+		t.Image = &msgMeta
+	case LinkedCorpMsgTypeVoice:
+		var msgMeta VoiceMeta
+		msgMeta.MediaID = content
+		t.Voice = &msgMeta
+	case LinkedCorpMsgTypeVideo:
+		var msgMeta VideoMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		if msgMeta.MediaID == "" {
+			return errors.New("media_id is empty")
+		}
+		t.Video = &msgMeta
+	case LinkedCorpMsgTypeFile:
+		var msgMeta FileMeta
+		msgMeta.MediaID = content
+		t.File = &msgMeta
+	case LinkedCorpMsgTypeTextCard:
+		var msgMeta TextCardMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		t.TextCard = &msgMeta
+	case LinkedCorpMsgTypeNews:
+		var msgMeta NewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return errors.New("length of articles is 1-8")
+		}
+		t.News = &msgMeta
+	case LinkedCorpMsgTypeMpNews:
+		var msgMeta MpNewsMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.Articles)
+		if lenArticles == 0 || lenArticles > 8 {
+			return errors.New("length of articles is 1-8")
+		}
+		t.MpNews = &msgMeta
+	case LinkedCorpMsgTypeMarkdown:
+		var msgMeta MarkdownMeta
+		msgMeta.Content = content
+		t.Markdown = &msgMeta
+	case LinkedCorpMsgTypeMiniProgramNotice:
+		var msgMeta MiniProgramNoticeMeta
+		if err := json.Unmarshal([]byte(content), &msgMeta); err != nil {
+			return fmt.Errorf("invalid json format, %v", err)
+		}
+		lenArticles := len(msgMeta.ContentItem)
+		if lenArticles > 10 {
+			return errors.New("content_item up to 10")
+		}
+		t.MiniProgramNotice = &msgMeta
+	}
+	return nil
+}
+
+// SetGeneric 设置通用消息：有按钮或链接时为 textcard，否则为 markdown
+func (t *LinkedCorpMessage) SetGeneric(g *notify.Generic) {
+	t.TextCard, t.Markdown = genericMeta(g)
+	if t.TextCard != nil {
+		t.MsgType = LinkedCorpMsgTypeTextCard
+	} else {
+		t.MsgType = LinkedCorpMsgTypeMarkdown
+	}
+}
+
+// LinkedCorpNotifier 企业微信互联企业消息
+type LinkedCorpNotifier struct {
+	Credential token.Credential
+	ToUser     []string // 成员ID列表，格式为 corpid/userid，本企业的成员直接为 userid
+	ToParty    []string // 部门ID列表，格式为 linked_id/party_id，本企业的部门直接为 party_id
+	ToTag      []string // 本企业的标签ID列表
+	ToAll      int      // 1 为发送给应用可见范围内的所有人
+	AgentID    int64
+	Safe       int
+}
+
+// Send 发送企业微信互联企业消息，不支持 @ 提醒，Result.Response 为 *LinkedCorpMessageResponse
+func (t *LinkedCorpNotifier) Send(ctx context.Context, msg notify.Message) (notify.Result, error) {
+	result := notify.Result{Provider: notify.ProviderWorkWeiXin}
+
+	m := LinkedCorpMessage{
+		ToUser:  t.ToUser,
+		ToParty: t.ToParty,
+		ToTag:   t.ToTag,
+		ToAll:   t.ToAll,
+		AgentID: t.AgentID,
+		MsgType: msg.Type,
+		Safe:    t.Safe,
+	}
+	g, err := msg.GenericContent()
+	if err != nil {
+		return result, err
+	}
+	if g != nil {
+		m.SetGeneric(g)
+	} else if err := m.SetContent(msg.Content); err != nil {
+		return result, err
+	}
+
+	var resp *LinkedCorpMessageResponse
+	if err := token.Do(ctx, &t.Credential, func(accessToken string) (err error) {
+		resp, err = SendLinkedCorp(ctx, t.Credential.Client, accessToken, &m)
+		return err
+	}); err != nil {
+		return result, err
+	}
+	result.InvalidUsers = resp.InvalidUser
+	result.InvalidParties = resp.InvalidParty
+	result.InvalidTags = resp.InvalidTag
+	result.Response = resp
+	return result, nil
+}
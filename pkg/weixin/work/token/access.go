@@ -15,10 +15,12 @@
 package token
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"time"
 
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
 	"github.com/lenye/pmsg/pkg/weixin"
 	"github.com/lenye/pmsg/pkg/weixin/client"
 	"github.com/lenye/pmsg/pkg/weixin/work"
@@ -43,7 +45,7 @@ type AccessTokenResponse struct {
 	AccessTokenMeta
 }
 
-const reqURL = work.Host + "/cgi-bin/gettoken?corpid="
+const reqPath = "/cgi-bin/gettoken?corpid="
 
 // FetchAccessToken 获取微信接口调用凭证
 //
@@ -53,15 +55,18 @@ const reqURL = work.Host + "/cgi-bin/gettoken?corpid="
 //	 "access_token": "accesstoken000001",
 //	 "expires_in": 7200
 //	}
-func FetchAccessToken(corpID, corpSecret string) (*AccessTokenMeta, error) {
-	u := reqURL + url.QueryEscape(corpID) + "&corpsecret=" + url.QueryEscape(corpSecret)
+func FetchAccessToken(ctx context.Context, c *httpClient.Client, corpID, corpSecret string) (*AccessTokenMeta, error) {
+	if !c.FetchToken() {
+		return &AccessTokenMeta{AccessToken: httpClient.DryRunAccessToken}, nil
+	}
+	u := work.URL(c, reqPath) + url.QueryEscape(corpID) + "&corpsecret=" + url.QueryEscape(corpSecret)
 	var resp AccessTokenResponse
-	_, err := client.GetJSON(u, &resp)
+	_, err := client.GetJSON(ctx, c, u, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Succeed() {
-		return nil, fmt.Errorf("%w; %v", weixin.ErrRequest, resp.ResponseMeta)
+		return nil, fmt.Errorf("%w; %v", resp.Err(), resp.ResponseMeta)
 	}
 
 	resp.AccessTokenMeta.ExpireAt = time.Now().Add(time.Second * time.Duration(resp.AccessTokenMeta.ExpireIn))
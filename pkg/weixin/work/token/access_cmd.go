@@ -15,28 +15,29 @@
 package token
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/output"
+	"github.com/lenye/pmsg/pkg/tokencache"
 	"github.com/lenye/pmsg/pkg/weixin"
 )
 
 type CmdWorkTokenParams struct {
-	UserAgent  string
+	Client     *client.Client
+	Printer    *output.Printer
+	TokenCache tokencache.Cache
 	CorpID     string
 	CorpSecret string
 }
 
 // CmdWorkGetAccessToken 获取企业微信接口调用凭证
-func CmdWorkGetAccessToken(arg *CmdWorkTokenParams) error {
+func CmdWorkGetAccessToken(ctx context.Context, arg *CmdWorkTokenParams) error {
 
-	client.SetUserAgent(arg.UserAgent)
-
-	accessTokenResp, err := FetchAccessToken(arg.CorpID, arg.CorpSecret)
+	accessTokenResp, err := AccessToken(ctx, arg.Client, arg.TokenCache, arg.CorpID, arg.CorpSecret)
 	if err != nil {
 		return err
 	}
-	fmt.Println(fmt.Sprintf("%v; %v", weixin.MessageOK, accessTokenResp))
-
-	return nil
+	return arg.Printer.Print(fmt.Sprintf("%v; %v", weixin.MessageOK, accessTokenResp), accessTokenResp)
 }
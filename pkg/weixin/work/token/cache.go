@@ -0,0 +1,68 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"context"
+	"time"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/tokencache"
+)
+
+// Provider 缓存中的平台名称
+const Provider = "workweixin"
+
+// fetchFunc 从平台获取 access_token
+func fetchFunc(c *httpClient.Client, corpID, corpSecret string) tokencache.FetchFunc {
+	return func(ctx context.Context) (*tokencache.Token, error) {
+		meta, err := FetchAccessToken(ctx, c, corpID, corpSecret)
+		if err != nil {
+			return nil, err
+		}
+		return &tokencache.Token{Provider: Provider, ID: corpID, AccessToken: meta.AccessToken, ExpireAt: meta.ExpireAt}, nil
+	}
+}
+
+// AccessToken 获取接口调用凭证，cache 不为 nil 时优先使用未过期的缓存
+func AccessToken(ctx context.Context, c *httpClient.Client, cache tokencache.Cache, corpID, corpSecret string) (*AccessTokenMeta, error) {
+	return accessToken(ctx, c, cache, corpID, corpSecret, false)
+}
+
+// RefreshAccessToken 不使用缓存，重新获取接口调用凭证并更新缓存
+func RefreshAccessToken(ctx context.Context, c *httpClient.Client, cache tokencache.Cache, corpID, corpSecret string) (*AccessTokenMeta, error) {
+	return accessToken(ctx, c, cache, corpID, corpSecret, true)
+}
+
+func accessToken(ctx context.Context, c *httpClient.Client, cache tokencache.Cache, corpID, corpSecret string, refresh bool) (*AccessTokenMeta, error) {
+	if cache == nil || !c.FetchToken() {
+		return FetchAccessToken(ctx, c, corpID, corpSecret)
+	}
+	key := tokencache.Key(Provider, corpID, "", corpSecret)
+	if refresh {
+		if err := cache.Delete(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	tk, err := cache.Get(ctx, key, fetchFunc(c, corpID, corpSecret))
+	if err != nil {
+		return nil, err
+	}
+	return &AccessTokenMeta{
+		AccessToken: tk.AccessToken,
+		ExpireIn:    int64(time.Until(tk.ExpireAt).Seconds()),
+		ExpireAt:    tk.ExpireAt,
+	}, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2022-2023 The pmsg Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	httpClient "github.com/lenye/pmsg/pkg/http/client"
+	"github.com/lenye/pmsg/pkg/tokencache"
+	"github.com/lenye/pmsg/pkg/weixin"
+)
+
+// Credential 接口调用凭证：命令行提供的 access_token，或用 corp_id/corp_secret 获取
+type Credential struct {
+	Client      *httpClient.Client
+	Cache       tokencache.Cache
+	AccessToken string
+	CorpID      string
+	CorpSecret  string
+}
+
+// source access_token 的来源，用于错误信息
+func (t *Credential) source() string {
+	if t.AccessToken != "" {
+		return "access_token flag"
+	}
+	if t.Cache != nil && t.Client.FetchToken() {
+		return fmt.Sprintf("%v, corp_id %s", t.Cache, t.CorpID)
+	}
+	return fmt.Sprintf("corp_id %s", t.CorpID)
+}
+
+// Do 使用 access_token 调用 fn；access_token 无效或过期且有 corp_id 时，重新获取 access_token 后重试一次
+func Do(ctx context.Context, cred *Credential, fn func(accessToken string) error) error {
+	accessToken := cred.AccessToken
+	if accessToken == "" {
+		meta, err := AccessToken(ctx, cred.Client, cred.Cache, cred.CorpID, cred.CorpSecret)
+		if err != nil {
+			return err
+		}
+		accessToken = meta.AccessToken
+	}
+
+	err := fn(accessToken)
+	if !errors.Is(err, weixin.ErrAccessTokenInvalid) {
+		return err
+	}
+	if cred.CorpID == "" {
+		return fmt.Errorf("%w; access_token from %s", err, cred.source())
+	}
+
+	meta, err := RefreshAccessToken(ctx, cred.Client, cred.Cache, cred.CorpID, cred.CorpSecret)
+	if err != nil {
+		return fmt.Errorf("%w; refresh access_token from %s", err, cred.source())
+	}
+	if err := fn(meta.AccessToken); err != nil {
+		if errors.Is(err, weixin.ErrUnauthorized) {
+			return fmt.Errorf("%w; access_token refreshed from %s", err, cred.source())
+		}
+		return err
+	}
+	return nil
+}